@@ -11,7 +11,10 @@ import (
 	"k8s.io/utils/pointer"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kprogo/ch3/ownerref"
 )
 
 func main() {
@@ -83,6 +86,13 @@ func main() {
 		corev1.SchemeGroupVersion.WithKind("Pod").
 			ToAPIVersionAndKind()
 
+	// Solution 3: use the ownerref package, which gets
+	// the APIVersion and Kind of the Pod from the scheme
+	ownerRef, err = ownerref.New(pod, scheme.Scheme)
+	if err != nil {
+		panic(err)
+	}
+
 	// #### Setting Controller
 	// Solution 1: declare a value and use its address
 	controller := true
@@ -0,0 +1,247 @@
+// Package ownerref builds and maintains OwnerReferences
+// without relying on the TypeMeta of the owner, which is
+// empty for objects returned by a clientset.
+package ownerref
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+)
+
+var (
+	// ErrCrossNamespace is returned when a namespaced owner
+	// is not in the same namespace as the owned object
+	ErrCrossNamespace = errors.New("cross-namespace owner references are not allowed")
+	// ErrClusterScopedDependent is returned when a cluster-scoped
+	// object references a namespaced owner
+	ErrClusterScopedDependent = errors.New("cluster-scoped objects cannot be owned by namespaced objects")
+	// ErrAlreadyControlled is returned when an object already
+	// has a controller reference to another owner
+	ErrAlreadyControlled = errors.New("object is already controlled by another owner")
+)
+
+// GVKForObject returns the GroupVersionKind of obj, as registered in scheme.
+// The GVK set in the TypeMeta of obj is used to disambiguate
+// types registered under several GroupVersionKinds.
+func GVKForObject(
+	obj runtime.Object,
+	scheme *runtime.Scheme,
+) (schema.GroupVersionKind, error) {
+	gvks, _, err := scheme.ObjectKinds(obj)
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	if len(gvks) == 1 {
+		return gvks[0], nil
+	}
+
+	declared := obj.GetObjectKind().GroupVersionKind()
+	for _, gvk := range gvks {
+		if gvk == declared {
+			return gvk, nil
+		}
+	}
+	return schema.GroupVersionKind{}, fmt.Errorf(
+		"%T is registered with multiple kinds %v, set its apiVersion and kind",
+		obj, gvks,
+	)
+}
+
+// New returns a non-controller OwnerReference to owner
+func New(
+	owner runtime.Object,
+	scheme *runtime.Scheme,
+) (metav1.OwnerReference, error) {
+	gvk, err := GVKForObject(owner, scheme)
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+	accessor, err := meta.Accessor(owner)
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+	if accessor.GetName() == "" || accessor.GetUID() == "" {
+		return metav1.OwnerReference{}, fmt.Errorf(
+			"owner %s %q must have a name and a uid",
+			gvk.Kind, accessor.GetName(),
+		)
+	}
+
+	ref := metav1.OwnerReference{
+		Name: accessor.GetName(),
+		UID:  accessor.GetUID(),
+	}
+	ref.APIVersion, ref.Kind = gvk.ToAPIVersionAndKind()
+	return ref, nil
+}
+
+// NewController returns a controller OwnerReference to owner,
+// blocking the deletion of the owner until the owned object is deleted
+func NewController(
+	owner runtime.Object,
+	scheme *runtime.Scheme,
+) (metav1.OwnerReference, error) {
+	ref, err := New(owner, scheme)
+	if err != nil {
+		return metav1.OwnerReference{}, err
+	}
+	ref.Controller = pointer.Bool(true)
+	ref.BlockOwnerDeletion = pointer.Bool(true)
+	return ref, nil
+}
+
+// Validate checks that owned is allowed to reference owner,
+// following the rules of the garbage collector:
+// a namespaced owner must be in the same namespace as the owned object,
+// and a cluster-scoped object can only be owned by cluster-scoped objects.
+// An object with an empty namespace is considered cluster-scoped.
+func Validate(owned metav1.Object, owner metav1.Object) error {
+	ownerNS := owner.GetNamespace()
+	if ownerNS == "" {
+		return nil
+	}
+	if owned.GetNamespace() == "" {
+		return fmt.Errorf("%w: owner %s/%s",
+			ErrClusterScopedDependent, ownerNS, owner.GetName())
+	}
+	if owned.GetNamespace() != ownerNS {
+		return fmt.Errorf("%w: owner %s/%s, owned %s/%s",
+			ErrCrossNamespace,
+			ownerNS, owner.GetName(),
+			owned.GetNamespace(), owned.GetName(),
+		)
+	}
+	return nil
+}
+
+// Set adds ref to the owner references of obj, or replaces
+// the existing reference to the same owner.
+// Two references point to the same owner when they share
+// the same group, kind and name; the version is ignored.
+// An error is returned if ref is a controller reference
+// and obj is already controlled by another owner.
+func Set(obj metav1.Object, ref metav1.OwnerReference) error {
+	refs := obj.GetOwnerReferences()
+	if isController(ref) {
+		if existing := metav1.GetControllerOfNoCopy(obj); existing != nil &&
+			!sameOwner(*existing, ref) {
+			return fmt.Errorf("%w: %s %q",
+				ErrAlreadyControlled, existing.Kind, existing.Name)
+		}
+	}
+
+	i := indexOf(refs, ref)
+	if i == -1 {
+		obj.SetOwnerReferences(append(refs, ref))
+		return nil
+	}
+	refs[i] = ref
+	obj.SetOwnerReferences(refs)
+	return nil
+}
+
+// SetFor validates that obj can be owned by owner,
+// then adds or replaces a reference to owner in obj.
+// The reference is a controller reference if controller is true.
+func SetFor(
+	obj metav1.Object,
+	owner runtime.Object,
+	scheme *runtime.Scheme,
+	controller bool,
+) error {
+	ownerMeta, err := meta.Accessor(owner)
+	if err != nil {
+		return err
+	}
+	if err = Validate(obj, ownerMeta); err != nil {
+		return err
+	}
+
+	var ref metav1.OwnerReference
+	if controller {
+		ref, err = NewController(owner, scheme)
+	} else {
+		ref, err = New(owner, scheme)
+	}
+	if err != nil {
+		return err
+	}
+	return Set(obj, ref)
+}
+
+// Remove removes the reference to the owner designated by ref
+// from obj. It returns false if obj was not referencing this owner.
+func Remove(obj metav1.Object, ref metav1.OwnerReference) bool {
+	refs := obj.GetOwnerReferences()
+	i := indexOf(refs, ref)
+	if i == -1 {
+		return false
+	}
+	result := make([]metav1.OwnerReference, 0, len(refs)-1)
+	result = append(result, refs[:i]...)
+	result = append(result, refs[i+1:]...)
+	obj.SetOwnerReferences(result)
+	return true
+}
+
+// Replace replaces the reference to the owner designated by old
+// with ref. It returns false if obj was not referencing old,
+// in which case obj is not modified.
+func Replace(obj metav1.Object, old, ref metav1.OwnerReference) bool {
+	refs := obj.GetOwnerReferences()
+	i := indexOf(refs, old)
+	if i == -1 {
+		return false
+	}
+	result := make([]metav1.OwnerReference, 0, len(refs))
+	for j := range refs {
+		switch {
+		case j == i:
+			result = append(result, ref)
+		case sameOwner(refs[j], ref):
+			// the new owner is already referenced, keep a single reference
+		default:
+			result = append(result, refs[j])
+		}
+	}
+	obj.SetOwnerReferences(result)
+	return true
+}
+
+// IsOwnedBy returns true if obj has a reference to the owner designated by ref
+func IsOwnedBy(obj metav1.Object, ref metav1.OwnerReference) bool {
+	return indexOf(obj.GetOwnerReferences(), ref) != -1
+}
+
+func indexOf(refs []metav1.OwnerReference, ref metav1.OwnerReference) int {
+	for i := range refs {
+		if sameOwner(refs[i], ref) {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameOwner(a, b metav1.OwnerReference) bool {
+	agv, err := schema.ParseGroupVersion(a.APIVersion)
+	if err != nil {
+		return false
+	}
+	bgv, err := schema.ParseGroupVersion(b.APIVersion)
+	if err != nil {
+		return false
+	}
+	return agv.Group == bgv.Group &&
+		a.Kind == b.Kind &&
+		a.Name == b.Name
+}
+
+func isController(ref metav1.OwnerReference) bool {
+	return ref.Controller != nil && *ref.Controller
+}
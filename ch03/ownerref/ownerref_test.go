@@ -0,0 +1,293 @@
+package ownerref
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+)
+
+func TestNew(t *testing.T) {
+	// As returned by a clientset, without TypeMeta
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mypod",
+			Namespace: "myns",
+			UID:       "uid-pod",
+		},
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{
+		Group: "mygroup.example.com", Version: "v1alpha1", Kind: "MyResource",
+	})
+	u.SetName("myres")
+	u.SetUID("uid-myres")
+
+	tests := []struct {
+		name  string
+		owner runtime.Object
+		want  metav1.OwnerReference
+		err   bool
+	}{
+		{
+			name:  "typed object without TypeMeta",
+			owner: pod,
+			want: metav1.OwnerReference{
+				APIVersion: "v1",
+				Kind:       "Pod",
+				Name:       "mypod",
+				UID:        "uid-pod",
+			},
+		},
+		{
+			name:  "unstructured object",
+			owner: u,
+			want: metav1.OwnerReference{
+				APIVersion: "mygroup.example.com/v1alpha1",
+				Kind:       "MyResource",
+				Name:       "myres",
+				UID:        "uid-myres",
+			},
+		},
+		{
+			name:  "object without uid",
+			owner: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}},
+			err:   true,
+		},
+		{
+			name:  "type not registered",
+			owner: &metav1.Status{},
+			err:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.owner, scheme.Scheme)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error: %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewController(t *testing.T) {
+	dep := &appsv1.Deployment{}
+	dep.SetName("mydep")
+	dep.SetUID("uid-dep")
+
+	got, err := NewController(dep, scheme.Scheme)
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	want := metav1.OwnerReference{
+		APIVersion:         "apps/v1",
+		Kind:               "Deployment",
+		Name:               "mydep",
+		UID:                "uid-dep",
+		Controller:         pointer.Bool(true),
+		BlockOwnerDeletion: pointer.Bool(true),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewController() = %v, want %v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		ownedNS string
+		ownerNS string
+		err     error
+	}{
+		{
+			name:    "same namespace",
+			ownedNS: "ns1",
+			ownerNS: "ns1",
+		},
+		{
+			name:    "cluster-scoped owner",
+			ownedNS: "ns1",
+			ownerNS: "",
+		},
+		{
+			name:    "cluster-scoped owner and owned",
+			ownedNS: "",
+			ownerNS: "",
+		},
+		{
+			name:    "cross namespace",
+			ownedNS: "ns1",
+			ownerNS: "ns2",
+			err:     ErrCrossNamespace,
+		},
+		{
+			name:    "cluster-scoped owned",
+			ownedNS: "",
+			ownerNS: "ns1",
+			err:     ErrClusterScopedDependent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owned := &corev1.Pod{}
+			owned.SetNamespace(tt.ownedNS)
+			owner := &corev1.ConfigMap{}
+			owner.SetNamespace(tt.ownerNS)
+			err := Validate(owned, owner)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Validate() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	refV1 := metav1.OwnerReference{
+		APIVersion: "mygroup.example.com/v1alpha1",
+		Kind:       "MyResource",
+		Name:       "myres",
+		UID:        "uid1",
+	}
+	refV2 := refV1
+	refV2.APIVersion = "mygroup.example.com/v1beta1"
+	other := metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       "cm",
+		UID:        "uid2",
+	}
+	controller := refV1
+	controller.Controller = pointer.Bool(true)
+	otherController := other
+	otherController.Controller = pointer.Bool(true)
+
+	tests := []struct {
+		name     string
+		existing []metav1.OwnerReference
+		ref      metav1.OwnerReference
+		want     []metav1.OwnerReference
+		err      error
+	}{
+		{
+			name: "add to empty list",
+			ref:  refV1,
+			want: []metav1.OwnerReference{refV1},
+		},
+		{
+			name:     "add is idempotent",
+			existing: []metav1.OwnerReference{refV1},
+			ref:      refV1,
+			want:     []metav1.OwnerReference{refV1},
+		},
+		{
+			name:     "same owner in another version is replaced",
+			existing: []metav1.OwnerReference{other, refV1},
+			ref:      refV2,
+			want:     []metav1.OwnerReference{other, refV2},
+		},
+		{
+			name:     "controller reference on already controlled object",
+			existing: []metav1.OwnerReference{otherController},
+			ref:      controller,
+			want:     []metav1.OwnerReference{otherController},
+			err:      ErrAlreadyControlled,
+		},
+		{
+			name:     "controller reference replaces reference to same owner",
+			existing: []metav1.OwnerReference{refV1, other},
+			ref:      controller,
+			want:     []metav1.OwnerReference{controller, other},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &corev1.Pod{}
+			obj.SetOwnerReferences(tt.existing)
+			err := Set(obj, tt.ref)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Set() = %v, want %v", err, tt.err)
+			}
+			if got := obj.GetOwnerReferences(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("owner references = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetFor(t *testing.T) {
+	owner := &corev1.ConfigMap{}
+	owner.SetName("cm")
+	owner.SetNamespace("ns1")
+	owner.SetUID("uid-cm")
+
+	obj := &corev1.Pod{}
+	obj.SetNamespace("ns2")
+	err := SetFor(obj, owner, scheme.Scheme, true)
+	if !errors.Is(err, ErrCrossNamespace) {
+		t.Errorf("SetFor() = %v, want %v", err, ErrCrossNamespace)
+	}
+
+	obj.SetNamespace("ns1")
+	err = SetFor(obj, owner, scheme.Scheme, true)
+	if err != nil {
+		t.Fatalf("SetFor() = %v, want nil", err)
+	}
+	ctrl := metav1.GetControllerOf(obj)
+	if ctrl == nil || ctrl.UID != "uid-cm" || ctrl.Kind != "ConfigMap" {
+		t.Errorf("controller = %v, want reference to ConfigMap cm", ctrl)
+	}
+}
+
+func TestRemoveAndReplace(t *testing.T) {
+	ref1 := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "cm1"}
+	ref2 := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "cm2"}
+	ref3 := metav1.OwnerReference{APIVersion: "v1", Kind: "Secret", Name: "secret"}
+
+	obj := &corev1.Pod{}
+	obj.SetOwnerReferences([]metav1.OwnerReference{ref1, ref2})
+
+	if !Replace(obj, ref1, ref3) {
+		t.Errorf("Replace() = false, want true")
+	}
+	want := []metav1.OwnerReference{ref3, ref2}
+	if got := obj.GetOwnerReferences(); !reflect.DeepEqual(got, want) {
+		t.Errorf("owner references = %v, want %v", got, want)
+	}
+
+	if Replace(obj, ref1, ref3) {
+		t.Errorf("Replace() of missing reference = true, want false")
+	}
+
+	// Replacing with an already referenced owner keeps a single reference
+	if !Replace(obj, ref3, ref2) {
+		t.Errorf("Replace() = false, want true")
+	}
+	want = []metav1.OwnerReference{ref2}
+	if got := obj.GetOwnerReferences(); !reflect.DeepEqual(got, want) {
+		t.Errorf("owner references = %v, want %v", got, want)
+	}
+
+	if !Remove(obj, ref2) {
+		t.Errorf("Remove() = false, want true")
+	}
+	if Remove(obj, ref2) {
+		t.Errorf("second Remove() = true, want false")
+	}
+	if IsOwnedBy(obj, ref2) {
+		t.Errorf("IsOwnedBy() = true after Remove()")
+	}
+	if got := obj.GetOwnerReferences(); len(got) != 0 {
+		t.Errorf("owner references = %v, want empty", got)
+	}
+}
@@ -0,0 +1,68 @@
+package capacity
+
+import (
+	"math"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var binarySuffixes = []string{"Ki", "Mi", "Gi", "Ti", "Pi", "Ei"}
+
+// Format returns a human readable representation of q,
+// depending on the resource:
+//   - cpu is displayed as a number of cores when it is a whole
+//     number of cores, in millicores otherwise ("2", "250m").
+//     A fraction of millicore is rounded up ("1u" is displayed "1m");
+//   - memory and storage are displayed with the greatest binary suffix
+//     giving a value of at least 1, with at most one decimal,
+//     rounded half away from zero ("1.5Gi", "512Mi", "1023").
+//     A fraction of byte is rounded up;
+//   - other resources are displayed in the canonical form of q.
+func Format(name corev1.ResourceName, q resource.Quantity) string {
+	switch name {
+	case corev1.ResourceCPU:
+		return formatCPU(q)
+	case corev1.ResourceMemory,
+		corev1.ResourceEphemeralStorage,
+		corev1.ResourceStorage:
+		return formatBytes(q)
+	}
+	return q.String()
+}
+
+func formatCPU(q resource.Quantity) string {
+	milli := q.MilliValue()
+	if milli%1000 == 0 {
+		return strconv.FormatInt(milli/1000, 10)
+	}
+	return strconv.FormatInt(milli, 10) + "m"
+}
+
+func formatBytes(q resource.Quantity) string {
+	value := q.Value()
+	sign := ""
+	abs := float64(value)
+	if value < 0 {
+		sign = "-"
+		abs = -abs
+	}
+	if abs < 1024 {
+		return sign + strconv.FormatInt(int64(abs), 10)
+	}
+
+	unit := 0
+	scaled := abs / 1024
+	for unit < len(binarySuffixes)-1 && roundTenth(scaled) >= 1024 {
+		unit++
+		scaled /= 1024
+	}
+	return sign +
+		strconv.FormatFloat(roundTenth(scaled), 'f', -1, 64) +
+		binarySuffixes[unit]
+}
+
+func roundTenth(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,71 @@
+package capacity
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource corev1.ResourceName
+		quantity resource.Quantity
+		want     string
+	}{
+		// cpu
+		{name: "cpu zero", resource: corev1.ResourceCPU, quantity: resource.MustParse("0"), want: "0"},
+		{name: "cpu zero value", resource: corev1.ResourceCPU, quantity: resource.Quantity{}, want: "0"},
+		{name: "cpu whole cores", resource: corev1.ResourceCPU, quantity: resource.MustParse("2"), want: "2"},
+		{name: "cpu cores as millicores", resource: corev1.ResourceCPU, quantity: resource.MustParse("2000m"), want: "2"},
+		{name: "cpu millicores", resource: corev1.ResourceCPU, quantity: resource.MustParse("250m"), want: "250m"},
+		{name: "cpu decimal cores", resource: corev1.ResourceCPU, quantity: resource.MustParse("1.5"), want: "1500m"},
+		{name: "cpu exponent", resource: corev1.ResourceCPU, quantity: resource.MustParse("5e-3"), want: "5m"},
+		{name: "cpu micro rounded up", resource: corev1.ResourceCPU, quantity: resource.MustParse("1u"), want: "1m"},
+		{name: "cpu fraction of millicore rounded up", resource: corev1.ResourceCPU, quantity: resource.MustParse("1.0001"), want: "1001m"},
+		{name: "cpu kilo", resource: corev1.ResourceCPU, quantity: resource.MustParse("1k"), want: "1000"},
+		{name: "cpu negative", resource: corev1.ResourceCPU, quantity: resource.MustParse("-250m"), want: "-250m"},
+		{name: "cpu negative cores", resource: corev1.ResourceCPU, quantity: resource.MustParse("-3"), want: "-3"},
+		{name: "cpu milli quantity", resource: corev1.ResourceCPU, quantity: *resource.NewMilliQuantity(1250, resource.DecimalSI), want: "1250m"},
+
+		// memory
+		{name: "memory zero", resource: corev1.ResourceMemory, quantity: resource.MustParse("0"), want: "0"},
+		{name: "memory zero value", resource: corev1.ResourceMemory, quantity: resource.Quantity{}, want: "0"},
+		{name: "memory bytes", resource: corev1.ResourceMemory, quantity: resource.MustParse("1023"), want: "1023"},
+		{name: "memory one kibibyte", resource: corev1.ResourceMemory, quantity: resource.MustParse("1024"), want: "1Ki"},
+		{name: "memory kibibytes", resource: corev1.ResourceMemory, quantity: resource.MustParse("1Ki"), want: "1Ki"},
+		{name: "memory mebibytes", resource: corev1.ResourceMemory, quantity: resource.MustParse("512Mi"), want: "512Mi"},
+		{name: "memory half gibibyte", resource: corev1.ResourceMemory, quantity: resource.MustParse("1536Mi"), want: "1.5Gi"},
+		{name: "memory decimal gigabyte", resource: corev1.ResourceMemory, quantity: resource.MustParse("1G"), want: "953.7Mi"},
+		{name: "memory decimal megabyte", resource: corev1.ResourceMemory, quantity: resource.MustParse("4M"), want: "3.8Mi"},
+		{name: "memory rounded half up", resource: corev1.ResourceMemory, quantity: resource.MustParse("1076Ki"), want: "1.1Mi"},
+		{name: "memory rounded down", resource: corev1.ResourceMemory, quantity: resource.MustParse("1070Ki"), want: "1Mi"},
+		{name: "memory rounding to next suffix", resource: corev1.ResourceMemory, quantity: resource.MustParse("1048575Ki"), want: "1Gi"},
+		{name: "memory just below rounding to next suffix", resource: corev1.ResourceMemory, quantity: resource.MustParse("1023.9Mi"), want: "1023.9Mi"},
+		{name: "memory in bytes", resource: corev1.ResourceMemory, quantity: resource.MustParse("128974848"), want: "123Mi"},
+		{name: "memory scientific notation", resource: corev1.ResourceMemory, quantity: resource.MustParse("1e3"), want: "1000"},
+		{name: "memory fraction of byte rounded up", resource: corev1.ResourceMemory, quantity: resource.MustParse("1.5"), want: "2"},
+		{name: "memory milli bytes rounded up", resource: corev1.ResourceMemory, quantity: resource.MustParse("100m"), want: "1"},
+		{name: "memory tebibytes", resource: corev1.ResourceMemory, quantity: resource.MustParse("2Ti"), want: "2Ti"},
+		{name: "memory exbibytes", resource: corev1.ResourceMemory, quantity: resource.MustParse("2Ei"), want: "2Ei"},
+		{name: "memory negative bytes", resource: corev1.ResourceMemory, quantity: resource.MustParse("-100"), want: "-100"},
+		{name: "memory negative", resource: corev1.ResourceMemory, quantity: resource.MustParse("-1536Mi"), want: "-1.5Gi"},
+		{name: "ephemeral storage", resource: corev1.ResourceEphemeralStorage, quantity: resource.MustParse("10Gi"), want: "10Gi"},
+		{name: "storage", resource: corev1.ResourceStorage, quantity: resource.MustParse("5Gi"), want: "5Gi"},
+
+		// other resources
+		{name: "pods", resource: corev1.ResourcePods, quantity: resource.MustParse("110"), want: "110"},
+		{name: "extended resource", resource: "nvidia.com/gpu", quantity: resource.MustParse("2"), want: "2"},
+		{name: "other decimal suffix", resource: "example.com/foo", quantity: resource.MustParse("4000"), want: "4k"},
+		{name: "other exponent form", resource: "example.com/foo", quantity: *resource.NewQuantity(4000, resource.DecimalExponent), want: "4e3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.resource, tt.quantity); got != tt.want {
+				t.Errorf("Format(%s, %s) = %q, want %q",
+					tt.resource, tt.quantity.String(), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package capacity
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// PodRequests returns the resources requested by a pod,
+// computed the way the scheduler does:
+// the requests of the containers are summed,
+// init containers running one after the other, the greatest request
+// of the init containers is used if greater than this sum,
+// and finally the pod overhead is added.
+func PodRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	return podResources(spec, func(c *corev1.Container) corev1.ResourceList {
+		return c.Resources.Requests
+	})
+}
+
+// PodLimits returns the resource limits of a pod,
+// computed the same way as PodRequests. A resource is left
+// out if a container or an init container has no limit for
+// it, the pod being unbounded for this resource.
+func PodLimits(spec *corev1.PodSpec) corev1.ResourceList {
+	result := podResources(spec, func(c *corev1.Container) corev1.ResourceList {
+		return c.Resources.Limits
+	})
+	for name := range result {
+		if !allLimited(spec, name) {
+			delete(result, name)
+		}
+	}
+	return result
+}
+
+// allLimited returns true if all the containers
+// and init containers have a limit for name
+func allLimited(spec *corev1.PodSpec, name corev1.ResourceName) bool {
+	for _, containers := range [][]corev1.Container{spec.Containers, spec.InitContainers} {
+		for i := range containers {
+			if _, found := containers[i].Resources.Limits[name]; !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func podResources(
+	spec *corev1.PodSpec,
+	get func(*corev1.Container) corev1.ResourceList,
+) corev1.ResourceList {
+	result := corev1.ResourceList{}
+	for i := range spec.Containers {
+		result = Add(result, get(&spec.Containers[i]))
+	}
+	for i := range spec.InitContainers {
+		result = Max(result, get(&spec.InitContainers[i]))
+	}
+	if spec.Overhead != nil {
+		result = Add(result, spec.Overhead)
+	}
+	return result
+}
+
+// IsTerminated returns true if the pod has terminated,
+// in which case its resources are not accounted
+// by the scheduler or by resource quotas
+func IsTerminated(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded ||
+		pod.Status.Phase == corev1.PodFailed
+}
+
+// Totals contains the resources requested by a set of pods
+type Totals struct {
+	// Pods is the number of non-terminated pods
+	Pods     int64
+	Requests corev1.ResourceList
+	Limits   corev1.ResourceList
+}
+
+// Sum returns the total requests and limits of the non-terminated
+// pods. The limits of a resource only include the pods limited
+// for this resource, see PodLimits.
+func Sum(pods []corev1.Pod) Totals {
+	result := Totals{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	for i := range pods {
+		if IsTerminated(&pods[i]) {
+			continue
+		}
+		result.Pods++
+		result.Requests = Add(result.Requests, PodRequests(&pods[i].Spec))
+		result.Limits = Add(result.Limits, PodLimits(&pods[i].Spec))
+	}
+	return result
+}
+
+// Usage is the usage of a resource, compared to its capacity
+type Usage struct {
+	Name     corev1.ResourceName
+	Used     resource.Quantity
+	Capacity resource.Quantity
+}
+
+// Exceeded returns true if the used quantity is greater than the capacity
+func (o Usage) Exceeded() bool {
+	return o.Used.Cmp(o.Capacity) > 0
+}
+
+// Percent returns the used quantity as a percentage of the capacity,
+// rounded down. It returns 0 if the capacity is zero.
+func (o Usage) Percent() int64 {
+	capacity := o.Capacity.AsApproximateFloat64()
+	if capacity == 0 {
+		return 0
+	}
+	return int64(o.Used.AsApproximateFloat64() * 100 / capacity)
+}
+
+// Compare returns the usage of each resource of capacity,
+// sorted by resource name
+func Compare(used, capacity corev1.ResourceList) []Usage {
+	result := make([]Usage, 0, len(capacity))
+	for _, name := range sortedNames(capacity) {
+		result = append(result, Usage{
+			Name:     name,
+			Used:     used[name].DeepCopy(),
+			Capacity: capacity[name].DeepCopy(),
+		})
+	}
+	return result
+}
+
+// NodeUsage compares the requests of the pods scheduled on node
+// to the allocatable resources of the node.
+// Pods not scheduled on the node are ignored.
+func NodeUsage(node *corev1.Node, pods []corev1.Pod) []Usage {
+	var onNode []corev1.Pod
+	for i := range pods {
+		if pods[i].Spec.NodeName == node.GetName() {
+			onNode = append(onNode, pods[i])
+		}
+	}
+	totals := Sum(onNode)
+	used := totals.Requests.DeepCopy()
+	used[corev1.ResourcePods] = *resource.NewQuantity(totals.Pods, resource.DecimalSI)
+	return Compare(used, node.Status.Allocatable)
+}
+
+// QuotaUsage compares the requests and limits of the pods
+// to the hard limits of quota. Only the resources of quota
+// related to the compute resources of pods and to the number
+// of pods are returned, other resources are ignored.
+// The pods are expected to be in the namespace of the quota.
+func QuotaUsage(quota *corev1.ResourceQuota, pods []corev1.Pod) []Usage {
+	totals := Sum(pods)
+	used := corev1.ResourceList{}
+	hard := corev1.ResourceList{}
+	for name, q := range quota.Spec.Hard {
+		u, found := quotaUsed(name, totals)
+		if !found {
+			continue
+		}
+		used[name] = u
+		hard[name] = q
+	}
+	return Compare(used, hard)
+}
+
+// quotaUsed returns the quantity used for the quota resource name
+func quotaUsed(name corev1.ResourceName, totals Totals) (resource.Quantity, bool) {
+	switch name {
+	case corev1.ResourcePods:
+		return *resource.NewQuantity(totals.Pods, resource.DecimalSI), true
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+		return totals.Requests[name], true
+	case corev1.ResourceRequestsStorage:
+		// storage is requested by persistent volume claims, not by pods
+		return resource.Quantity{}, false
+	}
+	if res, found := cutPrefix(name, "requests."); found {
+		return totals.Requests[res], true
+	}
+	if res, found := cutPrefix(name, "limits."); found {
+		return totals.Limits[res], true
+	}
+	return resource.Quantity{}, false
+}
+
+func cutPrefix(name corev1.ResourceName, prefix string) (corev1.ResourceName, bool) {
+	if !strings.HasPrefix(string(name), prefix) {
+		return name, false
+	}
+	return corev1.ResourceName(strings.TrimPrefix(string(name), prefix)), true
+}
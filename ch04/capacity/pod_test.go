@@ -0,0 +1,266 @@
+package capacity
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestPodRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		spec corev1.PodSpec
+		want map[corev1.ResourceName]string
+	}{
+		{
+			name: "no requests",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "main"}},
+			},
+			want: map[corev1.ResourceName]string{},
+		},
+		{
+			name: "containers are summed",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					container("250m", "64Mi"),
+					container("500m", "128Mi"),
+				},
+			},
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceCPU:    "750m",
+				corev1.ResourceMemory: "192Mi",
+			},
+		},
+		{
+			name: "greatest init container request is used when greater",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					container("1", "32Mi"),
+					container("100m", "16Mi"),
+				},
+				Containers: []corev1.Container{
+					container("250m", "64Mi"),
+					container("500m", "128Mi"),
+				},
+			},
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceCPU:    "1",
+				corev1.ResourceMemory: "192Mi",
+			},
+		},
+		{
+			name: "overhead is added",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					container("1", "32Mi"),
+				},
+				Containers: []corev1.Container{
+					container("250m", "64Mi"),
+				},
+				Overhead: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("16Mi"),
+				},
+			},
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceCPU:    "1100m",
+				corev1.ResourceMemory: "80Mi",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toStrings(PodRequests(&tt.spec))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PodRequests() = %v, want %v", got, tt.want)
+			}
+			// limits are set to the same values by container()
+			got = toStrings(PodLimits(&tt.spec))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PodLimits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodLimits_Unbounded(t *testing.T) {
+	unlimited := corev1.Container{Name: "sidecar"}
+	unlimited.Resources.Limits = corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("32Mi"),
+	}
+	tests := []struct {
+		name string
+		spec corev1.PodSpec
+		want map[corev1.ResourceName]string
+	}{
+		{
+			name: "container without cpu limit",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{container("250m", "64Mi"), unlimited},
+			},
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceMemory: "96Mi",
+			},
+		},
+		{
+			name: "init container without cpu limit",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{unlimited},
+				Containers:     []corev1.Container{container("250m", "64Mi")},
+			},
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceMemory: "64Mi",
+			},
+		},
+		{
+			name: "overhead of an unbounded resource",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{unlimited},
+				Overhead: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("100m"),
+					corev1.ResourceMemory: resource.MustParse("16Mi"),
+				},
+			},
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceMemory: "48Mi",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toStrings(PodLimits(&tt.spec))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PodLimits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	pods := []corev1.Pod{
+		pod("ns1", "node1", corev1.PodRunning, container("250m", "64Mi")),
+		pod("ns1", "node1", corev1.PodPending, container("250m", "64Mi")),
+		pod("ns1", "node1", corev1.PodSucceeded, container("1", "1Gi")),
+		pod("ns1", "node1", corev1.PodFailed, container("1", "1Gi")),
+	}
+	got := Sum(pods)
+	if got.Pods != 2 {
+		t.Errorf("Pods = %d, want 2", got.Pods)
+	}
+	want := map[corev1.ResourceName]string{
+		corev1.ResourceCPU:    "500m",
+		corev1.ResourceMemory: "128Mi",
+	}
+	if requests := toStrings(got.Requests); !reflect.DeepEqual(requests, want) {
+		t.Errorf("Requests = %v, want %v", requests, want)
+	}
+}
+
+func TestNodeUsage(t *testing.T) {
+	node := &corev1.Node{
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+		},
+	}
+	node.SetName("node1")
+	pods := []corev1.Pod{
+		pod("ns1", "node1", corev1.PodRunning, container("750m", "256Mi")),
+		pod("ns2", "node1", corev1.PodRunning, container("500m", "256Mi")),
+		pod("ns1", "node2", corev1.PodRunning, container("4", "4Gi")),
+	}
+
+	got := NodeUsage(node, pods)
+	want := []struct {
+		name     corev1.ResourceName
+		used     string
+		percent  int64
+		exceeded bool
+	}{
+		{name: corev1.ResourceCPU, used: "1250m", percent: 125, exceeded: true},
+		{name: corev1.ResourceMemory, used: "512Mi", percent: 50},
+		{name: corev1.ResourcePods, used: "2", percent: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("NodeUsage() = %v, want %d usages", got, len(want))
+	}
+	for i := range want {
+		if got[i].Name != want[i].name ||
+			got[i].Used.String() != want[i].used ||
+			got[i].Percent() != want[i].percent ||
+			got[i].Exceeded() != want[i].exceeded {
+			t.Errorf("usage %d = {%s %s %d%% %v}, want %v",
+				i, got[i].Name, got[i].Used.String(),
+				got[i].Percent(), got[i].Exceeded(), want[i])
+		}
+	}
+}
+
+func TestQuotaUsage(t *testing.T) {
+	quota := &corev1.ResourceQuota{
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{
+				corev1.ResourcePods:            resource.MustParse("10"),
+				corev1.ResourceCPU:             resource.MustParse("1"),
+				corev1.ResourceLimitsMemory:    resource.MustParse("1Gi"),
+				corev1.ResourceRequestsStorage: resource.MustParse("10Gi"),
+				"count/deployments.apps":       resource.MustParse("5"),
+			},
+		},
+	}
+	pods := []corev1.Pod{
+		pod("ns1", "node1", corev1.PodRunning, container("250m", "64Mi")),
+		pod("ns1", "node1", corev1.PodRunning, container("250m", "64Mi")),
+	}
+	got := map[corev1.ResourceName]string{}
+	for _, usage := range QuotaUsage(quota, pods) {
+		got[usage.Name] = usage.Used.String() + "/" + usage.Capacity.String()
+	}
+	want := map[corev1.ResourceName]string{
+		corev1.ResourceCPU:          "500m/1",
+		corev1.ResourceLimitsMemory: "128Mi/1Gi",
+		corev1.ResourcePods:         "2/10",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QuotaUsage() = %v, want %v", got, want)
+	}
+}
+
+// container returns a container with the same requests and limits
+func container(cpu, memory string) corev1.Container {
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+	return corev1.Container{
+		Name: "container",
+		Resources: corev1.ResourceRequirements{
+			Requests: resources,
+			Limits:   resources,
+		},
+	}
+}
+
+func pod(
+	namespace, node string,
+	phase corev1.PodPhase,
+	containers ...corev1.Container,
+) corev1.Pod {
+	result := corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName:   node,
+			Containers: containers,
+		},
+		Status: corev1.PodStatus{
+			Phase: phase,
+		},
+	}
+	result.SetNamespace(namespace)
+	return result
+}
@@ -0,0 +1,160 @@
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NamespaceReport contains the resources requested
+// by the pods of a namespace, and the usage of its quotas
+type NamespaceReport struct {
+	Namespace string
+	Totals
+	// Quotas contains the usage of each quota of the namespace,
+	// indexed by quota name
+	Quotas map[string][]Usage
+}
+
+// BuildReport returns the reports for the namespaces
+// containing pods or quotas, sorted by namespace
+func BuildReport(
+	pods []corev1.Pod,
+	quotas []corev1.ResourceQuota,
+) []NamespaceReport {
+	podsByNS := map[string][]corev1.Pod{}
+	for i := range pods {
+		ns := pods[i].GetNamespace()
+		podsByNS[ns] = append(podsByNS[ns], pods[i])
+	}
+	quotasByNS := map[string][]corev1.ResourceQuota{}
+	for i := range quotas {
+		ns := quotas[i].GetNamespace()
+		quotasByNS[ns] = append(quotasByNS[ns], quotas[i])
+	}
+
+	namespaces := make([]string, 0, len(podsByNS))
+	for ns := range podsByNS {
+		namespaces = append(namespaces, ns)
+	}
+	for ns := range quotasByNS {
+		if _, found := podsByNS[ns]; !found {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+
+	result := make([]NamespaceReport, 0, len(namespaces))
+	for _, ns := range namespaces {
+		report := NamespaceReport{
+			Namespace: ns,
+			Totals:    Sum(podsByNS[ns]),
+			Quotas:    map[string][]Usage{},
+		}
+		for i := range quotasByNS[ns] {
+			quota := &quotasByNS[ns][i]
+			report.Quotas[quota.GetName()] = QuotaUsage(quota, podsByNS[ns])
+		}
+		result = append(result, report)
+	}
+	return result
+}
+
+// Report lists the pods and resource quotas of all namespaces
+// and returns the report for each namespace
+func Report(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+) ([]NamespaceReport, error) {
+	pods, err := clientset.CoreV1().
+		Pods("").
+		List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	quotas, err := clientset.CoreV1().
+		ResourceQuotas("").
+		List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return BuildReport(pods.Items, quotas.Items), nil
+}
+
+// Print writes the reports to w, as a table of the requests
+// and limits of each namespace, followed by a table
+// of the usage of the quotas
+func Print(w io.Writer, reports []NamespaceReport) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tPODS\tCPU REQUESTS\tCPU LIMITS\tMEMORY REQUESTS\tMEMORY LIMITS")
+	for _, report := range reports {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n",
+			report.Namespace,
+			report.Pods,
+			Format(corev1.ResourceCPU, report.Requests[corev1.ResourceCPU]),
+			Format(corev1.ResourceCPU, report.Limits[corev1.ResourceCPU]),
+			Format(corev1.ResourceMemory, report.Requests[corev1.ResourceMemory]),
+			Format(corev1.ResourceMemory, report.Limits[corev1.ResourceMemory]),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if !hasQuotas(reports) {
+		return nil
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "NAMESPACE\tQUOTA\tRESOURCE\tUSED\tHARD\tUSE")
+	for _, report := range reports {
+		names := make([]string, 0, len(report.Quotas))
+		for name := range report.Quotas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, usage := range report.Quotas[name] {
+				use := fmt.Sprintf("%d%%", usage.Percent())
+				if usage.Exceeded() {
+					use += " (exceeded)"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					report.Namespace,
+					name,
+					usage.Name,
+					Format(quotaResource(usage.Name), usage.Used),
+					Format(quotaResource(usage.Name), usage.Capacity),
+					use,
+				)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+func hasQuotas(reports []NamespaceReport) bool {
+	for _, report := range reports {
+		if len(report.Quotas) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// quotaResource returns the compute resource
+// related to the quota resource name
+func quotaResource(name corev1.ResourceName) corev1.ResourceName {
+	if res, found := cutPrefix(name, "requests."); found {
+		return res
+	}
+	if res, found := cutPrefix(name, "limits."); found {
+		return res
+	}
+	return name
+}
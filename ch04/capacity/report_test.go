@@ -0,0 +1,53 @@
+package capacity
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReport(t *testing.T) {
+	pod1 := pod("default", "node1", corev1.PodRunning, container("250m", "64Mi"))
+	pod1.SetName("pod1")
+	pod2 := pod("default", "node1", corev1.PodRunning, container("500m", "128Mi"))
+	pod2.SetName("pod2")
+	pod3 := pod("project1", "node1", corev1.PodRunning, container("2", "1536Mi"))
+	pod3.SetName("pod3")
+	quota := corev1.ResourceQuota{
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{
+				corev1.ResourceRequestsCPU:  resource.MustParse("1"),
+				corev1.ResourceLimitsMemory: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	quota.SetName("compute")
+	quota.SetNamespace("project1")
+
+	clientset := fake.NewSimpleClientset(&pod1, &pod2, &pod3, &quota)
+	reports, err := Report(context.Background(), clientset)
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+
+	var buf bytes.Buffer
+	err = Print(&buf, reports)
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	want := `NAMESPACE  PODS  CPU REQUESTS  CPU LIMITS  MEMORY REQUESTS  MEMORY LIMITS
+default    2     750m          750m        192Mi            192Mi
+project1   1     2             2           1.5Gi            1.5Gi
+
+NAMESPACE  QUOTA    RESOURCE       USED   HARD  USE
+project1   compute  limits.memory  1.5Gi  1Gi   150% (exceeded)
+project1   compute  requests.cpu   2      1     200% (exceeded)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Print() =\n%s\nwant\n%s", got, want)
+	}
+}
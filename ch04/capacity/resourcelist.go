@@ -0,0 +1,91 @@
+// Package capacity sums the resources requested by pods,
+// compares them to the capacity of nodes and to resource quotas,
+// and reports the usage of resources per namespace.
+package capacity
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Add returns a new ResourceList containing the sum of a and b
+func Add(a, b corev1.ResourceList) corev1.ResourceList {
+	result := a.DeepCopy()
+	if result == nil {
+		result = corev1.ResourceList{}
+	}
+	for name, qb := range b {
+		if qa, found := result[name]; found {
+			qa.Add(qb)
+			result[name] = qa
+		} else {
+			result[name] = qb.DeepCopy()
+		}
+	}
+	return result
+}
+
+// Sub returns a new ResourceList containing a minus b.
+// Resources absent from a are considered to be zero,
+// and the result can contain negative quantities.
+func Sub(a, b corev1.ResourceList) corev1.ResourceList {
+	result := a.DeepCopy()
+	if result == nil {
+		result = corev1.ResourceList{}
+	}
+	for name, qb := range b {
+		qa := result[name]
+		qa.Sub(qb)
+		result[name] = qa
+	}
+	return result
+}
+
+// Max returns a new ResourceList containing,
+// for each resource, the greatest quantity between a and b
+func Max(a, b corev1.ResourceList) corev1.ResourceList {
+	result := a.DeepCopy()
+	if result == nil {
+		result = corev1.ResourceList{}
+	}
+	for name, qb := range b {
+		if qa, found := result[name]; !found || qb.Cmp(qa) > 0 {
+			result[name] = qb.DeepCopy()
+		}
+	}
+	return result
+}
+
+// Fits returns true if, for each resource in requests,
+// the requested quantity is lower or equal to the quantity in capacity.
+// A resource absent from capacity is considered to have a zero capacity.
+// The names of the resources exceeding the capacity are returned, sorted.
+func Fits(
+	requests, capacity corev1.ResourceList,
+) (bool, []corev1.ResourceName) {
+	var exceeded []corev1.ResourceName
+	for name, req := range requests {
+		c := capacity[name]
+		if req.Cmp(c) > 0 {
+			exceeded = append(exceeded, name)
+		}
+	}
+	sortNames(exceeded)
+	return len(exceeded) == 0, exceeded
+}
+
+func sortedNames(list corev1.ResourceList) []corev1.ResourceName {
+	result := make([]corev1.ResourceName, 0, len(list))
+	for name := range list {
+		result = append(result, name)
+	}
+	sortNames(result)
+	return result
+}
+
+func sortNames(names []corev1.ResourceName) {
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+}
@@ -0,0 +1,140 @@
+package capacity
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestAddSubMax(t *testing.T) {
+	a := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("250m"),
+		corev1.ResourceMemory: resource.MustParse("64Mi"),
+	}
+	b := corev1.ResourceList{
+		corev1.ResourceCPU:              resource.MustParse("1"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+	}
+
+	tests := []struct {
+		name string
+		got  corev1.ResourceList
+		want map[corev1.ResourceName]string
+	}{
+		{
+			name: "add",
+			got:  Add(a, b),
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceCPU:              "1250m",
+				corev1.ResourceMemory:           "64Mi",
+				corev1.ResourceEphemeralStorage: "1Gi",
+			},
+		},
+		{
+			name: "add to nil",
+			got:  Add(nil, a),
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceCPU:    "250m",
+				corev1.ResourceMemory: "64Mi",
+			},
+		},
+		{
+			name: "sub",
+			got:  Sub(a, b),
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceCPU:              "-750m",
+				corev1.ResourceMemory:           "64Mi",
+				corev1.ResourceEphemeralStorage: "-1Gi",
+			},
+		},
+		{
+			name: "max",
+			got:  Max(a, b),
+			want: map[corev1.ResourceName]string{
+				corev1.ResourceCPU:              "1",
+				corev1.ResourceMemory:           "64Mi",
+				corev1.ResourceEphemeralStorage: "1Gi",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toStrings(tt.got); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Operands must not be modified
+	if got := a[corev1.ResourceCPU]; got.String() != "250m" {
+		t.Errorf("a has been modified: %s", got.String())
+	}
+	if got := b[corev1.ResourceCPU]; got.String() != "1" {
+		t.Errorf("b has been modified: %s", got.String())
+	}
+}
+
+func TestFits(t *testing.T) {
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+	tests := []struct {
+		name     string
+		requests corev1.ResourceList
+		want     bool
+		exceeded []corev1.ResourceName
+	}{
+		{
+			name: "fits",
+			requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1500m"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+			want: true,
+		},
+		{
+			name: "exceeds",
+			requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("2001m"),
+				corev1.ResourceMemory: resource.MustParse("1025Mi"),
+			},
+			exceeded: []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory},
+		},
+		{
+			name: "resource absent from capacity",
+			requests: corev1.ResourceList{
+				"nvidia.com/gpu": resource.MustParse("1"),
+			},
+			exceeded: []corev1.ResourceName{"nvidia.com/gpu"},
+		},
+		{
+			name: "zero request of resource absent from capacity",
+			requests: corev1.ResourceList{
+				"nvidia.com/gpu": resource.MustParse("0"),
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exceeded := Fits(tt.requests, capacity)
+			if got != tt.want {
+				t.Errorf("Fits() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(exceeded, tt.exceeded) {
+				t.Errorf("exceeded = %v, want %v", exceeded, tt.exceeded)
+			}
+		})
+	}
+}
+
+func toStrings(list corev1.ResourceList) map[corev1.ResourceName]string {
+	result := map[corev1.ResourceName]string{}
+	for name, q := range list {
+		result[name] = q.String()
+	}
+	return result
+}
@@ -5,9 +5,12 @@ import (
 
 	"gopkg.in/inf.v0"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
+
+	"github.com/kprogo/ch4/capacity"
 )
 
 func main() {
@@ -101,6 +104,20 @@ func main() {
 	eq = q9.Equal(q10)
 	fmt.Printf("4M ==? 3M: %v\n", eq) // false
 
+	// ## Operations on ResourceLists
+	requests := capacity.Add(
+		corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+		corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("1Gi"),
+		},
+	)
+	fmt.Printf("64Mi + 1Gi: %s\n", capacity.Format(
+		corev1.ResourceMemory,
+		requests[corev1.ResourceMemory],
+	)) // 1.1Gi
+
 	// # IntOrString
 	ios1 := intstr.FromInt(10)
 	fmt.Printf("ios1: %s\n", ios1.String()) // 10
@@ -0,0 +1,83 @@
+// Command convert converts the objects of Kubernetes manifests
+// to another version of their group, e.g. apps/v1beta1 Deployments
+// to apps/v1 Deployments, and reports the fields which cannot
+// be represented in the target version.
+//
+// Usage:
+//
+//	convert -to apps/v1 [-f manifests.yaml]
+//
+// The converted manifests are written to the standard output,
+// the report to the standard error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kprogo/ch5/convert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func main() {
+	to := flag.String("to", "", "target group version, e.g. apps/v1")
+	filename := flag.String("f", "-", "manifests to convert, - for standard input")
+	flag.Parse()
+
+	if err := run(*to, *filename); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(to, filename string) error {
+	if to == "" {
+		return fmt.Errorf("the target version must be specified with -to")
+	}
+	target, err := schema.ParseGroupVersion(to)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	scheme, err := convert.NewScheme()
+	if err != nil {
+		return err
+	}
+	results, err := convert.NewConverter(scheme).
+		ConvertManifests(in, os.Stdout, target)
+	for _, result := range results {
+		report(os.Stderr, result)
+	}
+	return err
+}
+
+func report(w io.Writer, result convert.Result) {
+	name := result.Name
+	if result.Namespace != "" {
+		name = result.Namespace + "/" + name
+	}
+	from := result.From.GroupVersion().String()
+	if result.Skipped != "" {
+		fmt.Fprintf(w, "document %d: %s %s (%s): skipped, %s\n",
+			result.Index, result.From.Kind, name, from, result.Skipped)
+		return
+	}
+	fmt.Fprintf(w, "document %d: %s %s: %s -> %s\n",
+		result.Index, result.From.Kind, name,
+		from, result.To.GroupVersion().String())
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "  warning: %s\n", warning)
+	}
+}
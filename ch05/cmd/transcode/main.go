@@ -0,0 +1,147 @@
+// Command transcode converts Kubernetes objects among the JSON,
+// YAML and Protobuf formats, and measures the size and
+// encoding and decoding times of the objects in each format.
+//
+// Usage:
+//
+//	transcode -to protobuf [-from yaml] [-f manifest.yaml] > object.pb
+//	transcode -measure [-n 1000] [-f manifest.yaml]
+//
+// JSON and YAML input can contain several objects, Protobuf
+// input and output are limited to a single object.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kprogo/ch5/decoder"
+	"github.com/kprogo/ch5/transcode"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func main() {
+	filename := flag.String("f", "-", "file containing the objects, - for standard input")
+	from := flag.String("from", "", "format of the input: json, yaml or protobuf, detected if not set")
+	to := flag.String("to", "yaml", "format of the output: json, yaml or protobuf")
+	measure := flag.Bool("measure", false, "measure the objects in all formats instead of transcoding them")
+	iterations := flag.Int("n", 100, "number of encodings and decodings to measure the times")
+	flag.Parse()
+
+	if err := run(*filename, *from, *to, *measure, *iterations); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(filename, from, to string, measure bool, iterations int) error {
+	data, err := readInput(filename)
+	if err != nil {
+		return err
+	}
+	inputFormat := transcode.Detect(data)
+	if from != "" {
+		inputFormat, err = transcode.ParseFormat(from)
+		if err != nil {
+			return err
+		}
+	}
+	outputFormat, err := transcode.ParseFormat(to)
+	if err != nil {
+		return err
+	}
+
+	scheme, err := decoder.NewScheme()
+	if err != nil {
+		return err
+	}
+	transcoder := transcode.New(scheme)
+	objects, err := readObjects(scheme, transcoder, data, inputFormat, filename)
+	if err != nil {
+		return err
+	}
+
+	if measure {
+		for i, obj := range objects {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(describe(obj))
+			err = transcode.PrintMeasures(os.Stdout, transcoder.MeasureAll(obj, iterations))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if outputFormat == transcode.Protobuf && len(objects) != 1 {
+		return fmt.Errorf("protobuf output requires a single object, found %d", len(objects))
+	}
+	for i, obj := range objects {
+		out, err := transcoder.Encode(obj, outputFormat)
+		if err != nil {
+			return fmt.Errorf("%s: %w", describe(obj), err)
+		}
+		if i > 0 && outputFormat == transcode.YAML {
+			fmt.Println("---")
+		}
+		if _, err = os.Stdout.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readInput(filename string) ([]byte, error) {
+	if filename == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(filename)
+}
+
+// readObjects decodes the objects of the input: a single object
+// for Protobuf, all the objects of the manifests for JSON and YAML
+func readObjects(
+	scheme *runtime.Scheme,
+	transcoder *transcode.Transcoder,
+	data []byte,
+	format transcode.Format,
+	filename string,
+) ([]runtime.Object, error) {
+	if format == transcode.Protobuf {
+		obj, err := transcoder.Decode(data, format)
+		if err != nil {
+			return nil, err
+		}
+		return []runtime.Object{obj}, nil
+	}
+
+	objects, err := decoder.New(scheme, decoder.Options{}).
+		DecodeAll(bytes.NewReader(data), filename)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]runtime.Object, 0, len(objects))
+	for _, obj := range objects {
+		result = append(result, obj.Object)
+	}
+	return result, nil
+}
+
+func describe(obj runtime.Object) string {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return kind
+	}
+	name := accessor.GetName()
+	if ns := accessor.GetNamespace(); ns != "" {
+		name = ns + "/" + name
+	}
+	return kind + " " + name
+}
@@ -0,0 +1,315 @@
+// Package convert converts Kubernetes manifests
+// to another version of their group, using the conversion
+// functions registered into a runtime.Scheme
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	jsonserializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Warning describes a field of an object which cannot
+// be represented as is in the target version
+type Warning struct {
+	// Field is the path of the field in the source object
+	Field   string
+	Message string
+}
+
+func (o Warning) String() string {
+	return o.Field + ": " + o.Message
+}
+
+// Warnings collects the warnings of a conversion.
+// A *Warnings is passed as context to Scheme.Convert,
+// so the conversion functions can record warnings.
+type Warnings []Warning
+
+// Add records a warning for field. It does nothing
+// if o is nil, i.e. if no *Warnings has been passed
+// to Scheme.Convert
+func (o *Warnings) Add(field, message string) {
+	if o == nil {
+		return
+	}
+	*o = append(*o, Warning{Field: field, Message: message})
+}
+
+// Addf records a warning for field, with a formatted message
+func (o *Warnings) Addf(field, format string, args ...interface{}) {
+	o.Add(field, fmt.Sprintf(format, args...))
+}
+
+// warningsFrom returns the *Warnings passed as context
+// to Scheme.Convert, or nil
+func warningsFrom(scope conversion.Scope) *Warnings {
+	if scope == nil || scope.Meta() == nil {
+		return nil
+	}
+	warnings, _ := scope.Meta().Context.(*Warnings)
+	return warnings
+}
+
+// NewScheme returns a scheme containing the core/v1, apps/v1
+// and apps/v1beta1 types, and the conversion functions
+// between apps/v1 and apps/v1beta1 Deployments
+func NewScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	builder := runtime.NewSchemeBuilder(
+		corev1.AddToScheme,
+		appsv1.AddToScheme,
+		appsv1beta1.AddToScheme,
+		AddDeploymentConversions,
+	)
+	if err := builder.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return scheme, nil
+}
+
+// Converter converts objects to another version of their group
+type Converter struct {
+	scheme       *runtime.Scheme
+	deserializer runtime.Decoder
+	yamlEncoder  runtime.Encoder
+}
+
+// NewConverter returns a Converter using the types
+// and conversion functions registered into scheme
+func NewConverter(scheme *runtime.Scheme) *Converter {
+	return &Converter{
+		scheme:       scheme,
+		deserializer: serializer.NewCodecFactory(scheme).UniversalDeserializer(),
+		yamlEncoder: jsonserializer.NewSerializerWithOptions(
+			jsonserializer.DefaultMetaFactory,
+			scheme,
+			scheme,
+			jsonserializer.SerializerOptions{Yaml: true},
+		),
+	}
+}
+
+// ErrOtherGroup is returned when converting an object
+// to a version of another group
+var ErrOtherGroup = errors.New("target version is in another group")
+
+// ErrNotServed is returned when converting an object
+// to a version which does not define its kind
+var ErrNotServed = errors.New("kind not served by the target version")
+
+// ErrNoConversion is returned when no conversion function
+// is registered from the kind of an object to the target version
+var ErrNoConversion = errors.New("no conversion to the target version")
+
+// Convert converts obj to the target version of its group,
+// and returns the converted object and the warnings recorded
+// by the conversion functions. obj is returned as is
+// if it is already in the target version.
+func (o *Converter) Convert(
+	obj runtime.Object,
+	target schema.GroupVersion,
+) (runtime.Object, Warnings, error) {
+	gvks, _, err := o.scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	gvk := gvks[0]
+	if gvk.GroupVersion() == target {
+		return obj, nil, nil
+	}
+	if gvk.Group != target.Group {
+		return nil, nil, fmt.Errorf("converting %s to %s: %w", gvk, target, ErrOtherGroup)
+	}
+
+	targetGVK := target.WithKind(gvk.Kind)
+	out, err := o.scheme.New(targetGVK)
+	if runtime.IsNotRegisteredError(err) {
+		return nil, nil, fmt.Errorf("converting %s to %s: %w", gvk, target, ErrNotServed)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var warnings Warnings
+	err = o.scheme.Convert(obj, out, &warnings)
+	// the converter returns an untyped error
+	// when no conversion function is registered
+	if err != nil && strings.HasSuffix(err.Error(), "unknown conversion") {
+		return nil, nil, fmt.Errorf("converting %s to %s: %w", gvk, target, ErrNoConversion)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	out.GetObjectKind().SetGroupVersionKind(targetGVK)
+	return out, warnings, nil
+}
+
+// Result is the result of the conversion of a document
+type Result struct {
+	// Index is the index of the document in the manifests, from 0
+	Index     int
+	Name      string
+	Namespace string
+	// From is the kind of the document,
+	// To is its kind after conversion
+	From schema.GroupVersionKind
+	To   schema.GroupVersionKind
+	// Warnings lists the fields which cannot be represented
+	// as is in the target version
+	Warnings Warnings
+	// Skipped explains why the document has not been converted:
+	// its kind is not registered, it is in another group, or it
+	// cannot be converted to the target version
+	Skipped string
+}
+
+// ConvertManifests reads multi-document YAML or JSON manifests from r,
+// converts the objects of the target group to the target version,
+// and writes them to w as multi-document YAML.
+// The documents of kinds not registered into the scheme, of other
+// groups, or which cannot be converted to the target version
+// are written unchanged. It returns the result of the conversion
+// of each non-empty document.
+func (o *Converter) ConvertManifests(
+	r io.Reader,
+	w io.Writer,
+	target schema.GroupVersion,
+) ([]Result, error) {
+	reader := yaml.NewYAMLReader(bufio.NewReader(r))
+	var results []Result
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return results, err
+		}
+		if isEmpty(doc) {
+			continue
+		}
+
+		index := len(results)
+		result, out, err := o.convertDocument(doc, target)
+		if err != nil {
+			return results, fmt.Errorf("document %d: %w", index, err)
+		}
+		result.Index = index
+		results = append(results, result)
+
+		if index > 0 {
+			if _, err = io.WriteString(w, "---\n"); err != nil {
+				return results, err
+			}
+		}
+		if _, err = w.Write(out); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// convertDocument converts a single document, and returns
+// the result and the converted document as YAML
+func (o *Converter) convertDocument(
+	doc []byte,
+	target schema.GroupVersion,
+) (Result, []byte, error) {
+	obj, gvk, err := o.deserializer.Decode(doc, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		return unregisteredDocument(doc)
+	}
+	if err != nil {
+		return Result{}, nil, err
+	}
+
+	result := Result{From: *gvk}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		result.Name = accessor.GetName()
+		result.Namespace = accessor.GetNamespace()
+	}
+
+	converted, warnings, err := o.Convert(obj, target)
+	if errors.Is(err, ErrOtherGroup) {
+		result.Skipped = "not in group " + groupName(target.Group)
+		converted = obj
+	} else if errors.Is(err, ErrNotServed) {
+		result.Skipped = "kind not served by " + target.String()
+		converted = obj
+	} else if errors.Is(err, ErrNoConversion) {
+		result.Skipped = "no conversion to " + target.String()
+		converted = obj
+	} else if err != nil {
+		return Result{}, nil, err
+	} else {
+		result.To = converted.GetObjectKind().GroupVersionKind()
+		result.Warnings = warnings
+	}
+
+	var buffer bytes.Buffer
+	err = o.yamlEncoder.Encode(converted, &buffer)
+	if err != nil {
+		return Result{}, nil, err
+	}
+	return result, buffer.Bytes(), nil
+}
+
+// unregisteredDocument returns the result for a document
+// of a kind not registered into the scheme,
+// and the unchanged document as YAML
+func unregisteredDocument(doc []byte) (Result, []byte, error) {
+	j, err := yaml.ToJSON(doc)
+	if err != nil {
+		return Result{}, nil, err
+	}
+	gvk, err := jsonserializer.DefaultMetaFactory.Interpret(j)
+	if err != nil {
+		return Result{}, nil, err
+	}
+	result := Result{
+		From:    *gvk,
+		Skipped: "kind not registered",
+	}
+	u := unstructured.Unstructured{}
+	if err = u.UnmarshalJSON(j); err == nil {
+		result.Name = u.GetName()
+		result.Namespace = u.GetNamespace()
+	}
+	out, err := sigsyaml.JSONToYAML(j)
+	return result, out, err
+}
+
+// isEmpty returns true if the document contains
+// only spaces and comments
+func isEmpty(doc []byte) bool {
+	for _, line := range bytes.Split(doc, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return false
+		}
+	}
+	return true
+}
+
+func groupName(group string) string {
+	if group == "" {
+		return "core"
+	}
+	return group
+}
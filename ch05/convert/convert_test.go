@@ -0,0 +1,155 @@
+package convert
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+)
+
+const manifests = `# a v1beta1 deployment
+apiVersion: apps/v1beta1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+spec:
+  revisionHistoryLimit: 5
+  rollbackTo:
+    revision: 1
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - image: nginx
+        name: nginx
+---
+---
+{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "nginx"}}
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: foo
+`
+
+const wantManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  creationTimestamp: null
+  name: nginx
+  namespace: default
+spec:
+  revisionHistoryLimit: 5
+  selector:
+    matchLabels:
+      app: nginx
+  strategy: {}
+  template:
+    metadata:
+      creationTimestamp: null
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - image: nginx
+        name: nginx
+        resources: {}
+status: {}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  creationTimestamp: null
+  name: nginx
+spec: {}
+status:
+  loadBalancer: {}
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: foo
+`
+
+func TestConverter_ConvertManifests(t *testing.T) {
+	var out bytes.Buffer
+	results, err := newConverter(t).ConvertManifests(
+		strings.NewReader(manifests), &out, appsv1.SchemeGroupVersion,
+	)
+	if err != nil {
+		t.Fatalf("ConvertManifests() error = %v", err)
+	}
+	if out.String() != wantManifests {
+		t.Errorf("ConvertManifests() output:\n%s\nwant:\n%s", out.String(), wantManifests)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("ConvertManifests() returned %d results, want 3", len(results))
+	}
+	deploy := results[0]
+	if deploy.Index != 0 || deploy.Name != "nginx" || deploy.Namespace != "default" ||
+		deploy.From.Version != "v1beta1" || deploy.To.Version != "v1" ||
+		deploy.Skipped != "" {
+		t.Errorf("unexpected result for deployment: %+v", deploy)
+	}
+	if len(deploy.Warnings) != 2 ||
+		deploy.Warnings[0].Field != "spec.selector" ||
+		deploy.Warnings[1].Field != "spec.rollbackTo" {
+		t.Errorf("unexpected warnings for deployment: %v", deploy.Warnings)
+	}
+	if results[1].Index != 1 || results[1].Skipped != "not in group apps" {
+		t.Errorf("unexpected result for service: %+v", results[1])
+	}
+	if results[2].Index != 2 || results[2].Skipped != "kind not registered" ||
+		results[2].From.Kind != "Unknown" {
+		t.Errorf("unexpected result for unknown kind: %+v", results[2])
+	}
+}
+
+func TestConverter_ConvertManifestsSkipped(t *testing.T) {
+	// apps/v1beta1 does not define DaemonSets, and
+	// no conversion is registered for StatefulSets
+	manifest := `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: ds
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: sts
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deploy
+`
+	var out bytes.Buffer
+	results, err := newConverter(t).ConvertManifests(
+		strings.NewReader(manifest), &out, appsv1beta1.SchemeGroupVersion,
+	)
+	if err != nil {
+		t.Fatalf("ConvertManifests() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("ConvertManifests() returned %d results, want 3", len(results))
+	}
+	if got := results[0].Skipped; got != "kind not served by apps/v1beta1" {
+		t.Errorf("daemon set skipped = %q", got)
+	}
+	if got := results[1].Skipped; got != "no conversion to apps/v1beta1" {
+		t.Errorf("stateful set skipped = %q", got)
+	}
+	if results[2].Skipped != "" || results[2].To != appsv1beta1.SchemeGroupVersion.WithKind("Deployment") {
+		t.Errorf("unexpected result for deployment: %+v", results[2])
+	}
+	if strings.Count(out.String(), "apiVersion: apps/v1\n") != 2 ||
+		strings.Count(out.String(), "apiVersion: apps/v1beta1\n") != 1 {
+		t.Errorf("ConvertManifests() output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,163 @@
+package convert
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AddDeploymentConversions registers the conversion functions
+// between apps/v1 and apps/v1beta1 Deployments into scheme
+func AddDeploymentConversions(scheme *runtime.Scheme) error {
+	err := scheme.AddConversionFunc(
+		(*appsv1.Deployment)(nil),
+		(*appsv1beta1.Deployment)(nil),
+		func(a, b interface{}, scope conversion.Scope) error {
+			return convertV1ToV1beta1Deployment(
+				a.(*appsv1.Deployment),
+				b.(*appsv1beta1.Deployment),
+				scope,
+			)
+		})
+	if err != nil {
+		return err
+	}
+	return scheme.AddConversionFunc(
+		(*appsv1beta1.Deployment)(nil),
+		(*appsv1.Deployment)(nil),
+		func(a, b interface{}, scope conversion.Scope) error {
+			return convertV1beta1ToV1Deployment(
+				a.(*appsv1beta1.Deployment),
+				b.(*appsv1.Deployment),
+				scope,
+			)
+		})
+}
+
+func convertV1ToV1beta1Deployment(
+	in *appsv1.Deployment,
+	out *appsv1beta1.Deployment,
+	scope conversion.Scope,
+) error {
+	warnings := warningsFrom(scope)
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+
+	spec := in.Spec.DeepCopy()
+	out.Spec = appsv1beta1.DeploymentSpec{
+		Replicas:                spec.Replicas,
+		Selector:                spec.Selector,
+		Template:                spec.Template,
+		MinReadySeconds:         spec.MinReadySeconds,
+		RevisionHistoryLimit:    spec.RevisionHistoryLimit,
+		Paused:                  spec.Paused,
+		ProgressDeadlineSeconds: spec.ProgressDeadlineSeconds,
+		Strategy: appsv1beta1.DeploymentStrategy{
+			Type: appsv1beta1.DeploymentStrategyType(spec.Strategy.Type),
+		},
+	}
+	if ru := spec.Strategy.RollingUpdate; ru != nil {
+		out.Spec.Strategy.RollingUpdate = &appsv1beta1.RollingUpdateDeployment{
+			MaxUnavailable: ru.MaxUnavailable,
+			MaxSurge:       ru.MaxSurge,
+		}
+	}
+	if spec.RevisionHistoryLimit == nil {
+		warnings.Add("spec.revisionHistoryLimit",
+			"not set, defaults to 10 in apps/v1 but to 2 in apps/v1beta1")
+	}
+
+	status := in.Status.DeepCopy()
+	out.Status = appsv1beta1.DeploymentStatus{
+		ObservedGeneration:  status.ObservedGeneration,
+		Replicas:            status.Replicas,
+		UpdatedReplicas:     status.UpdatedReplicas,
+		ReadyReplicas:       status.ReadyReplicas,
+		AvailableReplicas:   status.AvailableReplicas,
+		UnavailableReplicas: status.UnavailableReplicas,
+		CollisionCount:      status.CollisionCount,
+	}
+	for _, c := range status.Conditions {
+		out.Status.Conditions = append(out.Status.Conditions,
+			appsv1beta1.DeploymentCondition{
+				Type:               appsv1beta1.DeploymentConditionType(c.Type),
+				Status:             c.Status,
+				LastUpdateTime:     c.LastUpdateTime,
+				LastTransitionTime: c.LastTransitionTime,
+				Reason:             c.Reason,
+				Message:            c.Message,
+			})
+	}
+	return nil
+}
+
+func convertV1beta1ToV1Deployment(
+	in *appsv1beta1.Deployment,
+	out *appsv1.Deployment,
+	scope conversion.Scope,
+) error {
+	warnings := warningsFrom(scope)
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+
+	spec := in.Spec.DeepCopy()
+	out.Spec = appsv1.DeploymentSpec{
+		Replicas:                spec.Replicas,
+		Selector:                spec.Selector,
+		Template:                spec.Template,
+		MinReadySeconds:         spec.MinReadySeconds,
+		RevisionHistoryLimit:    spec.RevisionHistoryLimit,
+		Paused:                  spec.Paused,
+		ProgressDeadlineSeconds: spec.ProgressDeadlineSeconds,
+		Strategy: appsv1.DeploymentStrategy{
+			Type: appsv1.DeploymentStrategyType(spec.Strategy.Type),
+		},
+	}
+	if ru := spec.Strategy.RollingUpdate; ru != nil {
+		out.Spec.Strategy.RollingUpdate = &appsv1.RollingUpdateDeployment{
+			MaxUnavailable: ru.MaxUnavailable,
+			MaxSurge:       ru.MaxSurge,
+		}
+	}
+	if spec.Selector == nil && len(spec.Template.Labels) > 0 {
+		// apps/v1beta1 defaults the selector
+		// to the labels of the template
+		out.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: spec.Template.Labels,
+		}
+		warnings.Add("spec.selector",
+			"not set, required in apps/v1, set to the labels of the pod template")
+	}
+	if spec.RevisionHistoryLimit == nil {
+		warnings.Add("spec.revisionHistoryLimit",
+			"not set, defaults to 2 in apps/v1beta1 but to 10 in apps/v1")
+	}
+	if spec.RollbackTo != nil {
+		warnings.Addf("spec.rollbackTo",
+			"cannot be represented in apps/v1, rollback to revision %d is dropped",
+			spec.RollbackTo.Revision)
+	}
+
+	status := in.Status.DeepCopy()
+	out.Status = appsv1.DeploymentStatus{
+		ObservedGeneration:  status.ObservedGeneration,
+		Replicas:            status.Replicas,
+		UpdatedReplicas:     status.UpdatedReplicas,
+		ReadyReplicas:       status.ReadyReplicas,
+		AvailableReplicas:   status.AvailableReplicas,
+		UnavailableReplicas: status.UnavailableReplicas,
+		CollisionCount:      status.CollisionCount,
+	}
+	for _, c := range status.Conditions {
+		out.Status.Conditions = append(out.Status.Conditions,
+			appsv1.DeploymentCondition{
+				Type:               appsv1.DeploymentConditionType(c.Type),
+				Status:             c.Status,
+				LastUpdateTime:     c.LastUpdateTime,
+				LastTransitionTime: c.LastTransitionTime,
+				Reason:             c.Reason,
+				Message:            c.Message,
+			})
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func newConverter(t *testing.T) *Converter {
+	t.Helper()
+	scheme, err := NewScheme()
+	if err != nil {
+		t.Fatalf("NewScheme() error = %v", err)
+	}
+	return NewConverter(scheme)
+}
+
+func TestConvert_V1ToV1beta1AndBack(t *testing.T) {
+	maxSurge := intstr.FromString("50%")
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "nginx"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32(3),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "nginx"},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "nginx"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}},
+				},
+			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{
+					MaxSurge: &maxSurge,
+				},
+			},
+			MinReadySeconds:         5,
+			RevisionHistoryLimit:    pointer.Int32(4),
+			ProgressDeadlineSeconds: pointer.Int32(60),
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           3,
+			ReadyReplicas:      2,
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:   appsv1.DeploymentAvailable,
+				Status: corev1.ConditionTrue,
+				Reason: "MinimumReplicasAvailable",
+			}},
+		},
+	}
+	converter := newConverter(t)
+
+	obj, warnings, err := converter.Convert(deploy, appsv1beta1.SchemeGroupVersion)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Convert() warnings = %v, want none", warnings)
+	}
+	v1beta1Deploy, ok := obj.(*appsv1beta1.Deployment)
+	if !ok {
+		t.Fatalf("Convert() returned %T, want *v1beta1.Deployment", obj)
+	}
+	if gvk := v1beta1Deploy.GroupVersionKind(); gvk != appsv1beta1.SchemeGroupVersion.WithKind("Deployment") {
+		t.Errorf("kind = %s, want apps/v1beta1 Deployment", gvk)
+	}
+	if *v1beta1Deploy.Spec.Replicas != 3 ||
+		v1beta1Deploy.Spec.Strategy.RollingUpdate.MaxSurge.String() != "50%" ||
+		v1beta1Deploy.Status.Conditions[0].Type != appsv1beta1.DeploymentAvailable {
+		t.Errorf("unexpected converted deployment: %+v", v1beta1Deploy)
+	}
+
+	back, warnings, err := converter.Convert(v1beta1Deploy, appsv1.SchemeGroupVersion)
+	if err != nil {
+		t.Fatalf("Convert() back error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Convert() back warnings = %v, want none", warnings)
+	}
+	deploy.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	if !reflect.DeepEqual(back, deploy) {
+		t.Errorf("round trip = %+v, want %+v", back, deploy)
+	}
+}
+
+func TestConvert_V1beta1ToV1Warnings(t *testing.T) {
+	deploy := &appsv1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+		Spec: appsv1beta1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "nginx"},
+				},
+			},
+			RollbackTo: &appsv1beta1.RollbackConfig{Revision: 2},
+		},
+	}
+	obj, warnings, err := newConverter(t).Convert(deploy, appsv1.SchemeGroupVersion)
+	if err != nil {
+		t.Fatalf("Convert() error = %v", err)
+	}
+	wantWarnings := []string{
+		"spec.selector: not set, required in apps/v1, set to the labels of the pod template",
+		"spec.revisionHistoryLimit: not set, defaults to 2 in apps/v1beta1 but to 10 in apps/v1",
+		"spec.rollbackTo: cannot be represented in apps/v1, rollback to revision 2 is dropped",
+	}
+	if len(warnings) != len(wantWarnings) {
+		t.Fatalf("Convert() warnings = %v, want %v", warnings, wantWarnings)
+	}
+	for i := range warnings {
+		if warnings[i].String() != wantWarnings[i] {
+			t.Errorf("warning %d = %q, want %q", i, warnings[i].String(), wantWarnings[i])
+		}
+	}
+	selector := obj.(*appsv1.Deployment).Spec.Selector
+	if selector == nil || selector.MatchLabels["app"] != "nginx" {
+		t.Errorf("selector = %v, want app=nginx", selector)
+	}
+}
+
+func TestConvert_SameVersion(t *testing.T) {
+	deploy := &appsv1.Deployment{}
+	obj, warnings, err := newConverter(t).Convert(deploy, appsv1.SchemeGroupVersion)
+	if err != nil || len(warnings) != 0 || obj != deploy {
+		t.Errorf("Convert() = %v, %v, %v, want the same object", obj, warnings, err)
+	}
+}
@@ -0,0 +1,328 @@
+// Package decoder decodes Kubernetes objects from multi-document
+// YAML or JSON manifests, into typed objects for the kinds
+// registered into a scheme, and into unstructured objects
+// for the other kinds, keeping track of the source of each object
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	mygroupv1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	jsonserializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Source is the location of an object in the manifests
+type Source struct {
+	File string
+	// Line is the line of the object in the file, from 1,
+	// or 0 if unknown
+	Line int
+	// Document is the index of the document
+	// containing the object in the file, from 0
+	Document int
+	// Item is the index of the object in the items of a list,
+	// from 0, or -1 if the object is not part of a list
+	Item int
+}
+
+// String returns the source as "file:line", or "file"
+// if the line is unknown
+func (o Source) String() string {
+	if o.Line == 0 {
+		return o.File
+	}
+	return fmt.Sprintf("%s:%d", o.File, o.Line)
+}
+
+// Object is an object decoded from manifests
+type Object struct {
+	// Object is a typed object if its kind is registered
+	// into the scheme, an *unstructured.Unstructured otherwise
+	Object runtime.Object
+	Source Source
+}
+
+// Error is an error decoding an object, with its source
+type Error struct {
+	Source Source
+	Err    error
+}
+
+func (o *Error) Error() string {
+	return o.Source.String() + ": " + o.Err.Error()
+}
+
+func (o *Error) Unwrap() error {
+	return o.Err
+}
+
+// Options are the options of a Decoder
+type Options struct {
+	// Strict returns an error for duplicate fields,
+	// and for unknown fields of typed objects, as done by
+	// the serializers with SerializerOptions.Strict.
+	// Unknown fields of unstructured objects cannot be detected.
+	Strict bool
+}
+
+// NewScheme returns a scheme containing the core/v1 and apps/v1
+// types, and the MyResource types
+func NewScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	builder := runtime.NewSchemeBuilder(
+		corev1.AddToScheme,
+		appsv1.AddToScheme,
+		mygroupv1alpha1.AddToScheme,
+	)
+	if err := builder.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return scheme, nil
+}
+
+// Decoder decodes objects from manifests
+type Decoder struct {
+	serializer *jsonserializer.Serializer
+	strict     bool
+}
+
+// New returns a Decoder decoding the kinds registered
+// into scheme into typed objects
+func New(scheme *runtime.Scheme, options Options) *Decoder {
+	return &Decoder{
+		serializer: jsonserializer.NewSerializerWithOptions(
+			jsonserializer.DefaultMetaFactory,
+			scheme,
+			scheme,
+			jsonserializer.SerializerOptions{
+				Yaml:   true,
+				Strict: options.Strict,
+			},
+		),
+		strict: options.Strict,
+	}
+}
+
+// Decode reads multi-document YAML or JSON manifests from r,
+// and calls fn for each object, in order. The items of lists
+// (e.g. of kind List or PodList, or a JSON array) are passed
+// as separate objects. file is the name of the manifests,
+// used in the sources of the objects.
+// Decode stops at the first error, either returned by fn or
+// decoding an object, in which case an *Error is returned.
+func (o *Decoder) Decode(
+	r io.Reader,
+	file string,
+	fn func(Object) error,
+) error {
+	yamlDecoder := yaml.NewDecoder(r)
+	for document := 0; ; document++ {
+		var doc yaml.Node
+		err := yamlDecoder.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		source := Source{File: file, Document: document, Item: -1}
+		if err != nil {
+			return &Error{Source: source, Err: err}
+		}
+		if len(doc.Content) == 0 {
+			// empty document
+			continue
+		}
+		root := doc.Content[0]
+		source.Line = root.Line
+		err = o.decodeNode(root, source, fn)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// DecodeAll decodes all the objects of the manifests read from r
+func (o *Decoder) DecodeAll(r io.Reader, file string) ([]Object, error) {
+	var result []Object
+	err := o.Decode(r, file, func(obj Object) error {
+		result = append(result, obj)
+		return nil
+	})
+	return result, err
+}
+
+// DecodeFile decodes the objects of the manifests
+// of the file at path, and calls fn for each object
+func (o *Decoder) DecodeFile(path string, fn func(Object) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return o.Decode(f, path, fn)
+}
+
+// decodeNode decodes the object, list of objects
+// or array of objects of node
+func (o *Decoder) decodeNode(
+	node *yaml.Node,
+	source Source,
+	fn func(Object) error,
+) error {
+	switch {
+	case isNull(node):
+		return nil
+	case node.Kind == yaml.SequenceNode:
+		return o.decodeItems(node, nil, source, fn)
+	case node.Kind != yaml.MappingNode:
+		return &Error{Source: source, Err: errors.New("expected an object")}
+	}
+
+	if items := value(node, "items"); items != nil &&
+		items.Kind == yaml.SequenceNode &&
+		isList(node) {
+		return o.decodeItems(items, node, source, fn)
+	}
+
+	obj, err := o.decodeObject(node)
+	if err != nil {
+		return &Error{Source: source, Err: err}
+	}
+	return fn(Object{Object: obj, Source: source})
+}
+
+// decodeItems decodes the items of a list or an array.
+// The items of a typed list, e.g. a PodList returned by the API,
+// do not define their kind, which is deduced from the kind of the list.
+func (o *Decoder) decodeItems(
+	items *yaml.Node,
+	list *yaml.Node,
+	source Source,
+	fn func(Object) error,
+) error {
+	for i, item := range items.Content {
+		itemSource := source
+		itemSource.Line = item.Line
+		itemSource.Item = i
+		if item.Kind != yaml.MappingNode {
+			return &Error{Source: itemSource, Err: errors.New("expected an object")}
+		}
+		if list != nil {
+			setDefaultKind(item, list)
+		}
+		obj, err := o.decodeObject(item)
+		if err != nil {
+			return &Error{Source: itemSource, Err: err}
+		}
+		err = fn(Object{Object: obj, Source: itemSource})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeObject decodes node into a typed object if its kind
+// is registered into the scheme, into an unstructured object otherwise
+func (o *Decoder) decodeObject(node *yaml.Node) (runtime.Object, error) {
+	if o.strict {
+		// detected on the node, to report the line in the file
+		if err := checkDuplicates(node); err != nil {
+			return nil, err
+		}
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	obj, _, err := o.serializer.Decode(data, nil, nil)
+	if err == nil {
+		return obj, nil
+	}
+	if !runtime.IsNotRegisteredError(err) {
+		return nil, err
+	}
+
+	j, err := sigsyaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{}
+	err = u.UnmarshalJSON(j)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// checkDuplicates returns an error if a mapping
+// of node defines the same field several times
+func checkDuplicates(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		keys := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if keys[key.Value] {
+				return fmt.Errorf("strict decoding error: line %d: duplicate field %q",
+					key.Line, key.Value)
+			}
+			keys[key.Value] = true
+		}
+	}
+	for _, child := range node.Content {
+		if err := checkDuplicates(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isList returns true if the kind of node is List
+// or the kind of a typed list, e.g. PodList
+func isList(node *yaml.Node) bool {
+	kind := value(node, "kind")
+	return kind != nil && strings.HasSuffix(kind.Value, "List")
+}
+
+// setDefaultKind sets the apiVersion and kind of item,
+// if not defined, from the apiVersion and kind of the typed list
+func setDefaultKind(item *yaml.Node, list *yaml.Node) {
+	listKind := value(list, "kind").Value
+	if listKind == "List" || value(item, "kind") != nil {
+		return
+	}
+	setValue(item, "kind", strings.TrimSuffix(listKind, "List"))
+	if apiVersion := value(list, "apiVersion"); apiVersion != nil &&
+		value(item, "apiVersion") == nil {
+		setValue(item, "apiVersion", apiVersion.Value)
+	}
+}
+
+// value returns the value of the key of the mapping node, or nil
+func value(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setValue(node *yaml.Node, key string, v string) {
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v},
+	)
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
@@ -0,0 +1,244 @@
+package decoder
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	mygroupv1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newDecoder(t *testing.T, options Options) *Decoder {
+	t.Helper()
+	scheme, err := NewScheme()
+	if err != nil {
+		t.Fatalf("NewScheme() error = %v", err)
+	}
+	return New(scheme, options)
+}
+
+const manifests = `# comment
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod1
+spec:
+  containers:
+  - name: main
+    image: nginx
+---
+---
+apiVersion: mygroup.example.com/v1alpha1
+kind: MyResource
+metadata:
+  name: myres
+spec:
+  image: nginx
+  memory: 512Mi
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+---
+{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "deploy"}},
+    {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "cm"}}
+  ]
+}
+---
+apiVersion: v1
+kind: ServiceList
+items:
+- metadata:
+    name: svc
+`
+
+func TestDecoder_Decode(t *testing.T) {
+	objects, err := newDecoder(t, Options{}).DecodeAll(
+		strings.NewReader(manifests), "manifests.yaml",
+	)
+	if err != nil {
+		t.Fatalf("DecodeAll() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		check  func(runtime.Object) bool
+		source Source
+	}{
+		{
+			name:   "pod1",
+			check:  func(o runtime.Object) bool { _, ok := o.(*corev1.Pod); return ok },
+			source: Source{File: "manifests.yaml", Line: 2, Document: 0, Item: -1},
+		},
+		{
+			name: "myres",
+			check: func(o runtime.Object) bool {
+				myres, ok := o.(*mygroupv1alpha1.MyResource)
+				return ok && myres.Spec.Memory.String() == "512Mi"
+			},
+			source: Source{File: "manifests.yaml", Line: 12, Document: 2, Item: -1},
+		},
+		{
+			name: "unknown",
+			check: func(o runtime.Object) bool {
+				u, ok := o.(*unstructured.Unstructured)
+				return ok && u.GetKind() == "Unknown"
+			},
+			source: Source{File: "manifests.yaml", Line: 20, Document: 3, Item: -1},
+		},
+		{
+			name:   "deploy",
+			check:  func(o runtime.Object) bool { _, ok := o.(*appsv1.Deployment); return ok },
+			source: Source{File: "manifests.yaml", Line: 29, Document: 4, Item: 0},
+		},
+		{
+			name:   "cm",
+			check:  func(o runtime.Object) bool { _, ok := o.(*corev1.ConfigMap); return ok },
+			source: Source{File: "manifests.yaml", Line: 30, Document: 4, Item: 1},
+		},
+		{
+			name: "svc",
+			check: func(o runtime.Object) bool {
+				svc, ok := o.(*corev1.Service)
+				return ok && svc.Kind == "Service" && svc.APIVersion == "v1"
+			},
+			source: Source{File: "manifests.yaml", Line: 37, Document: 5, Item: 0},
+		},
+	}
+	if len(objects) != len(tests) {
+		t.Fatalf("DecodeAll() returned %d objects, want %d", len(objects), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := objects[i]
+			name := obj.Object.(interface{ GetName() string }).GetName()
+			if name != tt.name {
+				t.Errorf("name = %q, want %q", name, tt.name)
+			}
+			if !tt.check(obj.Object) {
+				t.Errorf("unexpected object %T: %+v", obj.Object, obj.Object)
+			}
+			if obj.Source != tt.source {
+				t.Errorf("source = %+v, want %+v", obj.Source, tt.source)
+			}
+		})
+	}
+}
+
+func TestDecoder_JSONArray(t *testing.T) {
+	objects, err := newDecoder(t, Options{}).DecodeAll(strings.NewReader(`[
+  {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "a"}},
+  {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "b"}}
+]`), "pods.json")
+	if err != nil {
+		t.Fatalf("DecodeAll() error = %v", err)
+	}
+	if len(objects) != 2 ||
+		objects[1].Object.(*corev1.Pod).Name != "b" ||
+		objects[1].Source.String() != "pods.json:3" {
+		t.Errorf("unexpected objects: %+v", objects)
+	}
+}
+
+func TestDecoder_Strict(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name:     "unknown field",
+			manifest: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: a\nspec:\n  foo: bar\n",
+			wantErr:  `test.yaml:1: strict decoding error: unknown field "spec.foo"`,
+		},
+		{
+			name:     "duplicate field",
+			manifest: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: a\n  name: b\n",
+			wantErr:  `test.yaml:1: strict decoding error: line 5: duplicate field "name"`,
+		},
+		{
+			name:     "duplicate field in list item",
+			manifest: "apiVersion: v1\nkind: List\nitems:\n- apiVersion: example.com/v1\n  kind: Unknown\n  kind: Other\n",
+			wantErr:  `test.yaml:4: strict decoding error: line 6: duplicate field "kind"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newDecoder(t, Options{}).DecodeAll(
+				strings.NewReader(tt.manifest), "test.yaml",
+			)
+			if err != nil {
+				t.Fatalf("DecodeAll() not strict error = %v", err)
+			}
+
+			_, err = newDecoder(t, Options{Strict: true}).DecodeAll(
+				strings.NewReader(tt.manifest), "test.yaml",
+			)
+			var decodeErr *Error
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("DecodeAll() error = %v, want *Error", err)
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("DecodeAll() error = %q, want %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name:     "missing kind",
+			manifest: "apiVersion: v1\nmetadata:\n  name: a\n",
+			wantErr:  "test.yaml:1: Object 'Kind' is missing",
+		},
+		{
+			name:     "scalar",
+			manifest: "---\nfoo\n",
+			wantErr:  "test.yaml:2: expected an object",
+		},
+		{
+			name:     "invalid yaml",
+			manifest: "apiVersion: v1\n  kind: Pod\n",
+			wantErr:  "test.yaml: yaml: line 2: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newDecoder(t, Options{}).DecodeAll(
+				strings.NewReader(tt.manifest), "test.yaml",
+			)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("DecodeAll() error = %v, want prefix %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecoder_StopsOnCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	count := 0
+	err := newDecoder(t, Options{}).Decode(
+		strings.NewReader(manifests), "manifests.yaml",
+		func(Object) error {
+			count++
+			return stop
+		},
+	)
+	if err != stop || count != 1 {
+		t.Errorf("Decode() = %v after %d objects, want stop after 1", err, count)
+	}
+}
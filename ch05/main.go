@@ -13,6 +13,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	jsonserializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/apimachinery/pkg/runtime/serializer/protobuf"
+
+	"github.com/kprogo/ch5/convert"
 )
 
 func main() {
@@ -87,6 +89,10 @@ func main() {
 			return nil
 		})
 
+	// the convert package registers real conversion
+	// functions in both directions
+	convert.AddDeploymentConversions(scheme2)
+
 	// ### Converting
 	v1deployment := appsv1.Deployment{}
 	v1deployment.SetName("myname")
@@ -95,6 +101,8 @@ func main() {
 
 	var v1beta1Deployment appsv1beta1.Deployment
 	scheme2.Convert(&v1deployment, &v1beta1Deployment, nil)
+	fmt.Printf("converted: %s\n", v1beta1Deployment.GetName())
+	// converted: myname
 
 	// ## Serialization
 	// ### JSON and YAML jsonSerializer
@@ -140,31 +148,31 @@ func main() {
 	// # RESTMapper
 
 	restMapper := meta.NewDefaultRESTMapper(groupVersions)
-	restMapper.Add(appsv1beta1.SchemeGroupVersion.WithKind("Deployment"), nil)
-	restMapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), nil)
+	restMapper.Add(appsv1beta1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	restMapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
 
 	//	restMapper = meta.NewDefaultRESTMapper(groupVersions)
 	//	restMapper.AddSpecific(
 	//		appsv1.SchemeGroupVersion.WithKind("Deployment"),
 	//		appsv1.SchemeGroupVersion.WithResource("deployments"),
 	//		appsv1.SchemeGroupVersion.WithResource("deployment"),
-	//		nil,
+	//		meta.RESTScopeNamespace,
 	//	)
 
 	mapping, _ := restMapper.RESTMapping(
 		schema.GroupKind{Group: "apps", Kind: "Deployment"},
 	)
-	fmt.Printf("single mapping: %+v\n", *mapping)
-	// {Resource:apps/v1, Resource=deployments GroupVersionKind:apps/v1, Kind=Deployment Scope:<nil>}
+	fmt.Printf("single mapping: %s %s %s\n", mapping.Resource, mapping.GroupVersionKind, mapping.Scope.Name())
+	// apps/v1, Resource=deployments apps/v1, Kind=Deployment namespace
 
 	mappings, _ := restMapper.RESTMappings(
 		schema.GroupKind{Group: "apps", Kind: "Deployment"},
 	)
 	for _, mapping := range mappings {
-		fmt.Printf("mapping: %+v\n", *mapping)
+		fmt.Printf("mapping: %s %s %s\n", mapping.Resource, mapping.GroupVersionKind, mapping.Scope.Name())
 	}
-	// {Resource:apps/v1, Resource=deployments GroupVersionKind:apps/v1, Kind=Deployment Scope:<nil>}
-	// {Resource:apps/v1beta1, Resource=deployments GroupVersionKind:apps/v1beta1, Kind=Deployment Scope:<nil>}
+	// apps/v1, Resource=deployments apps/v1, Kind=Deployment namespace
+	// apps/v1beta1, Resource=deployments apps/v1beta1, Kind=Deployment namespace
 
 	kinds, _ := restMapper.KindsFor(
 		schema.GroupVersionResource{Group: "", Version: "", Resource: "deployment"},
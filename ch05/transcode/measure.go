@@ -0,0 +1,101 @@
+package transcode
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Measure is the measure of the encoding of an object in a format
+type Measure struct {
+	Format Format
+	// Size is the size in bytes of the encoded object
+	Size int
+	// EncodeTime and DecodeTime are the mean durations
+	// to encode and decode the object
+	EncodeTime time.Duration
+	DecodeTime time.Duration
+	// RoundTrip is true if the object decoded from its encoding
+	// is semantically equal to the original object
+	RoundTrip bool
+	// Err is the error encoding or decoding the object, e.g. when
+	// encoding an object without Protobuf definition in Protobuf
+	Err error
+}
+
+// Measure encodes and decodes obj in format iterations times,
+// and returns the size of the encoded object, the mean
+// encoding and decoding times, and if the decoded object is
+// semantically equal to obj. iterations is at least 1.
+func (o *Transcoder) Measure(
+	obj runtime.Object,
+	format Format,
+	iterations int,
+) Measure {
+	if iterations < 1 {
+		iterations = 1
+	}
+	result := Measure{Format: format}
+	obj, err := o.withKind(obj)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	var data []byte
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		data, err = o.Encode(obj, format)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	result.EncodeTime = time.Since(start) / time.Duration(iterations)
+	result.Size = len(data)
+
+	var decoded runtime.Object
+	start = time.Now()
+	for i := 0; i < iterations; i++ {
+		decoded, err = o.Decode(data, format)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	}
+	result.DecodeTime = time.Since(start) / time.Duration(iterations)
+	result.RoundTrip = equality.Semantic.DeepEqual(obj, decoded)
+	return result
+}
+
+// MeasureAll measures the encoding of obj in all the formats
+func (o *Transcoder) MeasureAll(obj runtime.Object, iterations int) []Measure {
+	result := make([]Measure, 0, len(Formats))
+	for _, format := range Formats {
+		result = append(result, o.Measure(obj, format, iterations))
+	}
+	return result
+}
+
+// PrintMeasures writes the measures as a table to w
+func PrintMeasures(w io.Writer, measures []Measure) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FORMAT\tSIZE\tENCODE\tDECODE\tROUND-TRIP")
+	for _, m := range measures {
+		if m.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\terror: %v\n", m.Format, m.Err)
+			continue
+		}
+		roundTrip := "ok"
+		if !m.RoundTrip {
+			roundTrip = "different"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n",
+			m.Format, m.Size, m.EncodeTime, m.DecodeTime, roundTrip)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,178 @@
+// Package transcode converts Kubernetes objects among
+// the JSON, YAML and Protobuf formats, and measures
+// the size and encoding and decoding times of each format
+package transcode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	jsonserializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/runtime/serializer/protobuf"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Format is a serialization format
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	// Protobuf is the Protobuf format used by the API Server:
+	// the message is wrapped into a runtime.Unknown
+	// prefixed with the "k8s\x00" magic number
+	Protobuf Format = "protobuf"
+)
+
+// Formats lists the supported formats
+var Formats = []Format{JSON, YAML, Protobuf}
+
+// protobufPrefix is the magic number prefixing
+// Kubernetes Protobuf messages
+var protobufPrefix = []byte("k8s\x00")
+
+// ParseFormat returns the format named s
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(s, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q, expected one of json, yaml, protobuf", s)
+}
+
+// Detect returns the format of data: Protobuf if data starts
+// with the Kubernetes Protobuf magic number, JSON if it starts
+// with an opening brace, YAML otherwise
+func Detect(data []byte) Format {
+	if bytes.HasPrefix(data, protobufPrefix) {
+		return Protobuf
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return JSON
+	}
+	return YAML
+}
+
+// Transcoder encodes and decodes objects
+// of the kinds registered into a scheme
+type Transcoder struct {
+	scheme      *runtime.Scheme
+	serializers map[Format]runtime.Serializer
+}
+
+// New returns a Transcoder for the kinds registered into scheme.
+// Objects of other kinds can be transcoded between JSON and YAML,
+// as unstructured objects.
+func New(scheme *runtime.Scheme) *Transcoder {
+	return &Transcoder{
+		scheme: scheme,
+		serializers: map[Format]runtime.Serializer{
+			JSON: jsonserializer.NewSerializerWithOptions(
+				jsonserializer.DefaultMetaFactory,
+				scheme,
+				scheme,
+				jsonserializer.SerializerOptions{},
+			),
+			YAML: jsonserializer.NewSerializerWithOptions(
+				jsonserializer.DefaultMetaFactory,
+				scheme,
+				scheme,
+				jsonserializer.SerializerOptions{Yaml: true},
+			),
+			Protobuf: protobuf.NewSerializer(scheme, scheme),
+		},
+	}
+}
+
+// Encode encodes obj in format. The apiVersion and kind of obj
+// are deduced from the scheme if not set.
+func (o *Transcoder) Encode(obj runtime.Object, format Format) ([]byte, error) {
+	serializer, err := o.serializer(format)
+	if err != nil {
+		return nil, err
+	}
+	obj, err = o.withKind(obj)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	err = serializer.Encode(obj, &buffer)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Decode decodes the object encoded in data in format.
+// JSON and YAML objects of kinds not registered into the scheme
+// are decoded as *unstructured.Unstructured.
+func (o *Transcoder) Decode(data []byte, format Format) (runtime.Object, error) {
+	serializer, err := o.serializer(format)
+	if err != nil {
+		return nil, err
+	}
+	obj, gvk, err := serializer.Decode(data, nil, nil)
+	if runtime.IsNotRegisteredError(err) && format != Protobuf {
+		return o.decodeUnstructured(data, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the Protobuf message of a typed object does not contain
+	// its apiVersion and kind, defined in the envelope
+	obj.GetObjectKind().SetGroupVersionKind(*gvk)
+	return obj, nil
+}
+
+// Transcode decodes the object encoded in data
+// in the from format, and encodes it in the to format
+func (o *Transcoder) Transcode(data []byte, from, to Format) ([]byte, error) {
+	obj, err := o.Decode(data, from)
+	if err != nil {
+		return nil, err
+	}
+	return o.Encode(obj, to)
+}
+
+func (o *Transcoder) serializer(format Format) (runtime.Serializer, error) {
+	serializer, found := o.serializers[format]
+	if !found {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return serializer, nil
+}
+
+func (o *Transcoder) decodeUnstructured(data []byte, format Format) (runtime.Object, error) {
+	if format == YAML {
+		var err error
+		data, err = sigsyaml.YAMLToJSON(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	u := &unstructured.Unstructured{}
+	err := u.UnmarshalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// withKind returns obj, or a copy of obj with its apiVersion
+// and kind set from the scheme if they are not set
+func (o *Transcoder) withKind(obj runtime.Object) (runtime.Object, error) {
+	if !obj.GetObjectKind().GroupVersionKind().Empty() {
+		return obj, nil
+	}
+	gvks, _, err := o.scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	obj = obj.DeepCopyObject()
+	obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+	return obj, nil
+}
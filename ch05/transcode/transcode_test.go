@@ -0,0 +1,199 @@
+package transcode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+)
+
+func newTranscoder(t *testing.T) *Transcoder {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return New(scheme)
+}
+
+func deployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "nginx"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32(3),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "nginx"},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "nginx"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}},
+				},
+			},
+		},
+	}
+}
+
+func TestTranscoder_Transcode(t *testing.T) {
+	transcoder := newTranscoder(t)
+	original := deployment()
+
+	data, err := transcoder.Encode(original, JSON)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"kind":"Deployment"`) {
+		t.Errorf("Encode() did not set the kind: %s", data)
+	}
+	if original.Kind != "" {
+		t.Errorf("Encode() modified the original object")
+	}
+
+	pb, err := transcoder.Transcode(data, JSON, Protobuf)
+	if err != nil {
+		t.Fatalf("Transcode() to Protobuf error = %v", err)
+	}
+	if !bytes.HasPrefix(pb, []byte("k8s\x00")) {
+		t.Errorf("Protobuf data does not start with the magic number: %q", pb[:4])
+	}
+
+	y, err := transcoder.Transcode(pb, Protobuf, YAML)
+	if err != nil {
+		t.Fatalf("Transcode() to YAML error = %v", err)
+	}
+	if !strings.HasPrefix(string(y), "apiVersion: apps/v1\nkind: Deployment\n") {
+		t.Errorf("unexpected YAML:\n%s", y)
+	}
+
+	decoded, err := transcoder.Decode(y, YAML)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	original.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	if !equality.Semantic.DeepEqual(decoded, original) {
+		t.Errorf("Decode() = %+v, want %+v", decoded, original)
+	}
+}
+
+func TestTranscoder_Unstructured(t *testing.T) {
+	transcoder := newTranscoder(t)
+	y := []byte("apiVersion: example.com/v1\nkind: Unknown\nmetadata:\n  name: foo\n")
+
+	obj, err := transcoder.Decode(y, YAML)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if u, ok := obj.(*unstructured.Unstructured); !ok || u.GetName() != "foo" {
+		t.Fatalf("Decode() = %#v, want an unstructured object", obj)
+	}
+	if _, err = transcoder.Encode(obj, Protobuf); err == nil {
+		t.Error("Encode() unstructured in Protobuf: expected an error")
+	}
+	j, err := transcoder.Encode(obj, JSON)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if want := `{"apiVersion":"example.com/v1","kind":"Unknown","metadata":{"name":"foo"}}` + "\n"; string(j) != want {
+		t.Errorf("Encode() = %s, want %s", j, want)
+	}
+}
+
+func TestTranscoder_MeasureAll(t *testing.T) {
+	transcoder := newTranscoder(t)
+	measures := transcoder.MeasureAll(deployment(), 2)
+	if len(measures) != len(Formats) {
+		t.Fatalf("MeasureAll() returned %d measures, want %d", len(measures), len(Formats))
+	}
+	for i, m := range measures {
+		if m.Format != Formats[i] {
+			t.Errorf("measure %d is for %s, want %s", i, m.Format, Formats[i])
+		}
+		if m.Err != nil {
+			t.Errorf("%s: error = %v", m.Format, m.Err)
+		}
+		if m.Size == 0 {
+			t.Errorf("%s: size is 0", m.Format)
+		}
+		if !m.RoundTrip {
+			t.Errorf("%s: round trip = false, want true", m.Format)
+		}
+	}
+	if measures[2].Size >= measures[0].Size {
+		t.Errorf("Protobuf size %d, want less than JSON size %d", measures[2].Size, measures[0].Size)
+	}
+
+	// all the formats encode times with a precision of one second
+	deploy := deployment()
+	deploy.SetCreationTimestamp(metav1.NewTime(
+		time.Date(2022, 10, 1, 12, 0, 0, 500, time.UTC),
+	))
+	for _, m := range transcoder.MeasureAll(deploy, 1) {
+		if m.RoundTrip {
+			t.Errorf("%s: round trip with nanoseconds = true, want false", m.Format)
+		}
+	}
+}
+
+func TestPrintMeasures(t *testing.T) {
+	var out bytes.Buffer
+	err := PrintMeasures(&out, []Measure{
+		{Format: JSON, Size: 402, EncodeTime: 12 * time.Microsecond, DecodeTime: 30 * time.Microsecond, RoundTrip: true},
+		{Format: YAML, Size: 423, EncodeTime: 170 * time.Microsecond, DecodeTime: 85 * time.Microsecond},
+		{Format: Protobuf, Err: errors.New("not marshalable")},
+	})
+	if err != nil {
+		t.Fatalf("PrintMeasures() error = %v", err)
+	}
+	want := `FORMAT    SIZE  ENCODE  DECODE  ROUND-TRIP
+json      402   12µs    30µs    ok
+yaml      423   170µs   85µs    different
+protobuf  -     -       -       error: not marshalable
+`
+	if out.String() != want {
+		t.Errorf("PrintMeasures() =\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		data string
+		want Format
+	}{
+		{data: "k8s\x00\n\x0f", want: Protobuf},
+		{data: "  \n{\"kind\": \"Pod\"}", want: JSON},
+		{data: "kind: Pod\n", want: YAML},
+		{data: "# {\nkind: Pod\n", want: YAML},
+	}
+	for _, tt := range tests {
+		if got := Detect([]byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("YAML"); err != nil || f != YAML {
+		t.Errorf("ParseFormat(YAML) = %s, %v", f, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml): expected an error")
+	}
+}
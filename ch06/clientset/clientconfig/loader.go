@@ -0,0 +1,346 @@
+// Package clientconfig loads the configuration to connect to
+// a cluster from the first available source among an in-memory
+// kubeconfig, kubeconfig files and the in-cluster configuration,
+// and applies the context selection, impersonation, proxy, CA
+// and rate limiting options of the program to it
+package clientconfig
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Source is the source of the configuration
+type Source string
+
+const (
+	// SourceInMemory is the kubeconfig content of ConfigLoader.Kubeconfig
+	SourceInMemory Source = "in-memory kubeconfig"
+	// SourceExplicitPath is the kubeconfig file ConfigLoader.KubeconfigPath
+	SourceExplicitPath Source = "kubeconfig file"
+	// SourceEnv is the list of kubeconfig files of the
+	// KUBECONFIG environment variable, merged
+	SourceEnv Source = "KUBECONFIG"
+	// SourceInCluster is the service account of the pod
+	// the program is running in
+	SourceInCluster Source = "in-cluster"
+	// SourceHome is the file .kube/config of the home directory
+	SourceHome Source = "home kubeconfig"
+)
+
+// ErrNoConfiguration is returned when no source of configuration is found
+var ErrNoConfiguration = errors.New("no configuration found: " +
+	"no kubeconfig provided, KUBECONFIG empty, not running in a cluster " +
+	"and no .kube/config in the home directory")
+
+// ClientOptions are the options of the configuration
+// for a specific client of the program
+type ClientOptions struct {
+	// QPS and Burst define the rate limiter of the client,
+	// the client-go defaults are used if 0
+	QPS   float32
+	Burst int
+	// Timeout is the timeout of the requests, no timeout if 0
+	Timeout time.Duration
+	// UserAgent is the user agent of the requests,
+	// the client-go default is used if empty
+	UserAgent string
+}
+
+// ConfigLoader loads the configuration to connect to a cluster
+// from the first available source, in this order:
+//
+//  1. the kubeconfig content Kubeconfig
+//  2. the kubeconfig file KubeconfigPath (an error if it does not exist)
+//  3. the kubeconfig files listed in the KUBECONFIG environment variable
+//  4. the in-cluster configuration, when running in a pod
+//  5. the file .kube/config of the home directory
+//
+// The zero value loads the configuration with the
+// default options from the available sources.
+type ConfigLoader struct {
+	// Kubeconfig is the content of a kubeconfig
+	Kubeconfig []byte
+	// KubeconfigPath is the path of a kubeconfig file
+	KubeconfigPath string
+
+	// Context, Cluster and User select the context, and the cluster
+	// and user of the context, of a kubeconfig. The current context
+	// of the kubeconfig is used if Context is empty. It is an error
+	// to select them when the in-cluster configuration is used.
+	Context string
+	Cluster string
+	User    string
+	// Modify, if not nil, is called to modify the kubeconfig
+	// loaded from a kubeconfig source before it is used
+	Modify func(*api.Config) error
+
+	// ImpersonateUser, ImpersonateUID and ImpersonateGroups define
+	// the user the requests are made as. Groups and UID
+	// require a user.
+	ImpersonateUser   string
+	ImpersonateUID    string
+	ImpersonateGroups []string
+
+	// ProxyURL is the URL of the proxy the requests are sent through
+	// (http, https or socks5 schemes)
+	ProxyURL string
+	// CAFile and CAData define the certificate authority
+	// used to verify the certificate of the API Server,
+	// replacing the one of the source
+	CAFile string
+	CAData []byte
+
+	// ClientOptions are the default options for all the clients
+	ClientOptions
+	// Clients are the options for specific clients, by name,
+	// overriding the default options when they are not zero
+	Clients map[string]ClientOptions
+
+	// overrides are the overrides bound to flags by BindFlags.
+	// They apply to the kubeconfig sources only, the fields
+	// above take precedence over them.
+	overrides clientcmd.ConfigOverrides
+
+	// getenv, homeDir and inClusterConfig access the
+	// environment, replaced by the tests
+	getenv          func(string) string
+	homeDir         func() (string, error)
+	inClusterConfig func() (*rest.Config, error)
+}
+
+// BindFlags adds the standard kubectl flags (--kubeconfig,
+// --context, --cluster, --user, --as, --as-group, --server,
+// --certificate-authority, ...) to flags
+func (o *ConfigLoader) BindFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.KubeconfigPath, clientcmd.RecommendedConfigPathFlag, o.KubeconfigPath,
+		"Path to the kubeconfig file to use")
+	clientcmd.BindOverrideFlags(&o.overrides, flags, clientcmd.RecommendedConfigOverrideFlags(""))
+}
+
+// Load returns the configuration with the default client options
+func (o *ConfigLoader) Load() (*rest.Config, error) {
+	config, _, err := o.load("")
+	return config, err
+}
+
+// ConfigFor returns the configuration for the client named name,
+// with the options of Clients[name] overriding the default options.
+// The user agent is suffixed with the name if not defined.
+func (o *ConfigLoader) ConfigFor(name string) (*rest.Config, error) {
+	config, _, err := o.load(name)
+	return config, err
+}
+
+// Source returns the source the configuration is loaded from
+func (o *ConfigLoader) Source() (Source, error) {
+	_, source, err := o.load("")
+	return source, err
+}
+
+func (o *ConfigLoader) load(name string) (*rest.Config, Source, error) {
+	config, source, err := o.loadSource()
+	if err != nil {
+		return nil, source, err
+	}
+	if err = o.apply(config); err != nil {
+		return nil, source, err
+	}
+	o.clientOptions(name).apply(config, name)
+	return config, source, nil
+}
+
+// loadSource loads the configuration from the first available source
+func (o *ConfigLoader) loadSource() (*rest.Config, Source, error) {
+	if len(o.Kubeconfig) > 0 {
+		apiConfig, err := clientcmd.Load(o.Kubeconfig)
+		if err != nil {
+			return nil, SourceInMemory, err
+		}
+		config, err := o.fromKubeconfig(apiConfig)
+		return config, SourceInMemory, err
+	}
+
+	if o.KubeconfigPath != "" {
+		config, err := o.fromFiles(&clientcmd.ClientConfigLoadingRules{
+			ExplicitPath: o.KubeconfigPath,
+		})
+		return config, SourceExplicitPath, err
+	}
+
+	if paths := filepath.SplitList(o.env(clientcmd.RecommendedConfigPathEnvVar)); anyExists(paths) {
+		config, err := o.fromFiles(&clientcmd.ClientConfigLoadingRules{
+			Precedence: paths,
+		})
+		return config, SourceEnv, err
+	}
+
+	inClusterConfig := rest.InClusterConfig
+	if o.inClusterConfig != nil {
+		inClusterConfig = o.inClusterConfig
+	}
+	config, err := inClusterConfig()
+	if err == nil {
+		if o.Context != "" || o.Cluster != "" || o.User != "" {
+			return nil, SourceInCluster, errors.New(
+				"context, cluster and user cannot be selected with the in-cluster configuration",
+			)
+		}
+		return config, SourceInCluster, nil
+	}
+	if !errors.Is(err, rest.ErrNotInCluster) {
+		return nil, SourceInCluster, err
+	}
+
+	if path, ok := o.homeKubeconfig(); ok {
+		config, err := o.fromFiles(&clientcmd.ClientConfigLoadingRules{
+			Precedence: []string{path},
+		})
+		return config, SourceHome, err
+	}
+	return nil, "", ErrNoConfiguration
+}
+
+func (o *ConfigLoader) fromFiles(rules *clientcmd.ClientConfigLoadingRules) (*rest.Config, error) {
+	apiConfig, err := rules.Load()
+	if err != nil {
+		return nil, err
+	}
+	return o.fromKubeconfig(apiConfig)
+}
+
+func (o *ConfigLoader) fromKubeconfig(apiConfig *api.Config) (*rest.Config, error) {
+	if o.Modify != nil {
+		if err := o.Modify(apiConfig); err != nil {
+			return nil, fmt.Errorf("modifying kubeconfig: %w", err)
+		}
+	}
+	overrides := o.overrides
+	if o.Context != "" {
+		overrides.CurrentContext = o.Context
+	}
+	if o.Cluster != "" {
+		overrides.Context.Cluster = o.Cluster
+	}
+	if o.User != "" {
+		overrides.Context.AuthInfo = o.User
+	}
+	return clientcmd.NewNonInteractiveClientConfig(
+		*apiConfig, "", &overrides, nil,
+	).ClientConfig()
+}
+
+// apply applies the impersonation, proxy and CA options to config
+func (o *ConfigLoader) apply(config *rest.Config) error {
+	if o.ImpersonateUser != "" {
+		config.Impersonate = rest.ImpersonationConfig{
+			UserName: o.ImpersonateUser,
+			UID:      o.ImpersonateUID,
+			Groups:   o.ImpersonateGroups,
+		}
+	} else if o.ImpersonateUID != "" || len(o.ImpersonateGroups) > 0 {
+		return errors.New("impersonating a UID or groups requires a user")
+	}
+
+	if o.ProxyURL != "" {
+		u, err := url.Parse(o.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("parsing proxy URL: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported proxy URL scheme %q", u.Scheme)
+		}
+		config.Proxy = http.ProxyURL(u)
+	}
+
+	if o.CAFile != "" || len(o.CAData) > 0 {
+		config.TLSClientConfig.CAFile = o.CAFile
+		config.TLSClientConfig.CAData = o.CAData
+		config.TLSClientConfig.Insecure = false
+	}
+	return nil
+}
+
+// clientOptions returns the options for the client named name
+func (o *ConfigLoader) clientOptions(name string) ClientOptions {
+	options := o.ClientOptions
+	specific, found := o.Clients[name]
+	if !found {
+		return options
+	}
+	if specific.QPS != 0 {
+		options.QPS = specific.QPS
+	}
+	if specific.Burst != 0 {
+		options.Burst = specific.Burst
+	}
+	if specific.Timeout != 0 {
+		options.Timeout = specific.Timeout
+	}
+	if specific.UserAgent != "" {
+		options.UserAgent = specific.UserAgent
+	}
+	return options
+}
+
+func (o ClientOptions) apply(config *rest.Config, name string) {
+	if o.QPS != 0 {
+		config.QPS = o.QPS
+	}
+	if o.Burst != 0 {
+		config.Burst = o.Burst
+	}
+	if o.Timeout != 0 {
+		config.Timeout = o.Timeout
+	}
+	switch {
+	case o.UserAgent != "":
+		config.UserAgent = o.UserAgent
+	case name != "":
+		config.UserAgent = rest.DefaultKubernetesUserAgent() + "/" + name
+	}
+}
+
+func (o *ConfigLoader) env(key string) string {
+	if o.getenv != nil {
+		return o.getenv(key)
+	}
+	return os.Getenv(key)
+}
+
+func (o *ConfigLoader) homeKubeconfig() (string, bool) {
+	homeDir := os.UserHomeDir
+	if o.homeDir != nil {
+		homeDir = o.homeDir
+	}
+	home, err := homeDir()
+	if err != nil {
+		return "", false
+	}
+	path := filepath.Join(home, clientcmd.RecommendedHomeDir, clientcmd.RecommendedFileName)
+	return path, anyExists([]string{path})
+}
+
+func anyExists(paths []string) bool {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
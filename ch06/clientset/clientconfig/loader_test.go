@@ -0,0 +1,355 @@
+package clientconfig
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com
+    insecure-skip-tls-verify: true
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+users:
+- name: dev-user
+  user:
+    token: dev-token
+- name: admin
+  user:
+    token: admin-token
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: admin
+`
+
+const otherKubeconfig = `apiVersion: v1
+kind: Config
+current-context: other
+clusters:
+- name: other-cluster
+  cluster:
+    server: https://other.example.com
+users:
+- name: other-user
+  user:
+    token: other-token
+contexts:
+- name: other
+  context:
+    cluster: other-cluster
+    user: other-user
+`
+
+// writeFile writes content to the file name into dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testEnv is the environment of a loader
+type testEnv struct {
+	env       map[string]string
+	home      string
+	inCluster bool
+}
+
+func newLoader(loader ConfigLoader, e testEnv) *ConfigLoader {
+	loader.getenv = func(key string) string { return e.env[key] }
+	loader.homeDir = func() (string, error) { return e.home, nil }
+	loader.inClusterConfig = func() (*rest.Config, error) {
+		if !e.inCluster {
+			return nil, rest.ErrNotInCluster
+		}
+		return &rest.Config{Host: "https://10.96.0.1:443", BearerToken: "sa-token"}, nil
+	}
+	return &loader
+}
+
+func TestConfigLoader_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "explicit", kubeconfig)
+	other := writeFile(t, dir, "other", otherKubeconfig)
+	home := t.TempDir()
+	writeFile(t, home, ".kube/config", otherKubeconfig)
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name       string
+		loader     ConfigLoader
+		env        testEnv
+		wantSource Source
+		wantHost   string
+		wantErr    bool
+	}{
+		{
+			name:       "in-memory first",
+			loader:     ConfigLoader{Kubeconfig: []byte(kubeconfig), KubeconfigPath: other},
+			env:        testEnv{inCluster: true},
+			wantSource: SourceInMemory,
+			wantHost:   "https://dev.example.com",
+		},
+		{
+			name:       "explicit path before KUBECONFIG",
+			loader:     ConfigLoader{KubeconfigPath: path},
+			env:        testEnv{env: map[string]string{"KUBECONFIG": other}},
+			wantSource: SourceExplicitPath,
+			wantHost:   "https://dev.example.com",
+		},
+		{
+			name:       "missing explicit path",
+			loader:     ConfigLoader{KubeconfigPath: missing},
+			env:        testEnv{home: home},
+			wantSource: SourceExplicitPath,
+			wantErr:    true,
+		},
+		{
+			name:       "KUBECONFIG merged, first file wins",
+			env:        testEnv{env: map[string]string{"KUBECONFIG": missing + string(filepath.ListSeparator) + other + string(filepath.ListSeparator) + path}, inCluster: true},
+			wantSource: SourceEnv,
+			wantHost:   "https://other.example.com",
+		},
+		{
+			name:       "in-cluster before home",
+			env:        testEnv{env: map[string]string{"KUBECONFIG": missing}, home: home, inCluster: true},
+			wantSource: SourceInCluster,
+			wantHost:   "https://10.96.0.1:443",
+		},
+		{
+			name:       "home",
+			env:        testEnv{home: home},
+			wantSource: SourceHome,
+			wantHost:   "https://other.example.com",
+		},
+		{
+			name:    "none",
+			env:     testEnv{home: t.TempDir()},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := newLoader(tt.loader, tt.env)
+			config, source, err := loader.load("")
+			if source != tt.wantSource {
+				t.Errorf("source = %q, want %q", source, tt.wantSource)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && config.Host != tt.wantHost {
+				t.Errorf("host = %q, want %q", config.Host, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestConfigLoader_NoConfiguration(t *testing.T) {
+	_, err := newLoader(ConfigLoader{}, testEnv{home: t.TempDir()}).Load()
+	if !errors.Is(err, ErrNoConfiguration) {
+		t.Errorf("Load() error = %v, want ErrNoConfiguration", err)
+	}
+}
+
+func TestConfigLoader_Selection(t *testing.T) {
+	tests := []struct {
+		name      string
+		loader    ConfigLoader
+		wantHost  string
+		wantToken string
+		wantErr   string
+	}{
+		{
+			name:      "current context",
+			wantHost:  "https://dev.example.com",
+			wantToken: "dev-token",
+		},
+		{
+			name:      "context",
+			loader:    ConfigLoader{Context: "prod"},
+			wantHost:  "https://prod.example.com",
+			wantToken: "admin-token",
+		},
+		{
+			name:      "cluster and user of the context",
+			loader:    ConfigLoader{Cluster: "prod-cluster", User: "admin"},
+			wantHost:  "https://prod.example.com",
+			wantToken: "admin-token",
+		},
+		{
+			name:    "unknown context",
+			loader:  ConfigLoader{Context: "staging"},
+			wantErr: `context "staging" does not exist`,
+		},
+		{
+			name: "modified kubeconfig",
+			loader: ConfigLoader{Modify: func(c *api.Config) error {
+				c.AuthInfos["dev-user"].Token = "modified-token"
+				return nil
+			}},
+			wantHost:  "https://dev.example.com",
+			wantToken: "modified-token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.loader.KubeconfigPath = writeFile(t, t.TempDir(), "config", kubeconfig)
+			config, err := newLoader(tt.loader, testEnv{}).Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if config.Host != tt.wantHost || config.BearerToken != tt.wantToken {
+				t.Errorf("Load() = %s with token %q, want %s with token %q",
+					config.Host, config.BearerToken, tt.wantHost, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestConfigLoader_InClusterSelection(t *testing.T) {
+	_, err := newLoader(ConfigLoader{Context: "prod"}, testEnv{inCluster: true}).Load()
+	if err == nil {
+		t.Error("Load() selecting a context in-cluster: expected an error")
+	}
+}
+
+func TestConfigLoader_Flags(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config", kubeconfig)
+	loader := newLoader(ConfigLoader{}, testEnv{})
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	loader.BindFlags(flags)
+	err := flags.Parse([]string{
+		"--kubeconfig", path,
+		"--context", "prod",
+		"--as", "jane",
+		"--server", "https://override.example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.Host != "https://override.example.com" ||
+		config.BearerToken != "admin-token" ||
+		config.Impersonate.UserName != "jane" {
+		t.Errorf("Load() = %+v, want the overrides of the flags", config)
+	}
+
+	// the fields take precedence over the flags
+	loader.Context = "dev"
+	config, err = loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.BearerToken != "dev-token" {
+		t.Errorf("token = %q, want dev-token", config.BearerToken)
+	}
+}
+
+func TestConfigLoader_Options(t *testing.T) {
+	dir := t.TempDir()
+	loader := newLoader(ConfigLoader{
+		Kubeconfig:        []byte(kubeconfig),
+		ImpersonateUser:   "jane",
+		ImpersonateUID:    "1234",
+		ImpersonateGroups: []string{"developers"},
+		ProxyURL:          "http://proxy.example.com:3128",
+		CAFile:            writeFile(t, dir, "ca.crt", "not parsed"),
+		ClientOptions: ClientOptions{
+			QPS:     20,
+			Burst:   40,
+			Timeout: 10 * time.Second,
+		},
+		Clients: map[string]ClientOptions{
+			"informers": {QPS: 100},
+		},
+	}, testEnv{})
+
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.Impersonate.UserName != "jane" ||
+		config.Impersonate.UID != "1234" ||
+		len(config.Impersonate.Groups) != 1 {
+		t.Errorf("impersonation = %+v", config.Impersonate)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://dev.example.com", nil)
+	if proxy, err := config.Proxy(req); err != nil || proxy.Host != "proxy.example.com:3128" {
+		t.Errorf("proxy = %v, %v", proxy, err)
+	}
+	if config.CAFile != filepath.Join(dir, "ca.crt") || config.Insecure {
+		t.Errorf("CA file = %q, insecure %v, want the CA file and secure", config.CAFile, config.Insecure)
+	}
+	if config.QPS != 20 || config.Burst != 40 || config.Timeout != 10*time.Second {
+		t.Errorf("QPS, Burst, Timeout = %v, %v, %v", config.QPS, config.Burst, config.Timeout)
+	}
+	if config.UserAgent != "" {
+		t.Errorf("user agent = %q, want empty", config.UserAgent)
+	}
+
+	config, err = loader.ConfigFor("informers")
+	if err != nil {
+		t.Fatalf("ConfigFor() error = %v", err)
+	}
+	if config.QPS != 100 || config.Burst != 40 {
+		t.Errorf("QPS, Burst = %v, %v, want 100, 40", config.QPS, config.Burst)
+	}
+	if !strings.HasSuffix(config.UserAgent, "/informers") {
+		t.Errorf("user agent = %q, want suffix /informers", config.UserAgent)
+	}
+}
+
+func TestConfigLoader_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		loader ConfigLoader
+	}{
+		{name: "groups without user", loader: ConfigLoader{ImpersonateGroups: []string{"admins"}}},
+		{name: "proxy scheme", loader: ConfigLoader{ProxyURL: "ftp://proxy.example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.loader.Kubeconfig = []byte(kubeconfig)
+			if _, err := newLoader(tt.loader, testEnv{}).Load(); err == nil {
+				t.Error("Load(): expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Command managed-fields prints the ownership tree of the fields
+// of an object, read from a file or from the cluster of the
+// current kubeconfig context, with the field managers owning
+// each field.
+//
+// Usage:
+//
+//	managed-fields -f deployment.yaml
+//	managed-fields [-n namespace] deployments nginx
+//	managed-fields -highlighted apps/v1/deployments nginx
+//
+// The fields owned by several managers are marked as SHARED, and
+// the fields owned by the before-first-apply manager as LEFTOVER.
+// With -highlighted, only these fields are printed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kprogo/ch6/clientset/managedfields"
+)
+
+func main() {
+	file := flag.String("f", "", "file containing the object, in YAML or JSON")
+	namespace := flag.String("n", "", "namespace of the object, the namespace of the kubeconfig context by default")
+	highlighted := flag.Bool("highlighted", false, "print only the shared and leftover fields")
+	flag.Parse()
+
+	if err := run(*file, *namespace, *highlighted, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(file string, namespace string, highlighted bool, args []string) error {
+	var obj *unstructured.Unstructured
+	var err error
+	switch {
+	case file != "" && len(args) == 0:
+		obj, err = readFile(file)
+	case file == "" && len(args) == 2:
+		obj, err = get(namespace, args[0], args[1])
+	default:
+		err = fmt.Errorf("either -f or a resource and a name are required")
+	}
+	if err != nil {
+		return err
+	}
+
+	root, err := managedfields.Tree(obj)
+	if err != nil {
+		return err
+	}
+	if !highlighted {
+		return managedfields.Print(os.Stdout, root)
+	}
+	for _, field := range managedfields.Highlighted(root) {
+		managers := make([]string, 0, len(field.Owners))
+		for _, owner := range field.Owners {
+			managers = append(managers, owner.String())
+		}
+		fmt.Printf("%s: %s\n", field.Path, strings.Join(managers, ", "))
+	}
+	return nil
+}
+
+func readFile(file string) (*unstructured.Unstructured, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err = yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	if err = obj.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// get returns the object name of resource, in the
+// form [[group/]version/]resource, from the cluster
+func get(namespace string, resource string, name string) (*unstructured.Unstructured, error) {
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		nil,
+	)
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewShortcutExpander(
+		restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient)),
+		discoveryClient,
+	)
+	mapping, err := resolve(mapper, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	var ri dynamic.ResourceInterface = client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = client.Resource(mapping.Resource).Namespace(namespace)
+	}
+	return ri.Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// resolve returns the mapping of resource,
+// in the form [[group/]version/]resource
+func resolve(mapper meta.RESTMapper, resource string) (*meta.RESTMapping, error) {
+	var gvr schema.GroupVersionResource
+	if i := strings.LastIndex(resource, "/"); i >= 0 {
+		gv, err := schema.ParseGroupVersion(resource[:i])
+		if err != nil {
+			return nil, err
+		}
+		gvr = gv.WithResource(resource[i+1:])
+	} else {
+		gvr = schema.ParseGroupResource(resource).WithVersion("")
+	}
+	gvk, err := mapper.KindFor(gvr)
+	if err != nil {
+		return nil, err
+	}
+	return mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
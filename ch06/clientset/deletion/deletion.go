@@ -0,0 +1,361 @@
+// Package deletion deletes resources with preconditions and a
+// propagation policy, and waits until they are actually gone
+package deletion
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultPollInterval is the interval between two lists
+// of the dependents when Options.PollInterval is 0
+const DefaultPollInterval = time.Second
+
+// Client is a typed client with get, delete and watch
+// calls, e.g. clientset.CoreV1().Pods("ns")
+type Client[T client.Object] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// Options define how to delete a resource
+type Options struct {
+	// GracePeriod is the duration in seconds before the
+	// object is deleted, the default of the resource if nil
+	GracePeriod *int64
+	// UID and ResourceVersion, if not empty, are preconditions:
+	// the deletion fails with a Conflict error if the object
+	// has a different UID or resource version
+	UID             types.UID
+	ResourceVersion string
+	// Propagation is the propagation policy of the deletion
+	// to the dependents, the default of the resource if empty
+	Propagation metav1.DeletionPropagation
+	// DryRun validates the deletion without deleting
+	DryRun bool
+	// IgnoreNotFound returns no error if the object does not exist
+	IgnoreNotFound bool
+
+	// Wait waits until the object is gone, e.g. after its
+	// finalizers have been removed. Without a UID precondition,
+	// the UID of the object is read first and used as precondition,
+	// so the deletion and the wait target the same object.
+	Wait bool
+	// Timeout, if not 0, is the maximum duration of the wait
+	Timeout time.Duration
+	// Dependents list the dependents of the object. With the
+	// Foreground propagation, the wait also waits until the
+	// dependents are gone, polling them every PollInterval. On
+	// timeout, the remaining dependents are reported in the
+	// PendingError.
+	Dependents []DependentLister
+	// PollInterval is the interval between two lists of the
+	// dependents, DefaultPollInterval if 0
+	PollInterval time.Duration
+}
+
+// DependentLister lists the objects of a resource,
+// to find the dependents of a deleted object
+type DependentLister struct {
+	resource string
+	list     func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+}
+
+// Dependents returns a DependentLister for the objects of
+// resource listed with list, e.g.:
+//
+//	deletion.Dependents("replicasets", clientset.AppsV1().ReplicaSets("ns").List)
+func Dependents[L runtime.Object](
+	resource string,
+	list func(ctx context.Context, opts metav1.ListOptions) (L, error),
+) DependentLister {
+	return DependentLister{
+		resource: resource,
+		list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return list(ctx, opts)
+		},
+	}
+}
+
+// Dependent is a dependent of a deleted object, not deleted yet
+type Dependent struct {
+	Resource   string
+	Namespace  string
+	Name       string
+	Finalizers []string
+}
+
+func (o Dependent) String() string {
+	name := o.Name
+	if o.Namespace != "" {
+		name = o.Namespace + "/" + o.Name
+	}
+	if len(o.Finalizers) == 0 {
+		return fmt.Sprintf("%s %s", o.Resource, name)
+	}
+	return fmt.Sprintf("%s %s (finalizers %v)", o.Resource, name, o.Finalizers)
+}
+
+// PendingError is returned when the wait times out, with what is
+// blocking the deletion: the finalizers of the object if it is not
+// gone yet, and the dependents not gone yet
+type PendingError struct {
+	Name       string
+	Finalizers []string
+	Dependents []Dependent
+	// Err is the error of the context
+	Err error
+}
+
+func (o *PendingError) Error() string {
+	var blockers []string
+	if len(o.Finalizers) > 0 {
+		blockers = append(blockers, fmt.Sprintf("finalizers %v", o.Finalizers))
+	}
+	if len(o.Dependents) > 0 {
+		dependents := make([]string, 0, len(o.Dependents))
+		for _, dependent := range o.Dependents {
+			dependents = append(dependents, dependent.String())
+		}
+		blockers = append(blockers, "dependents "+strings.Join(dependents, ", "))
+	}
+	if len(blockers) == 0 {
+		return fmt.Sprintf("deletion of %s pending: %v", o.Name, o.Err)
+	}
+	return fmt.Sprintf("deletion of %s pending, blocked by %s: %v", o.Name, strings.Join(blockers, "; "), o.Err)
+}
+
+func (o *PendingError) Unwrap() error {
+	return o.Err
+}
+
+// SafeDelete deletes the object name with opts, e.g.:
+//
+//	err := deletion.SafeDelete[*appsv1.Deployment](
+//		ctx,
+//		clientset.AppsV1().Deployments("ns"),
+//		"nginx",
+//		deletion.Options{
+//			Propagation: metav1.DeletePropagationForeground,
+//			Wait:        true,
+//			Timeout:     time.Minute,
+//		},
+//	)
+//
+// A failed precondition returns a Conflict error. When the wait
+// times out, SafeDelete returns a *PendingError. There is no wait
+// with DryRun.
+func SafeDelete[T client.Object](ctx context.Context, c Client[T], name string, opts Options) error {
+	waiting := opts.Wait && !opts.DryRun
+	if waiting && opts.UID == "" {
+		obj, err := c.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return opts.ignoreNotFound(err)
+		}
+		opts.UID = obj.GetUID()
+	}
+	if err := c.Delete(ctx, name, opts.deleteOptions()); err != nil {
+		return opts.ignoreNotFound(err)
+	}
+	if !waiting {
+		return nil
+	}
+
+	waitCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	last, err := waitGone(waitCtx, c, name, opts.UID)
+	if err == nil && opts.Propagation == metav1.DeletePropagationForeground {
+		err = opts.waitDependents(waitCtx)
+	}
+	if err != nil && waitCtx.Err() != nil {
+		return opts.pendingError(ctx, name, last, waitCtx.Err())
+	}
+	return err
+}
+
+func (o Options) deleteOptions() metav1.DeleteOptions {
+	opts := metav1.DeleteOptions{
+		GracePeriodSeconds: o.GracePeriod,
+	}
+	if o.UID != "" || o.ResourceVersion != "" {
+		opts.Preconditions = &metav1.Preconditions{}
+		if o.UID != "" {
+			opts.Preconditions.UID = &o.UID
+		}
+		if o.ResourceVersion != "" {
+			opts.Preconditions.ResourceVersion = &o.ResourceVersion
+		}
+	}
+	if o.Propagation != "" {
+		opts.PropagationPolicy = &o.Propagation
+	}
+	if o.DryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return opts
+}
+
+func (o Options) ignoreNotFound(err error) error {
+	if o.IgnoreNotFound && apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// waitGone waits until the object name with uid is gone. It
+// returns the last state of the object, or nil if it is gone.
+func waitGone[T client.Object](ctx context.Context, c Client[T], name string, uid types.UID) (metav1.Object, error) {
+	var last metav1.Object
+	for {
+		// the watch starts before the get, so a
+		// deletion after the get is not missed
+		w, err := c.Watch(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+		})
+		if err != nil {
+			return last, err
+		}
+		obj, err := c.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || err == nil && obj.GetUID() != uid {
+			w.Stop()
+			return nil, nil
+		}
+		if err != nil {
+			w.Stop()
+			return last, err
+		}
+		last = obj
+
+		gone, err := waitDeleted(ctx, w, name, uid, &last)
+		w.Stop()
+		if gone {
+			return nil, nil
+		}
+		if err != nil {
+			return last, err
+		}
+		// the watch has been closed, watch again
+	}
+}
+
+// waitDeleted waits for the deletion of the object name with uid
+// in the events of w, and updates last with the modifications.
+// It returns false and no error when the watch is closed.
+func waitDeleted(ctx context.Context, w watch.Interface, name string, uid types.UID, last *metav1.Object) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return false, nil
+			}
+			if event.Type == watch.Error {
+				return false, apierrors.FromObject(event.Object)
+			}
+			obj, err := meta.Accessor(event.Object)
+			if err != nil || obj.GetName() != name {
+				continue
+			}
+			if obj.GetUID() != uid || event.Type == watch.Deleted {
+				// deleted, and possibly created again
+				return true, nil
+			}
+			*last = obj
+		}
+	}
+}
+
+// waitDependents waits until no dependent of the object is left.
+// Unlike the object, the dependents are polled every PollInterval
+// (DefaultPollInterval, 1s, if 0), not watched: the owner
+// references cannot be selected by the API server, so a watch
+// would receive all the objects of the dependent resources.
+func (o Options) waitDependents(ctx context.Context) error {
+	if len(o.Dependents) == 0 {
+		return nil
+	}
+	interval := o.PollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+	return wait.PollImmediateUntilWithContext(ctx, interval, func(ctx context.Context) (bool, error) {
+		dependents, err := o.dependents(ctx)
+		return len(dependents) == 0, err
+	})
+}
+
+// dependents returns the dependents of the object not deleted yet
+func (o Options) dependents(ctx context.Context) ([]Dependent, error) {
+	var dependents []Dependent
+	for _, lister := range o.Dependents {
+		list, err := lister.list(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", lister.resource, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			obj, err := meta.Accessor(item)
+			if err != nil {
+				return nil, err
+			}
+			if !ownedBy(obj, o.UID) {
+				continue
+			}
+			dependents = append(dependents, Dependent{
+				Resource:   lister.resource,
+				Namespace:  obj.GetNamespace(),
+				Name:       obj.GetName(),
+				Finalizers: obj.GetFinalizers(),
+			})
+		}
+	}
+	return dependents, nil
+}
+
+// pendingError returns the error describing what blocks the
+// deletion, with last the last state of the object if not gone
+func (o Options) pendingError(ctx context.Context, name string, last metav1.Object, err error) error {
+	pending := &PendingError{Name: name, Err: err}
+	if last != nil {
+		if last.GetNamespace() != "" {
+			pending.Name = last.GetNamespace() + "/" + name
+		}
+		pending.Finalizers = last.GetFinalizers()
+	}
+	// the dependents are listed with the context of the
+	// caller, if the wait timeout only has expired
+	if ctx.Err() == nil {
+		pending.Dependents, _ = o.dependents(ctx)
+	}
+	return pending
+}
+
+func ownedBy(obj metav1.Object, uid types.UID) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
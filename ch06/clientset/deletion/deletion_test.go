@@ -0,0 +1,186 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
+)
+
+// blockDeletion makes the deletions of resource
+// pending, as if the objects had finalizers
+func blockDeletion(clientset *fake.Clientset, resource string) {
+	clientset.PrependReactor("delete", resource, func(ktesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+}
+
+func newPod() *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:       "nginx",
+		Namespace:  "ns",
+		UID:        "uid-pod",
+		Finalizers: []string{"example.com/block"},
+	}}
+}
+
+func TestSafeDelete_Options(t *testing.T) {
+	foreground := metav1.DeletePropagationForeground
+	tests := []struct {
+		name string
+		opts Options
+		want metav1.DeleteOptions
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "grace period and propagation",
+			opts: Options{GracePeriod: pointer.Int64(5), Propagation: foreground},
+			want: metav1.DeleteOptions{GracePeriodSeconds: pointer.Int64(5), PropagationPolicy: &foreground},
+		},
+		{
+			name: "preconditions",
+			opts: Options{UID: "uid-1", ResourceVersion: "10", DryRun: true},
+			want: metav1.DeleteOptions{
+				Preconditions: metav1.NewPreconditionDeleteOptions("uid-1").Preconditions,
+				DryRun:        []string{metav1.DryRunAll},
+			},
+		},
+		{
+			name: "wait",
+			opts: Options{Wait: true},
+			want: *metav1.NewPreconditionDeleteOptions("uid-pod"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opts.ResourceVersion != "" {
+				tt.want.Preconditions.ResourceVersion = &tt.opts.ResourceVersion
+			}
+			clientset := fake.NewSimpleClientset(newPod())
+			var got metav1.DeleteOptions
+			clientset.PrependReactor("delete", "pods", func(action ktesting.Action) (bool, runtime.Object, error) {
+				got = action.(ktesting.DeleteAction).GetDeleteOptions()
+				return false, nil, nil
+			})
+
+			err := SafeDelete[*corev1.Pod](context.Background(), clientset.CoreV1().Pods("ns"), "nginx", tt.opts)
+			if err != nil {
+				t.Fatalf("SafeDelete() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("delete options = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeDelete_NotFound(t *testing.T) {
+	pods := fake.NewSimpleClientset().CoreV1().Pods("ns")
+	for _, wait := range []bool{false, true} {
+		err := SafeDelete[*corev1.Pod](context.Background(), pods, "nginx", Options{Wait: wait})
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("SafeDelete(wait %v) error = %v, want a NotFound error", wait, err)
+		}
+		err = SafeDelete[*corev1.Pod](context.Background(), pods, "nginx", Options{Wait: wait, IgnoreNotFound: true})
+		if err != nil {
+			t.Errorf("SafeDelete(wait %v) error = %v, want no error", wait, err)
+		}
+	}
+}
+
+func TestSafeDelete_Wait(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPod())
+	blockDeletion(clientset, "pods")
+	// the finalizer is removed, and the pod deleted, later
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = clientset.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "ns", "nginx")
+	}()
+
+	err := SafeDelete[*corev1.Pod](context.Background(), clientset.CoreV1().Pods("ns"), "nginx", Options{
+		Wait:    true,
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Errorf("SafeDelete() error = %v", err)
+	}
+}
+
+func TestSafeDelete_Pending(t *testing.T) {
+	tests := []struct {
+		name           string
+		blocked        bool
+		wantFinalizers []string
+		wantErr        string
+	}{
+		{
+			name:           "deployment blocked",
+			blocked:        true,
+			wantFinalizers: []string{"foregroundDeletion"},
+			wantErr: "deletion of ns/nginx pending, blocked by finalizers [foregroundDeletion]; " +
+				"dependents replicasets ns/nginx-1 (finalizers [example.com/block]): context deadline exceeded",
+		},
+		{
+			name: "deployment deleted",
+			wantErr: "deletion of nginx pending, blocked by dependents " +
+				"replicasets ns/nginx-1 (finalizers [example.com/block]): context deadline exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+				Name:       "nginx",
+				Namespace:  "ns",
+				UID:        "uid-dep",
+				Finalizers: []string{"foregroundDeletion"},
+			}}
+			owned := func(name string, owner types.UID) *appsv1.ReplicaSet {
+				return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+					Name:            name,
+					Namespace:       "ns",
+					Finalizers:      []string{"example.com/block"},
+					OwnerReferences: []metav1.OwnerReference{{Name: "owner", UID: owner}},
+				}}
+			}
+			clientset := fake.NewSimpleClientset(dep, owned("nginx-1", "uid-dep"), owned("other-1", "uid-other"))
+			if tt.blocked {
+				blockDeletion(clientset, "deployments")
+			}
+
+			err := SafeDelete[*appsv1.Deployment](context.Background(), clientset.AppsV1().Deployments("ns"), "nginx", Options{
+				Propagation:  metav1.DeletePropagationForeground,
+				Wait:         true,
+				Timeout:      50 * time.Millisecond,
+				Dependents:   []DependentLister{Dependents("replicasets", clientset.AppsV1().ReplicaSets("ns").List)},
+				PollInterval: 10 * time.Millisecond,
+			})
+			var pending *PendingError
+			if !errors.As(err, &pending) {
+				t.Fatalf("SafeDelete() error = %v, want a PendingError", err)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("SafeDelete() error = %v, want to wrap the error of the context", err)
+			}
+			if fmt.Sprint(pending.Finalizers) != fmt.Sprint(tt.wantFinalizers) {
+				t.Errorf("finalizers = %v, want %v", pending.Finalizers, tt.wantFinalizers)
+			}
+			if err.Error() != tt.wantErr {
+				t.Errorf("SafeDelete() error =\n%v\nwant\n%v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Package inspect provides a transport wrapper for the clients
+// created from a rest.Config, to examine the requests they make:
+// it logs the requests, optionally with their redacted bodies,
+// writes them to a journal, and counts them per verb and resource
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// DefaultMaxBodySize is the size the bodies are truncated to
+// when Options.MaxBodySize is 0
+const DefaultMaxBodySize = 4096
+
+// Options are the options of an Inspector
+type Options struct {
+	// Logger, if set, logs a message for each request
+	Logger *logr.Logger
+	// Bodies includes the redacted bodies of the requests
+	// and responses into the logs and journal. The bodies
+	// of watch responses are never included.
+	Bodies bool
+	// MaxBodySize is the size the bodies are truncated to,
+	// DefaultMaxBodySize if 0, no limit if negative
+	MaxBodySize int
+	// Journal, if set, receives an Entry per request,
+	// encoded as a line of JSON
+	Journal io.Writer
+}
+
+// Entry describes a request and its response
+type Entry struct {
+	Time         time.Time     `json:"time"`
+	Method       string        `json:"method"`
+	URL          string        `json:"url"`
+	Verb         string        `json:"verb"`
+	Resource     string        `json:"resource,omitempty"`
+	Namespace    string        `json:"namespace,omitempty"`
+	Name         string        `json:"name,omitempty"`
+	Status       int           `json:"status,omitempty"`
+	Latency      time.Duration `json:"latency"`
+	Error        string        `json:"error,omitempty"`
+	RequestBody  string        `json:"requestBody,omitempty"`
+	ResponseBody string        `json:"responseBody,omitempty"`
+}
+
+// Inspector inspects the requests made through the transports
+// it wraps. It is safe for concurrent use.
+type Inspector struct {
+	options Options
+	stats   Stats
+
+	// journalMu serializes the writes to the journal
+	journalMu sync.Mutex
+}
+
+// New returns an Inspector with options
+func New(options Options) *Inspector {
+	if options.MaxBodySize == 0 {
+		options.MaxBodySize = DefaultMaxBodySize
+	}
+	return &Inspector{options: options}
+}
+
+// Stats returns the counts of the requests
+func (o *Inspector) Stats() *Stats {
+	return &o.stats
+}
+
+// WrapConfig adds the inspection to the transport of config,
+// after the wrappers already defined in config
+func (o *Inspector) WrapConfig(config *rest.Config) {
+	config.WrapTransport = transport.Wrappers(config.WrapTransport, o.Wrap)
+}
+
+// Wrap returns a RoundTripper inspecting the requests
+// made through rt. It is a transport.WrapperFunc.
+func (o *Inspector) Wrap(rt http.RoundTripper) http.RoundTripper {
+	return &roundTripper{inspector: o, delegate: rt}
+}
+
+type roundTripper struct {
+	inspector *Inspector
+	delegate  http.RoundTripper
+}
+
+func (o *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	info := ParseRequest(req.Method, req.URL)
+	entry := Entry{
+		Time:      time.Now(),
+		Method:    req.Method,
+		URL:       req.URL.String(),
+		Verb:      info.Verb,
+		Resource:  info.GroupResource(),
+		Namespace: info.Namespace,
+		Name:      info.Name,
+	}
+	options := o.inspector.options
+	// the patches of Secrets have no kind, the redaction
+	// is decided from the resource of the request
+	secret := info.Group == "" && info.Resource == "secrets"
+
+	if options.Bodies && req.Body != nil && req.GetBody != nil {
+		// GetBody returns a copy of the body
+		// without consuming the one to be sent
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			entry.RequestBody = RedactBody(data, req.Header.Get("Content-Type"), secret, options.MaxBodySize)
+		}
+	}
+
+	resp, err := o.delegate.RoundTrip(req)
+	entry.Latency = time.Since(entry.Time)
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		if options.Bodies && info.Verb != "watch" {
+			entry.ResponseBody, err = readResponseBody(resp, secret, options.MaxBodySize)
+			if err != nil {
+				entry.Error = err.Error()
+			}
+		}
+	}
+
+	o.inspector.stats.record(info, entry.Error != "" || entry.Status >= 400, entry.Latency)
+	o.inspector.log(entry)
+	o.inspector.writeJournal(entry)
+	return resp, err
+}
+
+// readResponseBody reads the body of resp, replaces it
+// with a copy, and returns its redacted version
+func readResponseBody(resp *http.Response, secret bool, maxSize int) (string, error) {
+	if resp.Body == nil {
+		return "", nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	return RedactBody(data, resp.Header.Get("Content-Type"), secret, maxSize), nil
+}
+
+func (o *Inspector) log(entry Entry) {
+	if o.options.Logger == nil {
+		return
+	}
+	keysAndValues := []interface{}{
+		"verb", entry.Verb,
+		"url", entry.URL,
+		"status", entry.Status,
+		"latency", entry.Latency,
+	}
+	if entry.RequestBody != "" {
+		keysAndValues = append(keysAndValues, "requestBody", entry.RequestBody)
+	}
+	if entry.ResponseBody != "" {
+		keysAndValues = append(keysAndValues, "responseBody", entry.ResponseBody)
+	}
+	if entry.Error != "" {
+		keysAndValues = append(keysAndValues, "error", entry.Error)
+	}
+	o.options.Logger.Info(entry.Method, keysAndValues...)
+}
+
+func (o *Inspector) writeJournal(entry Entry) {
+	if o.options.Journal == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+
+	o.journalMu.Lock()
+	defer o.journalMu.Unlock()
+	if _, err = o.options.Journal.Write(data); err != nil && o.options.Logger != nil {
+		o.options.Logger.Error(err, "writing request journal")
+	}
+}
@@ -0,0 +1,271 @@
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newServer returns a server answering all requests with a
+// Secret, or a NotFound status for the name "missing"
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+			return
+		}
+		io.WriteString(w, `{"kind":"Secret","apiVersion":"v1","metadata":{"name":"creds"},"data":{"password":"c2VjcmV0"}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestInspector(t *testing.T) {
+	server := newServer(t)
+	var journal, logs bytes.Buffer
+	logger := funcr.New(func(prefix, args string) {
+		logs.WriteString(args + "\n")
+	}, funcr.Options{})
+	inspector := New(Options{
+		Logger:  &logger,
+		Bodies:  true,
+		Journal: &journal,
+	})
+
+	config := &rest.Config{Host: server.URL}
+	inspector.WrapConfig(config)
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := clientset.CoreV1().Secrets("project1")
+	ctx := context.Background()
+
+	secret, err := secrets.Get(ctx, "creds", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(secret.Data["password"]) != "secret" {
+		t.Errorf("the response body was modified: %+v", secret.Data)
+	}
+	if _, err = secrets.Get(ctx, "missing", metav1.GetOptions{}); err == nil {
+		t.Fatal("Get(missing): expected an error")
+	}
+	_, err = secrets.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds"},
+		StringData: map[string]string{"password": "secret"},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// a patch has no kind, and its key is not sensitive
+	_, err = secrets.Patch(ctx, "creds", types.MergePatchType,
+		[]byte(`{"stringData":{"api-key":"secret"}}`), metav1.PatchOptions{})
+	if err != nil {
+		t.Fatalf("Patch() error = %v", err)
+	}
+
+	if got := inspector.Stats().Get("get", "secrets"); got.Requests != 2 || got.Errors != 1 {
+		t.Errorf("get secrets = %+v, want 2 requests and 1 error", got)
+	}
+	if got := inspector.Stats().Get("create", "secrets"); got.Requests != 1 || got.Errors != 0 {
+		t.Errorf("create secrets = %+v, want 1 request and no error", got)
+	}
+
+	var entries []Entry
+	decoder := json.NewDecoder(&journal)
+	for decoder.More() {
+		var entry Entry
+		if err = decoder.Decode(&entry); err != nil {
+			t.Fatalf("decoding journal: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("journal has %d entries, want 4", len(entries))
+	}
+	first := entries[0]
+	if first.Verb != "get" || first.Namespace != "project1" || first.Name != "creds" || first.Status != 200 {
+		t.Errorf("first entry = %+v", first)
+	}
+	if strings.Contains(first.ResponseBody, "c2VjcmV0") || !strings.Contains(first.ResponseBody, Redacted) {
+		t.Errorf("response body not redacted: %s", first.ResponseBody)
+	}
+	if entries[1].Status != 404 {
+		t.Errorf("second entry status = %d, want 404", entries[1].Status)
+	}
+	if body := entries[2].RequestBody; strings.Contains(body, `"secret"`) || !strings.Contains(body, Redacted) {
+		t.Errorf("request body not redacted: %s", body)
+	}
+	if body := entries[3].RequestBody; body != `{"stringData":{"api-key":"REDACTED"}}` {
+		t.Errorf("patch body not redacted: %s", body)
+	}
+
+	if strings.Count(logs.String(), `"verb"=`) != 4 || strings.Contains(logs.String(), "c2VjcmV0") {
+		t.Errorf("unexpected logs:\n%s", logs.String())
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		method string
+		url    string
+		want   RequestInfo
+	}{
+		{
+			method: "GET", url: "/api/v1/namespaces/ns/pods",
+			want: RequestInfo{Verb: "list", Version: "v1", Namespace: "ns", Resource: "pods"},
+		},
+		{
+			method: "GET", url: "/api/v1/pods?watch=true",
+			want: RequestInfo{Verb: "watch", Version: "v1", Resource: "pods"},
+		},
+		{
+			method: "GET", url: "/api/v1/namespaces/ns/pods/nginx/log",
+			want: RequestInfo{Verb: "get", Version: "v1", Namespace: "ns", Resource: "pods", Name: "nginx", Subresource: "log"},
+		},
+		{
+			method: "PATCH", url: "/apis/apps/v1/namespaces/ns/deployments/nginx",
+			want: RequestInfo{Verb: "patch", Group: "apps", Version: "v1", Namespace: "ns", Resource: "deployments", Name: "nginx"},
+		},
+		{
+			method: "DELETE", url: "/apis/apps/v1/namespaces/ns/deployments",
+			want: RequestInfo{Verb: "deletecollection", Group: "apps", Version: "v1", Namespace: "ns", Resource: "deployments"},
+		},
+		{
+			method: "PUT", url: "/api/v1/namespaces/ns/status",
+			want: RequestInfo{Verb: "update", Version: "v1", Namespace: "ns", Resource: "namespaces", Name: "ns", Subresource: "status"},
+		},
+		{
+			method: "GET", url: "/api/v1/namespaces/ns",
+			want: RequestInfo{Verb: "get", Version: "v1", Namespace: "ns", Resource: "namespaces", Name: "ns"},
+		},
+		{
+			method: "GET", url: "/version",
+			want: RequestInfo{Verb: "get"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Path = u.Path
+			if got := ParseRequest(tt.method, u); got != tt.want {
+				t.Errorf("ParseRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		secret      bool
+		maxSize     int
+		want        string
+	}{
+		{
+			name:        "secret list",
+			body:        `{"kind":"SecretList","items":[{"metadata":{"name":"a"},"data":{"key":"dmFsdWU="}}]}`,
+			contentType: "application/json",
+			secret:      true,
+			want:        `{"items":[{"data":{"key":"REDACTED"},"metadata":{"name":"a"}}],"kind":"SecretList"}`,
+		},
+		{
+			name:        "secret merge patch",
+			body:        `{"data":{"key":"dmFsdWU=","old":null},"stringData":{"other":"value"}}`,
+			contentType: "application/merge-patch+json",
+			secret:      true,
+			want:        `{"data":{"key":"REDACTED","old":null},"stringData":{"other":"REDACTED"}}`,
+		},
+		{
+			name:        "secret apply patch",
+			body:        `{"apiVersion":"v1","metadata":{"name":"a"},"stringData":{"key":"value"}}`,
+			contentType: "application/apply-patch+json",
+			secret:      true,
+			want:        `{"apiVersion":"v1","metadata":{"name":"a"},"stringData":{"key":"REDACTED"}}`,
+		},
+		{
+			name: "secret JSON patch",
+			body: `[{"op":"add","path":"/data/key","value":"dmFsdWU="},` +
+				`{"op":"replace","path":"/stringData","value":{"key":"value"}},` +
+				`{"op":"add","path":"/metadata/labels/app","value":"web"}]`,
+			contentType: "application/json-patch+json",
+			secret:      true,
+			want: `[{"op":"add","path":"/data/key","value":"REDACTED"},` +
+				`{"op":"replace","path":"/stringData","value":{"key":"REDACTED"}},` +
+				`{"op":"add","path":"/metadata/labels/app","value":"web"}]`,
+		},
+		{
+			name:        "token",
+			body:        `{"kind":"TokenReview","spec":{"token":"abc"}}`,
+			contentType: "application/json",
+			want:        `{"kind":"TokenReview","spec":{"token":"REDACTED"}}`,
+		},
+		{
+			name:        "config map",
+			body:        `{"kind":"ConfigMap","data":{"key":"value"}}`,
+			contentType: "application/json",
+			want:        `{"data":{"key":"value"},"kind":"ConfigMap"}`,
+		},
+		{
+			name:        "truncated",
+			body:        `{"kind":"ConfigMap"}`,
+			contentType: "application/json",
+			maxSize:     8,
+			want:        `{"kind":... (20 bytes)`,
+		},
+		{
+			name:        "protobuf",
+			body:        "k8s\x00\x0a",
+			contentType: "application/vnd.kubernetes.protobuf",
+			want:        "<5 bytes of application/vnd.kubernetes.protobuf>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactBody([]byte(tt.body), tt.contentType, tt.secret, tt.maxSize); got != tt.want {
+				t.Errorf("RedactBody() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats_Print(t *testing.T) {
+	var stats Stats
+	stats.record(RequestInfo{Verb: "list", Group: "apps", Resource: "deployments"}, false, 2000)
+	stats.record(RequestInfo{Verb: "list", Group: "apps", Resource: "deployments"}, true, 4000)
+	stats.record(RequestInfo{Verb: "get", Path: "/version"}, false, 1000)
+
+	var out bytes.Buffer
+	if err := stats.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `RESOURCE          VERB  REQUESTS  ERRORS  MEAN LATENCY
+/version          get   1         0       1µs
+deployments.apps  list  2         1       3µs
+`
+	if out.String() != want {
+		t.Errorf("Print() =\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,107 @@
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Redacted replaces the redacted values
+const Redacted = "REDACTED"
+
+// sensitiveKeys are the keys of the values redacted in any object
+var sensitiveKeys = map[string]bool{
+	"token":                   true,
+	"password":                true,
+	"client-key-data":         true,
+	"client-certificate-data": true,
+}
+
+// RedactBody returns a printable version of a request or response
+// body of contentType, truncated to maxSize bytes if maxSize > 0.
+// In JSON bodies, the values of the token and password fields of
+// any object are replaced by REDACTED. secret is true for the bodies
+// sent to and received from the secrets resource: the values of all
+// the data and stringData fields are redacted as well, whether the
+// body is a Secret, a list, or a merge, strategic merge, JSON or
+// apply patch, which have no kind. Other bodies, e.g. Protobuf,
+// are replaced by their size and content type.
+func RedactBody(body []byte, contentType string, secret bool, maxSize int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if !strings.Contains(contentType, "json") {
+		return fmt.Sprintf("<%d bytes of %s>", len(body), contentType)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Sprintf("<%d bytes of invalid %s>", len(body), contentType)
+	}
+	redacted, err := json.Marshal(redact(value, secret))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes of %s>", len(body), contentType)
+	}
+	if maxSize > 0 && len(redacted) > maxSize {
+		return string(redacted[:maxSize]) + fmt.Sprintf("... (%d bytes)", len(redacted))
+	}
+	return string(redacted)
+}
+
+// redact redacts value, and the objects it contains, in place.
+// secret is true if value is the body of a secrets request.
+func redact(value interface{}, secret bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			_, isString := child.(string)
+			switch {
+			case secret && (key == "data" || key == "stringData"):
+				v[key] = redactValues(child)
+			case isString && sensitiveKeys[key]:
+				v[key] = Redacted
+			default:
+				v[key] = redact(child, secret)
+			}
+		}
+		// the operations of a JSON patch set the data
+		// with a path, e.g. {"op": "add", "path": "/data/key"}
+		if path, ok := v["path"].(string); ok && secret && isDataPath(path) {
+			if _, found := v["value"]; found {
+				v["value"] = redactValues(v["value"])
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i], secret)
+		}
+	}
+	return value
+}
+
+// redactValues redacts value, or its values if it is an object.
+// The null values, removing data, are kept.
+func redactValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for key, child := range v {
+			if child != nil {
+				v[key] = Redacted
+			}
+		}
+		return v
+	default:
+		return Redacted
+	}
+}
+
+func isDataPath(path string) bool {
+	for _, field := range []string{"/data", "/stringData"} {
+		if path == field || strings.HasPrefix(path, field+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package inspect
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RequestInfo describes a request to the API Server
+type RequestInfo struct {
+	// Verb is the Kubernetes verb of a resource request (get, list,
+	// watch, create, update, patch, delete, deletecollection),
+	// or the lowercase HTTP method of a non-resource request
+	Verb string
+	// Group, Version, Resource and Subresource identify the
+	// resource of the request, Resource is empty for
+	// non-resource requests (e.g. /version or /healthz)
+	Group       string
+	Version     string
+	Resource    string
+	Subresource string
+	// Namespace and Name are the namespace and name
+	// of the object, when the request targets one
+	Namespace string
+	Name      string
+	// Path is the path of the URL
+	Path string
+}
+
+// GroupResource returns the resource in the form resource[.group][/subresource]
+func (o RequestInfo) GroupResource() string {
+	if o.Resource == "" {
+		return ""
+	}
+	result := o.Resource
+	if o.Group != "" {
+		result += "." + o.Group
+	}
+	if o.Subresource != "" {
+		result += "/" + o.Subresource
+	}
+	return result
+}
+
+// namespaceSubresources are the subresources of namespaces
+var namespaceSubresources = map[string]bool{"status": true, "finalize": true}
+
+// ParseRequest returns the information about a request
+// from its method and URL, following the API Server paths:
+//
+//	/api/{version}/[namespaces/{namespace}/]{resource}[/{name}[/{subresource}]]
+//	/apis/{group}/{version}/[namespaces/{namespace}/]{resource}[/{name}[/{subresource}]]
+func ParseRequest(method string, u *url.URL) RequestInfo {
+	info := RequestInfo{
+		Verb: strings.ToLower(method),
+		Path: u.Path,
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch {
+	case len(parts) >= 3 && parts[0] == "api":
+		info.Version = parts[1]
+		parts = parts[2:]
+	case len(parts) >= 4 && parts[0] == "apis":
+		info.Group = parts[1]
+		info.Version = parts[2]
+		parts = parts[3:]
+	default:
+		return info
+	}
+
+	// namespaces/{namespace} is the namespace of the object, or the
+	// namespace itself (e.g. namespaces/foo or namespaces/foo/status)
+	if parts[0] == "namespaces" && len(parts) >= 2 {
+		info.Namespace = parts[1]
+		if len(parts) >= 3 && !namespaceSubresources[parts[2]] {
+			parts = parts[2:]
+		}
+	}
+	info.Resource = parts[0]
+	if len(parts) >= 2 {
+		info.Name = parts[1]
+	}
+	if len(parts) >= 3 {
+		info.Subresource = strings.Join(parts[2:], "/")
+	}
+
+	info.Verb = resourceVerb(method, info.Name, u.Query())
+	return info
+}
+
+func resourceVerb(method, name string, query url.Values) string {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		switch {
+		case query.Get("watch") == "true" || query.Get("watch") == "1":
+			return "watch"
+		case name == "":
+			return "list"
+		default:
+			return "get"
+		}
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		if name == "" {
+			return "deletecollection"
+		}
+		return "delete"
+	}
+	return strings.ToLower(method)
+}
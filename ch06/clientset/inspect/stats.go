@@ -0,0 +1,118 @@
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Key identifies the requests counted together
+type Key struct {
+	// Verb is the verb of the requests, see RequestInfo
+	Verb string
+	// Resource is the resource of the requests in the form
+	// resource[.group][/subresource], or the path of
+	// non-resource requests
+	Resource string
+}
+
+// Count is the count of the requests with the same key
+type Count struct {
+	Key
+	// Requests is the number of requests
+	Requests int
+	// Errors is the number of requests failed without response,
+	// or with a response status code 400 or greater
+	Errors int
+	// Latency is the cumulated latency of the requests
+	Latency time.Duration
+}
+
+// MeanLatency returns the mean latency of the requests
+func (o Count) MeanLatency() time.Duration {
+	if o.Requests == 0 {
+		return 0
+	}
+	return o.Latency / time.Duration(o.Requests)
+}
+
+// Stats counts the requests per verb and resource.
+// It is safe for concurrent use.
+type Stats struct {
+	mu     sync.Mutex
+	counts map[Key]*Count
+}
+
+// record counts a request
+func (o *Stats) record(info RequestInfo, failed bool, latency time.Duration) {
+	key := Key{Verb: info.Verb, Resource: info.GroupResource()}
+	if key.Resource == "" {
+		key.Resource = info.Path
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.counts == nil {
+		o.counts = map[Key]*Count{}
+	}
+	count, found := o.counts[key]
+	if !found {
+		count = &Count{Key: key}
+		o.counts[key] = count
+	}
+	count.Requests++
+	if failed {
+		count.Errors++
+	}
+	count.Latency += latency
+}
+
+// Counts returns the counts, sorted by resource and verb
+func (o *Stats) Counts() []Count {
+	o.mu.Lock()
+	result := make([]Count, 0, len(o.counts))
+	for _, count := range o.counts {
+		result = append(result, *count)
+	}
+	o.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Resource != result[j].Resource {
+			return result[i].Resource < result[j].Resource
+		}
+		return result[i].Verb < result[j].Verb
+	})
+	return result
+}
+
+// Get returns the count of the requests for verb and resource
+func (o *Stats) Get(verb, resource string) Count {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if count, found := o.counts[Key{Verb: verb, Resource: resource}]; found {
+		return *count
+	}
+	return Count{Key: Key{Verb: verb, Resource: resource}}
+}
+
+// Reset sets all the counts to zero
+func (o *Stats) Reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.counts = nil
+}
+
+// Print writes the counts as a table to w
+func (o *Stats) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tVERB\tREQUESTS\tERRORS\tMEAN LATENCY")
+	for _, count := range o.Counts() {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n",
+			count.Resource, count.Verb, count.Requests, count.Errors,
+			count.MeanLatency().Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	goerrors "errors"
 	"flag"
 	"fmt"
 	"os"
@@ -16,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/watch"
 	acappsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -24,6 +26,15 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kprogo/ch6/clientset/clientconfig"
+	"github.com/kprogo/ch6/clientset/deletion"
+	"github.com/kprogo/ch6/clientset/inspect"
+	"github.com/kprogo/ch6/clientset/mutate"
+	"github.com/kprogo/ch6/clientset/paginate"
+	"github.com/kprogo/ch6/clientset/resumewatch"
+	"github.com/kprogo/ch6/clientset/selector"
+	"github.com/kprogo/ch6/clientset/ssapreview"
 )
 
 func main() {
@@ -35,8 +46,18 @@ func main() {
 
 	// # Examining the requests
 	klog.InitFlags(nil)
+	forceConflicts := flag.Bool("force-conflicts", false,
+		"take the ownership of the fields owned by other managers when applying")
 	flag.Parse()
 
+	// ## Inspecting the requests
+	logger := klog.Background().WithName("requests")
+	inspector := inspect.New(inspect.Options{
+		Logger: &logger,
+		Bodies: true,
+	})
+	inspector.WrapConfig(config)
+
 	// # Getting a clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -120,6 +141,22 @@ func main() {
 		fmt.Printf("%s\n", pod.GetName())
 	}
 
+	// ## Listing resources page by page
+	podPager := paginate.New(
+		clientset.CoreV1().Pods("").List,
+		func(l *corev1.PodList) []corev1.Pod { return l.Items },
+	)
+	podPager.PageSize = 100
+	podPager.ExpiredPolicy = paginate.ExpiredRestart
+	result, err := podPager.Each(ctx, metav1.ListOptions{}, func(pod corev1.Pod) error {
+		fmt.Printf("%s\n", pod.GetName())
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%d pods in %d pages at version %s\n", result.Items, result.Pages, result.ResourceVersion)
+
 	// # Filtering the result of a list
 	// ## Setting LabelSelector using the labels package
 	// ### Using Requirements
@@ -258,6 +295,12 @@ func main() {
 		fmt.Printf("%s\n", pod.GetName())
 	}
 
+	// ## Using the selector builder
+	err = listWithSelectorBuilder(ctx, clientset)
+	if err != nil {
+		panic(err)
+	}
+
 	uid := createdPod.GetUID()
 	rv := createdPod.GetResourceVersion()
 
@@ -334,6 +377,30 @@ func main() {
 		}
 	}
 
+	// ## Waiting for the deletion with SafeDelete
+	// The pod is deleted with the same options, and SafeDelete
+	// waits until it is actually gone, or reports what blocks it
+	err = deletion.SafeDelete[*corev1.Pod](
+		ctx,
+		clientset.CoreV1().Pods("project1"),
+		"nginx-pod",
+		deletion.Options{
+			GracePeriod:    pointer.Int64(5),
+			Propagation:    metav1.DeletePropagationForeground,
+			IgnoreNotFound: true,
+			Wait:           true,
+			Timeout:        time.Minute,
+		},
+	)
+	if err != nil {
+		var pending *deletion.PendingError
+		if goerrors.As(err, &pending) {
+			fmt.Printf("%v\n", pending)
+		} else {
+			panic(err)
+		}
+	}
+
 	// # Deleting a collection of resources
 	err = clientset.
 		CoreV1().
@@ -474,6 +541,37 @@ func main() {
 
 	time.Sleep(3 * time.Second)
 
+	// ## Retrying the conflicts with the mutate helper
+	// The deployment is read again and the mutation applied
+	// again to the latest version after each conflict
+	mutatedDep, attempts, err := mutate.Mutate[*appsv1.Deployment](
+		ctx,
+		clientset.AppsV1().Deployments("project1"),
+		"nginx",
+		func(dep *appsv1.Deployment) error {
+			dep.Spec.Replicas = pointer.Int32(2)
+			return nil
+		},
+	)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			fmt.Printf("Deployment %q is not found\n", "nginx")
+			os.Exit(1)
+		} else if errors.IsInvalid(err) {
+			fmt.Printf("Deployment specification is invalid: %v\n", err)
+			os.Exit(1)
+		} else if errors.IsConflict(err) {
+			fmt.Printf("Conflict patching deployment %q: %v\n", "nginx", err)
+			os.Exit(1)
+		} else {
+			panic(err)
+		}
+	}
+	fmt.Printf("Deployment %q patched after %d attempts, resource version %s\n",
+		mutatedDep.GetName(), attempts, mutatedDep.GetResourceVersion())
+
+	time.Sleep(3 * time.Second)
+
 	// # Applying resources server-side with Patch
 	ssaDep := appsv1.Deployment{
 		Spec: appsv1.DeploymentSpec{
@@ -538,6 +636,35 @@ func main() {
 
 	time.Sleep(3 * time.Second)
 
+	// ## Previewing the apply
+	// The apply is forced only if the conflicts shown
+	// in the preview are confirmed with -force-conflicts
+	deployments := clientset.AppsV1().Deployments("project1")
+	applier := ssapreview.New(deployments.Get, deployments.Patch, "my-program")
+	applier.Confirm = func(preview *ssapreview.Preview) bool {
+		if err := preview.Print(os.Stdout); err != nil {
+			panic(err)
+		}
+		return len(preview.Conflicts) == 0 || *forceConflicts
+	}
+	patchedDep, _, err = applier.Apply(ctx, &ssaDep)
+	if err != nil {
+		if goerrors.Is(err, ssapreview.ErrAborted) {
+			fmt.Printf("Apply aborted, use -force-conflicts to take the ownership of the fields\n")
+		} else if errors.IsInvalid(err) {
+			fmt.Printf("Deployment specification is invalid: %v\n", err)
+			os.Exit(1)
+		} else if errors.IsConflict(err) {
+			fmt.Printf("Conflict server-side patching deployment %q: %v\n", "nginx", err)
+			os.Exit(1)
+		} else {
+			panic(err)
+		}
+	}
+	_ = patchedDep
+
+	time.Sleep(3 * time.Second)
+
 	// # Server-Side Apply using Apply Configurations
 	deployConfig := acappsv1.Deployment(
 		"nginx",
@@ -568,6 +695,9 @@ func main() {
 	}
 	_ = patchedDep
 
+	// ## Counting the requests
+	inspector.Stats().Print(os.Stdout)
+
 	// # Watching resources
 	watcher, err := clientset.AppsV1().
 		Deployments("project1").
@@ -590,6 +720,12 @@ func main() {
 		}
 	}
 
+	// ## Resuming the watch
+	err = watchWithResume(ctx, clientset)
+	if err != nil {
+		panic(err)
+	}
+
 }
 
 // # Connecting to the cluster
@@ -662,3 +798,58 @@ func getConfig6() (*rest.Config, error) {
 	).ClientConfig()
 
 }
+
+// ### Chaining all the sources
+func getConfig7() (*rest.Config, error) {
+	loader := clientconfig.ConfigLoader{
+		ClientOptions: clientconfig.ClientOptions{
+			QPS:   20,
+			Burst: 40,
+		},
+	}
+	return loader.Load()
+}
+
+// ## Using the selector builder
+func listWithSelectorBuilder(ctx context.Context, clientset kubernetes.Interface) error {
+	podSelector, err := selector.New().
+		ForResource("pods").
+		In("mykey", "value1", "value2").
+		DoesNotExist("legacy").
+		FieldEquals("status.phase", "Running").
+		Build()
+	if err != nil {
+		return err
+	}
+	podList, err := clientset.
+		CoreV1().
+		Pods("").
+		List(ctx, podSelector.ListOptions())
+	if err != nil {
+		return err
+	}
+	for _, pod := range podList.Items {
+		fmt.Printf("%s\n", pod.GetName())
+	}
+	return nil
+}
+
+// ## Resuming the watch
+// The watch starts from the resource version of a list, is resumed
+// when the server closes it, and the deployments are listed again
+// when the resource version has expired
+func watchWithResume(ctx context.Context, clientset kubernetes.Interface) error {
+	deployments := clientset.AppsV1().Deployments("project1")
+	watcher := resumewatch.New(deployments.List, deployments.Watch)
+	watcher.OnRelist = func() {
+		fmt.Printf("resource version expired, listing again\n")
+	}
+
+	fmt.Printf("==============================\nWatching, press Ctrl-c to exit\n==============================\n")
+	return watcher.Run(ctx, func(ev watch.Event) error {
+		if v, ok := ev.Object.(*appsv1.Deployment); ok {
+			fmt.Printf("%s %s\n", ev.Type, v.GetName())
+		}
+		return nil
+	})
+}
@@ -0,0 +1,220 @@
+// Package managedfields decodes the managed fields of an object,
+// i.e. the sets of fields owned by each field manager, into an
+// ownership tree of the fields
+package managedfields
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// BeforeFirstApplyManager is the manager owning the fields set
+// with create or update operations before the first server-side
+// apply of an object, when the API Server has not tracked the
+// managers yet
+const BeforeFirstApplyManager = "before-first-apply"
+
+// Owner is a field manager owning a field
+type Owner struct {
+	Manager     string
+	Operation   metav1.ManagedFieldsOperationType
+	APIVersion  string
+	Subresource string
+	Time        *metav1.Time
+}
+
+func (o Owner) String() string {
+	details := []string{string(o.Operation)}
+	if o.Subresource != "" {
+		details = append(details, o.Subresource)
+	}
+	if o.Time != nil {
+		details = append(details, o.Time.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s (%s)", o.Manager, strings.Join(details, ", "))
+}
+
+// Field is a node of the ownership tree
+type Field struct {
+	// Path is the path of the field, e.g. .spec.replicas,
+	// or .spec.template.spec.containers[name="main"]
+	Path string
+	// Element is the last element of the path, e.g. .replicas
+	Element string
+	// Owners are the managers owning the field, sorted by
+	// manager. A field containing owned fields may have no owner.
+	Owners []Owner
+	// Children are the fields contained by the field,
+	// e.g. the fields of an object or the items of a list
+	Children []*Field
+
+	element fieldpath.PathElement
+}
+
+// Shared returns true if the field is owned by several managers
+func (o *Field) Shared() bool {
+	return len(o.managers()) > 1
+}
+
+// Leftover returns true if the field is owned
+// by the BeforeFirstApplyManager manager
+func (o *Field) Leftover() bool {
+	for _, owner := range o.Owners {
+		if owner.Manager == BeforeFirstApplyManager {
+			return true
+		}
+	}
+	return false
+}
+
+// Highlighted returns true if the field is shared or a leftover
+func (o *Field) Highlighted() bool {
+	return o.Shared() || o.Leftover()
+}
+
+// Walk calls fn for the field and its descendants, depth first
+func (o *Field) Walk(fn func(*Field)) {
+	fn(o)
+	for _, child := range o.Children {
+		child.Walk(fn)
+	}
+}
+
+// Highlighted returns the fields of the tree which are
+// shared or leftovers, in the order of the tree
+func Highlighted(root *Field) []*Field {
+	var fields []*Field
+	root.Walk(func(field *Field) {
+		if field.Highlighted() {
+			fields = append(fields, field)
+		}
+	})
+	return fields
+}
+
+// managers returns the distinct managers
+// of the field, as a manager may own a field
+// with an update and with an apply
+func (o *Field) managers() []string {
+	var managers []string
+	for _, owner := range o.Owners {
+		if len(managers) == 0 || managers[len(managers)-1] != owner.Manager {
+			managers = append(managers, owner.Manager)
+		}
+	}
+	return managers
+}
+
+// Tree returns the ownership tree of the fields of obj, decoded from
+// its managed fields. The root of the tree is the object itself.
+func Tree(obj metav1.Object) (*Field, error) {
+	root := &Field{}
+	for _, entry := range obj.GetManagedFields() {
+		if entry.FieldsType != "FieldsV1" || entry.FieldsV1 == nil {
+			return nil, fmt.Errorf("manager %s: unsupported fields type %q", entry.Manager, entry.FieldsType)
+		}
+		set := &fieldpath.Set{}
+		if err := set.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			return nil, fmt.Errorf("manager %s: decoding fields: %w", entry.Manager, err)
+		}
+		owner := Owner{
+			Manager:     entry.Manager,
+			Operation:   entry.Operation,
+			APIVersion:  entry.APIVersion,
+			Subresource: entry.Subresource,
+			Time:        entry.Time,
+		}
+		set.Iterate(func(path fieldpath.Path) {
+			field := root.insert(path)
+			field.Owners = append(field.Owners, owner)
+		})
+	}
+	root.sort()
+	return root, nil
+}
+
+// insert returns the descendant field at path, created if needed
+func (o *Field) insert(path fieldpath.Path) *Field {
+	field := o
+	for i, element := range path {
+		var child *Field
+		for _, c := range field.Children {
+			if c.element.Equals(element) {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			child = &Field{
+				Path:    path[:i+1].String(),
+				Element: element.String(),
+				element: element,
+			}
+			field.Children = append(field.Children, child)
+		}
+		field = child
+	}
+	return field
+}
+
+func (o *Field) sort() {
+	sort.SliceStable(o.Owners, func(i, j int) bool {
+		return o.Owners[i].Manager < o.Owners[j].Manager
+	})
+	sort.Slice(o.Children, func(i, j int) bool {
+		return o.Children[i].element.Less(o.Children[j].element)
+	})
+	for _, child := range o.Children {
+		child.sort()
+	}
+}
+
+// Print writes the tree of the fields under root, indented, with
+// their owners, and marks the fields owned by several managers
+// as SHARED, and by the BeforeFirstApplyManager as LEFTOVER
+func Print(w io.Writer, root *Field) error {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tNOTES\tOWNERS")
+	for _, child := range root.Children {
+		printField(tw, child, 0)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// the fields without owner are padded with spaces
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printField(w io.Writer, field *Field, depth int) {
+	owners := make([]string, 0, len(field.Owners))
+	for _, owner := range field.Owners {
+		owners = append(owners, owner.String())
+	}
+	var marks []string
+	if field.Shared() {
+		marks = append(marks, "SHARED")
+	}
+	if field.Leftover() {
+		marks = append(marks, "LEFTOVER")
+	}
+	fmt.Fprintf(w, "%s%s\t%s\t%s\n",
+		strings.Repeat("  ", depth), field.Element,
+		strings.Join(marks, " "), strings.Join(owners, ", "))
+	for _, child := range field.Children {
+		printField(w, child, depth+1)
+	}
+}
@@ -0,0 +1,105 @@
+package managedfields
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func managedFields(t *testing.T) []metav1.ManagedFieldsEntry {
+	t.Helper()
+	updated := metav1.NewTime(time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC))
+	entry := func(manager string, operation metav1.ManagedFieldsOperationType, fields string) metav1.ManagedFieldsEntry {
+		return metav1.ManagedFieldsEntry{
+			Manager:    manager,
+			Operation:  operation,
+			APIVersion: "apps/v1",
+			Time:       &updated,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+		}
+	}
+	return []metav1.ManagedFieldsEntry{
+		entry("my-program", metav1.ManagedFieldsOperationApply, `{
+			"f:spec": {
+				"f:replicas": {},
+				"f:template": {"f:spec": {"f:containers": {
+					"k:{\"name\":\"main\"}": {".": {}, "f:image": {}, "f:name": {}}
+				}}}
+			}
+		}`),
+		entry("kubectl", metav1.ManagedFieldsOperationUpdate, `{
+			"f:metadata": {"f:labels": {".": {}, "f:app": {}}},
+			"f:spec": {"f:replicas": {}}
+		}`),
+		entry(BeforeFirstApplyManager, metav1.ManagedFieldsOperationUpdate, `{
+			"f:metadata": {"f:annotations": {"f:note": {}}}
+		}`),
+	}
+}
+
+func TestTree(t *testing.T) {
+	dep := &appsv1.Deployment{}
+	dep.ManagedFields = managedFields(t)
+	root, err := Tree(dep)
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+
+	var highlighted []string
+	for _, field := range Highlighted(root) {
+		highlighted = append(highlighted, field.Path)
+	}
+	want := []string{".metadata.annotations.note", ".spec.replicas"}
+	if strings.Join(highlighted, " ") != strings.Join(want, " ") {
+		t.Errorf("Highlighted() = %v, want %v", highlighted, want)
+	}
+
+	var buf bytes.Buffer
+	if err = Print(&buf, root); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	wantPrint := `FIELD                  NOTES     OWNERS
+.metadata
+  .annotations
+    .note              LEFTOVER  before-first-apply (Update, 2022-10-01T10:00:00Z)
+  .labels                        kubectl (Update, 2022-10-01T10:00:00Z)
+    .app                         kubectl (Update, 2022-10-01T10:00:00Z)
+.spec
+  .replicas            SHARED    kubectl (Update, 2022-10-01T10:00:00Z), my-program (Apply, 2022-10-01T10:00:00Z)
+  .template
+    .spec
+      .containers
+        [name="main"]            my-program (Apply, 2022-10-01T10:00:00Z)
+          .image                 my-program (Apply, 2022-10-01T10:00:00Z)
+          .name                  my-program (Apply, 2022-10-01T10:00:00Z)
+`
+	if buf.String() != wantPrint {
+		t.Errorf("Print() =\n%s\nwant\n%s", buf.String(), wantPrint)
+	}
+}
+
+func TestTree_Unstructured(t *testing.T) {
+	u := &unstructured.Unstructured{}
+	u.SetManagedFields(managedFields(t))
+	root, err := Tree(u)
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	fields := 0
+	root.Walk(func(*Field) { fields++ })
+	// the root, and the fields of TestTree
+	if fields != 14 {
+		t.Errorf("%d fields, want 14", fields)
+	}
+
+	u.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "old", FieldsType: "FieldsV0"}})
+	if _, err = Tree(u); err == nil {
+		t.Errorf("Tree() with an unsupported fields type: expected an error")
+	}
+}
@@ -0,0 +1,254 @@
+// Package mutate modifies a resource with a patch computed from
+// a mutation function, and retries when the resource has been
+// modified concurrently, reading and mutating it again
+package mutate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gomodules.xyz/jsonpatch/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Default values of the Mutator fields
+const (
+	DefaultTimeout        = 30 * time.Second
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+	DefaultJitter         = 0.5
+)
+
+// GetFunc is a get call, e.g. clientset.AppsV1().Deployments("ns").Get
+type GetFunc[T client.Object] func(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+
+// PatchFunc is a patch call, e.g. clientset.AppsV1().Deployments("ns").Patch
+type PatchFunc[T client.Object] func(
+	ctx context.Context,
+	name string,
+	pt types.PatchType,
+	data []byte,
+	opts metav1.PatchOptions,
+	subresources ...string,
+) (T, error)
+
+// Client is a typed client with get and patch calls,
+// e.g. clientset.AppsV1().Deployments("ns")
+type Client[T client.Object] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Patch(
+		ctx context.Context,
+		name string,
+		pt types.PatchType,
+		data []byte,
+		opts metav1.PatchOptions,
+		subresources ...string,
+	) (T, error)
+}
+
+// Mutator mutates resources
+type Mutator[T client.Object] struct {
+	get   GetFunc[T]
+	patch PatchFunc[T]
+
+	// PatchType is the type of the patches: StrategicMergePatchType
+	// (the default, for the native resources only), MergePatchType
+	// or JSONPatchType
+	PatchType types.PatchType
+	// PatchOptions are the options of the patch calls,
+	// e.g. the field manager or dry run
+	PatchOptions metav1.PatchOptions
+	// Timeout is the maximum duration of a mutation,
+	// including the retries, DefaultTimeout if 0
+	Timeout time.Duration
+	// InitialBackoff and MaxBackoff define the exponential backoff
+	// between two attempts, DefaultInitialBackoff and
+	// DefaultMaxBackoff if 0
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter is the maximum factor added to the backoff, randomly,
+	// so concurrent writers do not retry at the same time,
+	// DefaultJitter if 0, no jitter if negative
+	Jitter float64
+}
+
+// New returns a Mutator using the get and patch calls
+// of a resource, e.g.:
+//
+//	mutate.New(
+//		clientset.AppsV1().Deployments("ns").Get,
+//		clientset.AppsV1().Deployments("ns").Patch,
+//	)
+func New[T client.Object](get GetFunc[T], patch PatchFunc[T]) *Mutator[T] {
+	return &Mutator[T]{get: get, patch: patch}
+}
+
+// Mutate mutates the resource name with a Mutator using the
+// default values, and returns the resource after the mutation
+// and the number of attempts, e.g.:
+//
+//	mutate.Mutate[*appsv1.Deployment](
+//		ctx,
+//		clientset.AppsV1().Deployments("ns"),
+//		"nginx",
+//		func(dep *appsv1.Deployment) error { ... },
+//	)
+func Mutate[T client.Object](
+	ctx context.Context,
+	c Client[T],
+	name string,
+	fn func(T) error,
+) (T, int, error) {
+	return New(c.Get, c.Patch).Mutate(ctx, name, fn)
+}
+
+// Mutate gets the resource name, calls fn to modify it, and
+// patches the resource with the differences. The patch contains
+// the resource version of the resource read, so it fails with a
+// Conflict error if the resource has been modified since; the
+// resource is then read and modified again, after a backoff,
+// until the patch succeeds or the timeout expires.
+//
+// fn may be called several times, with a new copy of the resource
+// each time. An error returned by fn stops the mutation, and is
+// returned as is. When fn does not modify the resource, the resource
+// is not patched. Mutate returns the resource after the mutation
+// and the number of attempts, i.e. of calls to fn.
+func (o *Mutator[T]) Mutate(ctx context.Context, name string, fn func(T) error) (T, int, error) {
+	var zero T
+	ctx, cancel := context.WithTimeout(ctx, o.timeout())
+	defer cancel()
+
+	backoff := o.initialBackoff()
+	for attempts := 1; ; attempts++ {
+		obj, conflict, err := o.mutateOnce(ctx, name, fn)
+		if err == nil {
+			return obj, attempts, nil
+		}
+		if !conflict {
+			return zero, attempts, err
+		}
+
+		delay := backoff
+		if jitter := o.jitter(); jitter > 0 {
+			delay = wait.Jitter(backoff, jitter)
+		}
+		select {
+		case <-ctx.Done():
+			return zero, attempts, fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+		case <-time.After(delay):
+		}
+		if backoff *= 2; backoff > o.maxBackoff() {
+			backoff = o.maxBackoff()
+		}
+	}
+}
+
+// mutateOnce gets, mutates and patches the resource once.
+// It returns true with the error of the patch if it is a conflict.
+func (o *Mutator[T]) mutateOnce(ctx context.Context, name string, fn func(T) error) (T, bool, error) {
+	var zero T
+	original, err := o.get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return zero, false, err
+	}
+	modified, ok := original.DeepCopyObject().(T)
+	if !ok {
+		return zero, false, fmt.Errorf("copying %T", original)
+	}
+	if err = fn(modified); err != nil {
+		return zero, false, err
+	}
+
+	patchType, data, err := o.patchData(original, modified)
+	if err != nil {
+		return zero, false, err
+	}
+	if data == nil {
+		return original, false, nil
+	}
+	patched, err := o.patch(ctx, name, patchType, data, o.PatchOptions)
+	if err != nil {
+		return zero, apierrors.IsConflict(err), err
+	}
+	return patched, false, nil
+}
+
+// patchData returns the patch from original to modified,
+// including the resource version of original, or nil if
+// modified is not different from original
+func (o *Mutator[T]) patchData(original, modified T) (types.PatchType, []byte, error) {
+	before, err := json.Marshal(original)
+	if err != nil {
+		return "", nil, err
+	}
+	after, err := json.Marshal(modified)
+	if err != nil {
+		return "", nil, err
+	}
+	if bytes.Equal(before, after) {
+		return "", nil, nil
+	}
+
+	var patch client.Patch
+	switch o.PatchType {
+	case "", types.StrategicMergePatchType:
+		patch = client.StrategicMergeFrom(original, client.MergeFromWithOptimisticLock{})
+	case types.MergePatchType:
+		patch = client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+	case types.JSONPatchType:
+		data, err := jsonPatch(before, after, original.GetResourceVersion())
+		return types.JSONPatchType, data, err
+	default:
+		return "", nil, fmt.Errorf("unsupported patch type %q", o.PatchType)
+	}
+	data, err := patch.Data(modified)
+	return patch.Type(), data, err
+}
+
+// jsonPatch returns the JSON patch from before to after, adding an
+// operation to set the resource version, so the patch fails with a
+// Conflict error when the resource has been modified since
+func jsonPatch(before, after []byte, resourceVersion string) ([]byte, error) {
+	ops, err := jsonpatch.CreatePatch(before, after)
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, jsonpatch.NewOperation("add", "/metadata/resourceVersion", resourceVersion))
+	return json.Marshal(ops)
+}
+
+func (o *Mutator[T]) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (o *Mutator[T]) initialBackoff() time.Duration {
+	if o.InitialBackoff > 0 {
+		return o.InitialBackoff
+	}
+	return DefaultInitialBackoff
+}
+
+func (o *Mutator[T]) maxBackoff() time.Duration {
+	if o.MaxBackoff > 0 {
+		return o.MaxBackoff
+	}
+	return DefaultMaxBackoff
+}
+
+func (o *Mutator[T]) jitter() float64 {
+	if o.Jitter == 0 {
+		return DefaultJitter
+	}
+	return o.Jitter
+}
@@ -0,0 +1,172 @@
+package mutate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+	"k8s.io/utils/pointer"
+)
+
+// newClientset returns a fake clientset with a deployment, where
+// the first conflicts patches (all if negative) fail with a
+// Conflict error. The patches are recorded in patches.
+func newClientset(t *testing.T, conflicts int, patches *[]ktesting.PatchAction) *fake.Clientset {
+	t.Helper()
+	clientset := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "ns", ResourceVersion: "1"},
+		Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(1)},
+	})
+	clientset.PrependReactor("patch", "deployments", func(action ktesting.Action) (bool, runtime.Object, error) {
+		*patches = append(*patches, action.(ktesting.PatchAction))
+		if conflicts != 0 {
+			conflicts--
+			return true, nil, apierrors.NewConflict(
+				schema.GroupResource{Group: "apps", Resource: "deployments"},
+				"nginx",
+				errors.New("the object has been modified"),
+			)
+		}
+		return false, nil, nil
+	})
+	return clientset
+}
+
+func scale(replicas int32) func(*appsv1.Deployment) error {
+	return func(dep *appsv1.Deployment) error {
+		dep.Spec.Replicas = pointer.Int32(replicas)
+		return nil
+	}
+}
+
+func TestMutator_Mutate(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType types.PatchType
+		wantPatch string
+	}{
+		{
+			name:      "strategic merge patch",
+			wantPatch: `{"metadata":{"resourceVersion":"1"},"spec":{"replicas":3}}`,
+		},
+		{
+			name:      "merge patch",
+			patchType: types.MergePatchType,
+			wantPatch: `{"metadata":{"resourceVersion":"1"},"spec":{"replicas":3}}`,
+		},
+		{
+			name:      "json patch",
+			patchType: types.JSONPatchType,
+			wantPatch: `[{"op":"replace","path":"/spec/replicas","value":3},{"op":"add","path":"/metadata/resourceVersion","value":"1"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patches []ktesting.PatchAction
+			deployments := newClientset(t, 2, &patches).AppsV1().Deployments("ns")
+			mutator := New(deployments.Get, deployments.Patch)
+			mutator.PatchType = tt.patchType
+			mutator.InitialBackoff = time.Millisecond
+
+			dep, attempts, err := mutator.Mutate(context.Background(), "nginx", scale(3))
+			if err != nil {
+				t.Fatalf("Mutate() error = %v", err)
+			}
+			if attempts != 3 {
+				t.Errorf("attempts = %d, want 3", attempts)
+			}
+			if *dep.Spec.Replicas != 3 {
+				t.Errorf("replicas = %d, want 3", *dep.Spec.Replicas)
+			}
+			if len(patches) != 3 {
+				t.Fatalf("%d patches, want 3", len(patches))
+			}
+			wantType := tt.patchType
+			if wantType == "" {
+				wantType = types.StrategicMergePatchType
+			}
+			if patch := patches[2]; patch.GetPatchType() != wantType || string(patch.GetPatch()) != tt.wantPatch {
+				t.Errorf("patch = %s %s, want %s %s", patch.GetPatchType(), patch.GetPatch(), wantType, tt.wantPatch)
+			}
+		})
+	}
+}
+
+func TestMutator_NoChange(t *testing.T) {
+	var patches []ktesting.PatchAction
+	deployments := newClientset(t, 0, &patches).AppsV1().Deployments("ns")
+	dep, attempts, err := Mutate[*appsv1.Deployment](context.Background(), deployments, "nginx", scale(1))
+	if err != nil {
+		t.Fatalf("Mutate() error = %v", err)
+	}
+	if attempts != 1 || len(patches) != 0 || dep.Name != "nginx" {
+		t.Errorf("Mutate() = %s after %d attempts and %d patches, want no patch", dep.Name, attempts, len(patches))
+	}
+}
+
+func TestMutator_Errors(t *testing.T) {
+	stop := errors.New("stop")
+	tests := []struct {
+		name         string
+		conflicts    int
+		resource     string
+		fn           func(*appsv1.Deployment) error
+		wantErr      func(error) bool
+		wantAttempts int
+	}{
+		{
+			name:         "mutation error",
+			resource:     "nginx",
+			fn:           func(*appsv1.Deployment) error { return stop },
+			wantErr:      func(err error) bool { return err == stop },
+			wantAttempts: 1,
+		},
+		{
+			name:         "not found",
+			resource:     "unknown",
+			fn:           scale(3),
+			wantErr:      apierrors.IsNotFound,
+			wantAttempts: 1,
+		},
+		{
+			name:      "timeout",
+			conflicts: -1,
+			resource:  "nginx",
+			fn:        scale(3),
+			wantErr: func(err error) bool {
+				return apierrors.IsConflict(err) && strings.HasPrefix(err.Error(), "giving up after")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patches []ktesting.PatchAction
+			deployments := newClientset(t, tt.conflicts, &patches).AppsV1().Deployments("ns")
+			mutator := New(deployments.Get, deployments.Patch)
+			mutator.Timeout = 50 * time.Millisecond
+			mutator.InitialBackoff = time.Millisecond
+			mutator.MaxBackoff = 5 * time.Millisecond
+
+			_, attempts, err := mutator.Mutate(context.Background(), tt.resource, tt.fn)
+			if !tt.wantErr(err) {
+				t.Errorf("Mutate() error = %v", err)
+			}
+			if tt.wantAttempts > 0 && attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantAttempts == 0 && attempts < 2 {
+				t.Errorf("attempts = %d, want several attempts", attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package paginate lists resources page by page, using the
+// limit and continue options of the list calls, and streams the
+// items to a callback, so the whole list is never held in memory
+package paginate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultPageSize is the size of the pages when Pager.PageSize is 0
+const DefaultPageSize = 500
+
+// ExpiredPolicy defines what a Pager does when the continue
+// token of a page has expired (a 410 Gone response with the
+// Expired reason), i.e. when the snapshot of the list is
+// older than the history kept by the API Server
+type ExpiredPolicy int
+
+const (
+	// ExpiredFail returns the Expired error
+	ExpiredFail ExpiredPolicy = iota
+	// ExpiredRestart restarts the list from the first page,
+	// with a new snapshot. The items of the previous pages are
+	// sent again to the callback, after a call to OnRestart.
+	ExpiredRestart
+	// ExpiredContinueInconsistent continues the list with the
+	// token returned by the API Server with the Expired error:
+	// the remaining items are listed from the latest version,
+	// and the list may miss or contain changed items.
+	ExpiredContinueInconsistent
+)
+
+// ListFunc is a list call, e.g. clientset.CoreV1().Pods("").List
+type ListFunc[L metav1.ListInterface] func(ctx context.Context, opts metav1.ListOptions) (L, error)
+
+// Pager lists resources page by page
+type Pager[L metav1.ListInterface, T any] struct {
+	list  ListFunc[L]
+	items func(L) []T
+
+	// PageSize is the maximum number of items
+	// per page, DefaultPageSize if 0
+	PageSize int64
+	// ExpiredPolicy defines what to do when a continue
+	// token has expired, ExpiredFail by default
+	ExpiredPolicy ExpiredPolicy
+	// OnRestart, if not nil, is called before restarting the
+	// list with the ExpiredRestart policy, e.g. to forget
+	// the items received before
+	OnRestart func()
+}
+
+// New returns a Pager calling list, and getting
+// the items of the pages with items, e.g.:
+//
+//	paginate.New(
+//		clientset.CoreV1().Pods("").List,
+//		func(l *corev1.PodList) []corev1.Pod { return l.Items },
+//	)
+func New[L metav1.ListInterface, T any](list ListFunc[L], items func(L) []T) *Pager[L, T] {
+	return &Pager[L, T]{list: list, items: items}
+}
+
+// Result describes a complete list
+type Result struct {
+	// ResourceVersion is the resource version of the snapshot
+	// of the list, the version of the last page if Inconsistent
+	ResourceVersion string
+	// Pages and Items are the numbers of pages and items
+	// of the list, including the ones listed before a restart
+	Pages int
+	Items int
+	// Restarts is the number of restarts due to expired continue tokens
+	Restarts int
+	// Inconsistent is true if the list has been continued
+	// after an expired continue token, with the
+	// ExpiredContinueInconsistent policy
+	Inconsistent bool
+}
+
+// Each lists the items matching opts page by page, and
+// calls fn for each item. It stops at the first error
+// returned by fn or by a list call, and returns it.
+//
+// The ResourceVersion and ResourceVersionMatch options apply to
+// the first page, the following pages are part of the same
+// snapshot. With an empty ResourceVersion, the snapshot is the
+// latest version (a consistent read). With ResourceVersionMatch
+// Exact, the snapshot is ResourceVersion, and the list fails
+// when it has expired, whatever the policy. Note that the API
+// Server ignores the limit, and returns all the items in a single
+// page, for ResourceVersion "0" and for ResourceVersionMatch
+// NotOlderThan when it serves the list from its cache.
+func (o *Pager[L, T]) Each(
+	ctx context.Context,
+	opts metav1.ListOptions,
+	fn func(T) error,
+) (Result, error) {
+	if err := validate(opts); err != nil {
+		return Result{}, err
+	}
+	opts.Limit = o.PageSize
+	if opts.Limit == 0 {
+		opts.Limit = DefaultPageSize
+	}
+	first := opts
+
+	var result Result
+	for {
+		page, err := o.list(ctx, opts)
+		if err != nil {
+			if !apierrors.IsResourceExpired(err) || opts.Continue == "" {
+				return result, err
+			}
+			opts, err = o.onExpired(err, first, opts, &result)
+			if err != nil {
+				return result, err
+			}
+			continue
+		}
+
+		result.Pages++
+		if result.ResourceVersion == "" || result.Inconsistent {
+			result.ResourceVersion = page.GetResourceVersion()
+		}
+		for _, item := range o.items(page) {
+			result.Items++
+			if err = fn(item); err != nil {
+				return result, err
+			}
+		}
+
+		next := page.GetContinue()
+		if next == "" {
+			return result, nil
+		}
+		// the resource version is defined by the
+		// continue token for the next pages
+		opts.Continue = next
+		opts.ResourceVersion = ""
+		opts.ResourceVersionMatch = ""
+	}
+}
+
+// All returns all the items matching opts, listed page by page.
+// Prefer Each to process the items without keeping them in memory.
+func (o *Pager[L, T]) All(ctx context.Context, opts metav1.ListOptions) ([]T, Result, error) {
+	var items []T
+	onRestart := o.OnRestart
+	pager := *o
+	pager.OnRestart = func() {
+		items = nil
+		if onRestart != nil {
+			onRestart()
+		}
+	}
+	result, err := pager.Each(ctx, opts, func(item T) error {
+		items = append(items, item)
+		return nil
+	})
+	return items, result, err
+}
+
+// onExpired returns the options to continue the list
+// after err, an Expired error, according to the policy
+func (o *Pager[L, T]) onExpired(
+	err error,
+	first metav1.ListOptions,
+	opts metav1.ListOptions,
+	result *Result,
+) (metav1.ListOptions, error) {
+	switch o.ExpiredPolicy {
+	case ExpiredRestart:
+		if first.ResourceVersionMatch == metav1.ResourceVersionMatchExact {
+			return opts, fmt.Errorf("restarting the list at the exact resource version %s: %w",
+				first.ResourceVersion, err)
+		}
+		result.Restarts++
+		result.ResourceVersion = ""
+		if o.OnRestart != nil {
+			o.OnRestart()
+		}
+		return first, nil
+
+	case ExpiredContinueInconsistent:
+		token := inconsistentContinue(err)
+		if token == "" {
+			return opts, fmt.Errorf("no token to continue the list: %w", err)
+		}
+		result.Inconsistent = true
+		opts.Continue = token
+		return opts, nil
+	}
+	return opts, err
+}
+
+// inconsistentContinue returns the token returned by the API Server
+// with an Expired error, to continue the list from the latest version
+func inconsistentContinue(err error) string {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return ""
+	}
+	return status.Status().ListMeta.Continue
+}
+
+// validate returns an error if the resource version
+// options of opts are not valid for a paginated list
+func validate(opts metav1.ListOptions) error {
+	if opts.Continue != "" {
+		return errors.New("the continue option is managed by the pager")
+	}
+	switch opts.ResourceVersionMatch {
+	case "":
+	case metav1.ResourceVersionMatchExact, metav1.ResourceVersionMatchNotOlderThan:
+		if opts.ResourceVersion == "" {
+			return fmt.Errorf("resourceVersionMatch %s requires a resourceVersion", opts.ResourceVersionMatch)
+		}
+		if opts.ResourceVersionMatch == metav1.ResourceVersionMatchExact && opts.ResourceVersion == "0" {
+			return errors.New("resourceVersionMatch Exact is not allowed with resourceVersion 0")
+		}
+	default:
+		return fmt.Errorf("unknown resourceVersionMatch %q", opts.ResourceVersionMatch)
+	}
+	return nil
+}
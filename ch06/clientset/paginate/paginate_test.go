@@ -0,0 +1,228 @@
+package paginate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeServer serves a list of pods page by page, with continue
+// tokens "<resourceVersion>/<offset>". With expireAfterFirstPage,
+// the version is incremented after the first page, and the tokens
+// of the previous version return an Expired error.
+type fakeServer struct {
+	pods                 int
+	version              int
+	expireAfterFirstPage bool
+	expired              map[int]bool
+	calls                []metav1.ListOptions
+}
+
+func (o *fakeServer) list(_ context.Context, opts metav1.ListOptions) (*corev1.PodList, error) {
+	o.calls = append(o.calls, opts)
+	version, offset := o.version, 0
+	if opts.Continue != "" {
+		if opts.ResourceVersion != "" || opts.ResourceVersionMatch != "" {
+			return nil, apierrors.NewBadRequest("specifying resource version is not allowed when using continue")
+		}
+		if _, err := fmt.Sscanf(opts.Continue, "%d/%d", &version, &offset); err != nil {
+			return nil, apierrors.NewBadRequest("invalid continue token")
+		}
+		if o.expired[version] {
+			statusErr := apierrors.NewResourceExpired("The provided continue parameter is too old")
+			statusErr.ErrStatus.ListMeta.Continue = fmt.Sprintf("%d/%d", o.version, offset)
+			return nil, statusErr
+		}
+	}
+
+	list := &corev1.PodList{}
+	list.ResourceVersion = strconv.Itoa(version)
+	end := offset + int(opts.Limit)
+	if opts.Limit == 0 || end > o.pods {
+		end = o.pods
+	}
+	for i := offset; i < end; i++ {
+		pod := corev1.Pod{}
+		pod.Name = fmt.Sprintf("pod-%d", i)
+		list.Items = append(list.Items, pod)
+	}
+	if end < o.pods {
+		list.Continue = fmt.Sprintf("%d/%d", version, end)
+	}
+	if o.expireAfterFirstPage {
+		o.expireAfterFirstPage = false
+		o.expired = map[int]bool{o.version: true}
+		o.version++
+	}
+	return list, nil
+}
+
+func podItems(l *corev1.PodList) []corev1.Pod { return l.Items }
+
+func TestPager_Each(t *testing.T) {
+	server := &fakeServer{pods: 25, version: 100}
+	pager := New(server.list, podItems)
+	pager.PageSize = 10
+
+	var names []string
+	result, err := pager.Each(context.Background(), metav1.ListOptions{
+		LabelSelector: "app=nginx",
+	}, func(pod corev1.Pod) error {
+		names = append(names, pod.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each() error = %v", err)
+	}
+	want := Result{ResourceVersion: "100", Pages: 3, Items: 25}
+	if result != want {
+		t.Errorf("Each() = %+v, want %+v", result, want)
+	}
+	if len(names) != 25 || names[24] != "pod-24" {
+		t.Errorf("items = %v", names)
+	}
+	for _, call := range server.calls {
+		if call.Limit != 10 || call.LabelSelector != "app=nginx" {
+			t.Errorf("call options = %+v, want limit 10 and the label selector", call)
+		}
+	}
+}
+
+func TestPager_ResourceVersion(t *testing.T) {
+	server := &fakeServer{pods: 5, version: 100}
+	pager := New(server.list, podItems)
+	pager.PageSize = 2
+	_, err := pager.Each(context.Background(), metav1.ListOptions{
+		ResourceVersion:      "90",
+		ResourceVersionMatch: metav1.ResourceVersionMatchExact,
+	}, func(corev1.Pod) error { return nil })
+	if err != nil {
+		t.Fatalf("Each() error = %v", err)
+	}
+	if first := server.calls[0]; first.ResourceVersion != "90" || first.ResourceVersionMatch != metav1.ResourceVersionMatchExact {
+		t.Errorf("first call = %+v, want the resource version options", first)
+	}
+
+	tests := []metav1.ListOptions{
+		{ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+		{ResourceVersion: "0", ResourceVersionMatch: metav1.ResourceVersionMatchExact},
+		{ResourceVersion: "10", ResourceVersionMatch: "Newest"},
+		{Continue: "token"},
+	}
+	for _, opts := range tests {
+		if _, err = pager.Each(context.Background(), opts, func(corev1.Pod) error { return nil }); err == nil {
+			t.Errorf("Each(%+v): expected an error", opts)
+		}
+	}
+}
+
+func TestPager_Expired(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     ExpiredPolicy
+		opts       metav1.ListOptions
+		wantErr    bool
+		wantResult Result
+		wantItems  int
+	}{
+		{
+			name:       "fail",
+			policy:     ExpiredFail,
+			wantErr:    true,
+			wantResult: Result{ResourceVersion: "100", Pages: 1, Items: 10},
+		},
+		{
+			name:       "restart",
+			policy:     ExpiredRestart,
+			wantResult: Result{ResourceVersion: "101", Pages: 3, Items: 30, Restarts: 1},
+			wantItems:  20,
+		},
+		{
+			name:   "restart exact version",
+			policy: ExpiredRestart,
+			opts: metav1.ListOptions{
+				ResourceVersion:      "100",
+				ResourceVersionMatch: metav1.ResourceVersionMatchExact,
+			},
+			wantErr:    true,
+			wantResult: Result{ResourceVersion: "100", Pages: 1, Items: 10},
+		},
+		{
+			name:       "continue inconsistent",
+			policy:     ExpiredContinueInconsistent,
+			wantResult: Result{ResourceVersion: "101", Pages: 2, Items: 20, Inconsistent: true},
+			wantItems:  20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the first page is listed at version 100,
+			// which expires before the second page
+			server := &fakeServer{pods: 20, version: 100, expireAfterFirstPage: true}
+			pager := New(server.list, podItems)
+			pager.PageSize = 10
+			pager.ExpiredPolicy = tt.policy
+			restarts := 0
+			pager.OnRestart = func() { restarts++ }
+
+			items, result, err := pager.All(context.Background(), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("All() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !apierrors.IsResourceExpired(err) {
+				t.Errorf("All() error = %v, want an Expired error", err)
+			}
+			if result != tt.wantResult {
+				t.Errorf("All() = %+v, want %+v", result, tt.wantResult)
+			}
+			if !tt.wantErr && len(items) != tt.wantItems {
+				t.Errorf("All() returned %d items, want %d", len(items), tt.wantItems)
+			}
+			if restarts != result.Restarts {
+				t.Errorf("OnRestart called %d times, want %d", restarts, result.Restarts)
+			}
+		})
+	}
+}
+
+func TestPager_CallbackError(t *testing.T) {
+	server := &fakeServer{pods: 25, version: 100}
+	pager := New(server.list, podItems)
+	pager.PageSize = 10
+	stop := errors.New("stop")
+	result, err := pager.Each(context.Background(), metav1.ListOptions{}, func(pod corev1.Pod) error {
+		if pod.Name == "pod-12" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Each() error = %v, want stop", err)
+	}
+	if result.Pages != 2 || result.Items != 13 || len(server.calls) != 2 {
+		t.Errorf("Each() = %+v after %d calls, want to stop in the second page", result, len(server.calls))
+	}
+}
+
+func TestPager_Clientset(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "ns"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod2", Namespace: "ns"}},
+	)
+	// the fake clientset ignores the limit, and returns all the items
+	items, result, err := New(clientset.CoreV1().Pods("ns").List, podItems).
+		All(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	if len(items) != 2 || result.Pages != 1 {
+		t.Errorf("All() = %d items in %d pages, want 2 items in 1 page", len(items), result.Pages)
+	}
+}
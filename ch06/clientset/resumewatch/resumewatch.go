@@ -0,0 +1,392 @@
+// Package resumewatch watches resources without interruption:
+// it lists the resources, watches them from the resource version
+// of the list, resumes the watch when the server closes it, and
+// lists the resources again when the resource version has expired.
+// The handler receives a deduplicated stream of events. Only the
+// transient errors are retried, e.g. timeouts or unavailable
+// servers, the others (e.g. Forbidden) are returned.
+package resumewatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+)
+
+// Default backoff durations between two failed watch calls
+const (
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 30 * time.Second
+)
+
+// ListFunc is a list call, e.g. clientset.CoreV1().Pods("").List
+type ListFunc[L runtime.Object] func(ctx context.Context, opts metav1.ListOptions) (L, error)
+
+// WatchFunc is a watch call, e.g. clientset.CoreV1().Pods("").Watch
+type WatchFunc func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+
+// Handler handles an event. The watcher does not read
+// the next event until the handler returns, so a slow
+// handler slows down the watch instead of accumulating
+// events in memory. An error returned by the handler
+// stops the watch.
+type Handler func(watch.Event) error
+
+// Watcher watches resources without interruption
+type Watcher struct {
+	list  func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+	watch WatchFunc
+
+	// ListOptions are the options of the list and watch calls,
+	// e.g. label and field selectors. The resource version and
+	// watch related options are managed by the Watcher.
+	ListOptions metav1.ListOptions
+	// WatchTimeout, if not 0, is the duration after which the
+	// server closes the watch, before it is resumed
+	WatchTimeout time.Duration
+	// InitialBackoff and MaxBackoff define the exponential backoff
+	// between failed watch calls, DefaultInitialBackoff and
+	// DefaultMaxBackoff if 0
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// OnRelist, if not nil, is called when the resources
+	// are listed again after an expired resource version
+	OnRelist func()
+
+	// resourceVersion is the version of the last
+	// list, event or bookmark received
+	resourceVersion string
+	// objects are the last known state of the objects, by key,
+	// to deduplicate the events and detect the deletions
+	// when listing the resources again
+	objects map[types.NamespacedName]runtime.Object
+}
+
+// New returns a Watcher using the list and watch calls
+// of a resource, e.g.:
+//
+//	resumewatch.New(
+//		clientset.CoreV1().Pods("").List,
+//		clientset.CoreV1().Pods("").Watch,
+//	)
+func New[L runtime.Object](list ListFunc[L], watch WatchFunc) *Watcher {
+	return &Watcher{
+		list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return list(ctx, opts)
+		},
+		watch: watch,
+	}
+}
+
+// Run lists the resources, sends an Added event for each of
+// them to handler, and then the events of the watch, until ctx
+// is done, handler returns an error or a list or watch call
+// fails with an error which is not transient. Run returns
+// this error, or the error of ctx. The transient errors are
+// logged with the logger of ctx, and retried.
+//
+// When the resources are listed again, the handler receives the
+// differences with the last known state: Added events for the new
+// objects, Modified events for the changed objects, and Deleted
+// events for the objects not present anymore. Events already
+// received, e.g. replayed after resuming the watch, are dropped.
+func (o *Watcher) Run(ctx context.Context, handler Handler) error {
+	o.objects = map[types.NamespacedName]runtime.Object{}
+	if err := o.relist(ctx, handler); err != nil {
+		return unwrapHandlerError(err)
+	}
+
+	backoff := o.initialBackoff()
+	for {
+		progressed, err := o.watchOnce(ctx, handler)
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case isHandlerError(err):
+			return unwrapHandlerError(err)
+		case isExpired(err):
+			if o.OnRelist != nil {
+				o.OnRelist()
+			}
+			if err = o.relist(ctx, handler); err != nil {
+				return unwrapHandlerError(err)
+			}
+			progressed = true
+		case !isTransient(err):
+			return err
+		default:
+			klog.FromContext(ctx).Error(err, "watch failed, retrying", "backoff", backoff)
+		}
+
+		if progressed {
+			backoff = o.initialBackoff()
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if max := o.maxBackoff(); backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// ResourceVersion returns the resource version
+// of the last list, event or bookmark received
+func (o *Watcher) ResourceVersion() string {
+	return o.resourceVersion
+}
+
+// relist lists the resources until the list succeeds or fails
+// with an error which is not transient, and sends the
+// differences with the known state to handler
+func (o *Watcher) relist(ctx context.Context, handler Handler) error {
+	opts := o.ListOptions
+	opts.ResourceVersion = ""
+	opts.ResourceVersionMatch = ""
+	opts.Watch = false
+	opts.AllowWatchBookmarks = false
+
+	var list runtime.Object
+	backoff := o.initialBackoff()
+	for {
+		var err error
+		list, err = o.list(ctx, opts)
+		if err == nil {
+			break
+		}
+		if !isTransient(err) {
+			return err
+		}
+		klog.FromContext(ctx).Error(err, "list failed, retrying", "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > o.maxBackoff() {
+			backoff = o.maxBackoff()
+		}
+	}
+
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+
+	seen := map[types.NamespacedName]bool{}
+	for _, item := range items {
+		key, err := keyOf(item)
+		if err != nil {
+			return err
+		}
+		seen[key] = true
+		if err = o.deliver(handler, watch.Event{Type: watch.Added, Object: item}); err != nil {
+			return err
+		}
+	}
+	for key, obj := range o.objects {
+		if seen[key] {
+			continue
+		}
+		if err = o.deliver(handler, watch.Event{Type: watch.Deleted, Object: obj}); err != nil {
+			return err
+		}
+	}
+	o.resourceVersion = listMeta.GetResourceVersion()
+	return nil
+}
+
+// watchOnce watches the resources from the current resource version,
+// until the watch is closed or fails. It returns true if some events
+// have been received.
+func (o *Watcher) watchOnce(ctx context.Context, handler Handler) (bool, error) {
+	opts := o.ListOptions
+	opts.Watch = true
+	opts.ResourceVersion = o.resourceVersion
+	opts.ResourceVersionMatch = ""
+	opts.AllowWatchBookmarks = true
+	if o.WatchTimeout > 0 {
+		seconds := int64(o.WatchTimeout / time.Second)
+		opts.TimeoutSeconds = &seconds
+	}
+
+	w, err := o.watch(ctx, opts)
+	if err != nil {
+		return false, err
+	}
+	defer w.Stop()
+
+	progressed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return progressed, ctx.Err()
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return progressed, nil
+			}
+			switch event.Type {
+			case watch.Error:
+				return progressed, apierrors.FromObject(event.Object)
+			case watch.Bookmark:
+				if accessor, err := meta.Accessor(event.Object); err == nil {
+					o.resourceVersion = accessor.GetResourceVersion()
+				}
+			default:
+				if err = o.deliver(handler, event); err != nil {
+					return progressed, err
+				}
+				if accessor, err := meta.Accessor(event.Object); err == nil {
+					o.resourceVersion = accessor.GetResourceVersion()
+				}
+			}
+			progressed = true
+		}
+	}
+}
+
+// deliver sends event to handler, if it is not a duplicate, with its
+// type adapted to the known state, and updates the known state
+func (o *Watcher) deliver(handler Handler, event watch.Event) error {
+	key, err := keyOf(event.Object)
+	if err != nil {
+		return err
+	}
+	accessor, err := meta.Accessor(event.Object)
+	if err != nil {
+		return err
+	}
+	known, found := o.objects[key]
+
+	switch event.Type {
+	case watch.Added, watch.Modified:
+		if found {
+			knownAccessor, _ := meta.Accessor(known)
+			if knownAccessor.GetResourceVersion() == accessor.GetResourceVersion() {
+				return nil
+			}
+			if knownAccessor.GetUID() != accessor.GetUID() {
+				// the object has been deleted and created
+				// again while the watch was interrupted
+				if err = callHandler(handler, watch.Event{Type: watch.Deleted, Object: known}); err != nil {
+					return err
+				}
+				event.Type = watch.Added
+			} else {
+				event.Type = watch.Modified
+			}
+		} else {
+			event.Type = watch.Added
+		}
+		o.objects[key] = event.Object
+	case watch.Deleted:
+		if !found {
+			return nil
+		}
+		delete(o.objects, key)
+	}
+	return callHandler(handler, event)
+}
+
+func (o *Watcher) initialBackoff() time.Duration {
+	if o.InitialBackoff > 0 {
+		return o.InitialBackoff
+	}
+	return DefaultInitialBackoff
+}
+
+func (o *Watcher) maxBackoff() time.Duration {
+	if o.MaxBackoff > 0 {
+		return o.MaxBackoff
+	}
+	return DefaultMaxBackoff
+}
+
+// handlerError wraps the errors returned by the handler,
+// to distinguish them from the errors of the watch
+type handlerError struct {
+	err error
+}
+
+func (o handlerError) Error() string {
+	return o.err.Error()
+}
+
+func callHandler(handler Handler, event watch.Event) error {
+	if err := handler(event); err != nil {
+		return handlerError{err: err}
+	}
+	return nil
+}
+
+func isHandlerError(err error) bool {
+	_, ok := err.(handlerError)
+	return ok
+}
+
+// unwrapHandlerError returns the error returned by the
+// handler if err wraps it, err otherwise
+func unwrapHandlerError(err error) error {
+	if handlerErr, ok := err.(handlerError); ok {
+		return handlerErr.err
+	}
+	return err
+}
+
+// isExpired returns true if err indicates that the resource
+// version of the watch is too old to be resumed
+func isExpired(err error) bool {
+	if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+		return true
+	}
+	status, ok := err.(apierrors.APIStatus)
+	return ok && status.Status().Code == http.StatusGone
+}
+
+// isTransient returns true if err may not happen again when
+// retrying the call: a timeout, a 429 Too Many Requests, a 5xx
+// server error, a 410 Expired, or a network error
+func isTransient(err error) bool {
+	if apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) || isExpired(err) {
+		return true
+	}
+	if status, ok := err.(apierrors.APIStatus); ok {
+		return status.Status().Code >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || utilnet.IsConnectionRefused(err) ||
+		utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err)
+}
+
+func keyOf(obj runtime.Object) (types.NamespacedName, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return types.NamespacedName{}, fmt.Errorf("getting metadata: %w", err)
+	}
+	return types.NamespacedName{
+		Namespace: accessor.GetNamespace(),
+		Name:      accessor.GetName(),
+	}, nil
+}
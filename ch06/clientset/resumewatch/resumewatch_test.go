@@ -0,0 +1,241 @@
+package resumewatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func pod(name, uid, rv string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "ns",
+		UID:             types.UID(uid),
+		ResourceVersion: rv,
+	}}
+}
+
+func podList(rv string, pods ...*corev1.Pod) *corev1.PodList {
+	list := &corev1.PodList{}
+	list.ResourceVersion = rv
+	for _, p := range pods {
+		list.Items = append(list.Items, *p)
+	}
+	return list
+}
+
+// fakeWatch returns a closed watcher sending events
+func fakeWatch(events ...watch.Event) watch.Interface {
+	w := watch.NewFakeWithChanSize(len(events), false)
+	for _, event := range events {
+		w.Action(event.Type, event.Object)
+	}
+	w.Stop()
+	return w
+}
+
+// fakeServer answers the list and watch calls with the lists
+// and watchers prepared, then with err, or a ServiceUnavailable
+// error if nil, and records the options of the calls
+type fakeServer struct {
+	lists     []*corev1.PodList
+	watches   []watch.Interface
+	err       error
+	listOpts  []metav1.ListOptions
+	watchOpts []metav1.ListOptions
+}
+
+func (o *fakeServer) error() error {
+	if o.err != nil {
+		return o.err
+	}
+	return apierrors.NewServiceUnavailable("no more calls")
+}
+
+func (o *fakeServer) list(_ context.Context, opts metav1.ListOptions) (*corev1.PodList, error) {
+	o.listOpts = append(o.listOpts, opts)
+	if len(o.lists) == 0 {
+		return nil, o.error()
+	}
+	list := o.lists[0]
+	o.lists = o.lists[1:]
+	return list, nil
+}
+
+func (o *fakeServer) watch(_ context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	o.watchOpts = append(o.watchOpts, opts)
+	if len(o.watches) == 0 {
+		return nil, o.error()
+	}
+	w := o.watches[0]
+	o.watches = o.watches[1:]
+	return w, nil
+}
+
+func TestWatcher_Run(t *testing.T) {
+	bookmark := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "12"}}
+	expired := apierrors.NewResourceExpired("too old resource version: 12 (13)")
+	server := &fakeServer{
+		lists: []*corev1.PodList{
+			podList("10", pod("a", "uid-a", "1"), pod("b", "uid-b", "2")),
+			// after the expired watch, b is deleted, c is created
+			// and d is deleted and created again
+			podList("14", pod("a", "uid-a", "11"), pod("c", "uid-c", "13"), pod("d", "uid-d2", "13")),
+		},
+		watches: []watch.Interface{
+			fakeWatch(
+				watch.Event{Type: watch.Modified, Object: pod("a", "uid-a", "11")},
+				watch.Event{Type: watch.Added, Object: pod("b", "uid-b", "2")},
+				watch.Event{Type: watch.Added, Object: pod("d", "uid-d1", "5")},
+				watch.Event{Type: watch.Bookmark, Object: bookmark},
+			),
+			fakeWatch(
+				watch.Event{Type: watch.Error, Object: &expired.ErrStatus},
+			),
+			fakeWatch(
+				watch.Event{Type: watch.Deleted, Object: pod("x", "uid-x", "15")},
+				watch.Event{Type: watch.Deleted, Object: pod("c", "uid-c", "16")},
+			),
+		},
+	}
+
+	watcher := New(server.list, server.watch)
+	watcher.ListOptions = metav1.ListOptions{LabelSelector: "app=nginx"}
+	watcher.InitialBackoff = time.Millisecond
+	relists := 0
+	watcher.OnRelist = func() { relists++ }
+
+	var got []string
+	stop := errors.New("stop")
+	err := watcher.Run(context.Background(), func(event watch.Event) error {
+		p := event.Object.(*corev1.Pod)
+		got = append(got, fmt.Sprintf("%s %s %s", event.Type, p.Name, p.UID))
+		if event.Type == watch.Deleted && p.Name == "c" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("Run() error = %v, want the error of the handler", err)
+	}
+
+	want := []string{
+		"ADDED a uid-a",
+		"ADDED b uid-b",
+		"MODIFIED a uid-a",
+		"ADDED d uid-d1",
+		// relist
+		"ADDED c uid-c",
+		"DELETED d uid-d1",
+		"ADDED d uid-d2",
+		"DELETED b uid-b",
+		// watch
+		"DELETED c uid-c",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events:\n%v\nwant:\n%v", got, want)
+	}
+	if relists != 1 {
+		t.Errorf("relists = %d, want 1", relists)
+	}
+
+	wantVersions := []string{"10", "12", "14"}
+	if len(server.watchOpts) != len(wantVersions) {
+		t.Fatalf("%d watch calls, want %d", len(server.watchOpts), len(wantVersions))
+	}
+	for i, opts := range server.watchOpts {
+		if opts.ResourceVersion != wantVersions[i] || !opts.AllowWatchBookmarks ||
+			!opts.Watch || opts.LabelSelector != "app=nginx" {
+			t.Errorf("watch call %d options = %+v, want resource version %s", i, opts, wantVersions[i])
+		}
+	}
+	for i, opts := range server.listOpts {
+		if opts.ResourceVersion != "" || opts.LabelSelector != "app=nginx" {
+			t.Errorf("list call %d options = %+v", i, opts)
+		}
+	}
+}
+
+func TestWatcher_RetriesFailedWatches(t *testing.T) {
+	server := &fakeServer{
+		lists: []*corev1.PodList{podList("10")},
+	}
+	watcher := New(server.list, server.watch)
+	watcher.InitialBackoff = time.Millisecond
+	watcher.MaxBackoff = 4 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := watcher.Run(ctx, func(watch.Event) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want the error of the context", err)
+	}
+	if len(server.watchOpts) < 3 {
+		t.Errorf("%d watch calls, want the failed watch to be retried", len(server.watchOpts))
+	}
+	for _, opts := range server.watchOpts {
+		if opts.ResourceVersion != "10" {
+			t.Errorf("watch resource version = %q, want 10", opts.ResourceVersion)
+		}
+	}
+}
+
+func TestWatcher_ReturnsPermanentErrors(t *testing.T) {
+	forbidden := apierrors.NewForbidden(corev1.Resource("pods"), "", errors.New("no RBAC"))
+	tests := []struct {
+		name      string
+		lists     []*corev1.PodList
+		wantLists int
+	}{
+		{name: "list", wantLists: 1},
+		{name: "watch", lists: []*corev1.PodList{podList("10")}, wantLists: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeServer{lists: tt.lists, err: forbidden}
+			watcher := New(server.list, server.watch)
+			watcher.InitialBackoff = time.Millisecond
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err := watcher.Run(ctx, func(watch.Event) error { return nil })
+			if !apierrors.IsForbidden(err) {
+				t.Errorf("Run() error = %v, want Forbidden", err)
+			}
+			if len(server.listOpts) != tt.wantLists || len(server.watchOpts) > 1 {
+				t.Errorf("%d list and %d watch calls, want no retry", len(server.listOpts), len(server.watchOpts))
+			}
+		})
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: apierrors.NewTimeoutError("timeout", 1), want: true},
+		{err: apierrors.NewTooManyRequests("slow down", 1), want: true},
+		{err: apierrors.NewInternalError(errors.New("etcd")), want: true},
+		{err: apierrors.NewResourceExpired("too old"), want: true},
+		{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{err: apierrors.NewUnauthorized("no token")},
+		{err: apierrors.NewForbidden(corev1.Resource("pods"), "", errors.New("no RBAC"))},
+		{err: apierrors.NewBadRequest("invalid selector")},
+		{err: errors.New("decoding error")},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package selector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// commonFields are the fields supported in the
+// field selectors of all the resources
+var commonFields = []string{"metadata.name", "metadata.namespace"}
+
+// supportedFields are the fields supported in the field selectors
+// of the built-in resources, in addition to the common fields,
+// by resource in the form resource[.group]
+var supportedFields = map[string][]string{
+	"pods": {
+		"spec.nodeName",
+		"spec.restartPolicy",
+		"spec.schedulerName",
+		"spec.serviceAccountName",
+		"spec.hostNetwork",
+		"status.phase",
+		"status.podIP",
+		"status.nominatedNodeName",
+	},
+	"nodes":      {"spec.unschedulable"},
+	"namespaces": {"status.phase"},
+	"secrets":    {"type"},
+	"events": {
+		"involvedObject.kind",
+		"involvedObject.namespace",
+		"involvedObject.name",
+		"involvedObject.uid",
+		"involvedObject.apiVersion",
+		"involvedObject.resourceVersion",
+		"involvedObject.fieldPath",
+		"reason",
+		"reportingComponent",
+		"source",
+		"type",
+	},
+	"replicationcontrollers": {"status.replicas"},
+	"replicasets.apps":       {"status.replicas"},
+	"jobs.batch":             {"status.successful"},
+	"certificatesigningrequests.certificates.k8s.io": {"spec.signerName"},
+}
+
+// fieldPaths are the paths in the objects of the
+// selectable fields not named after their path
+var fieldPaths = map[string]string{
+	"source": "source.component",
+}
+
+// fieldDefaults are the values used by the API Server
+// for the selectable fields omitted when not set
+var fieldDefaults = map[string]string{
+	"spec.hostNetwork":   "false",
+	"spec.unschedulable": "false",
+	"status.replicas":    "0",
+	"status.successful":  "0",
+}
+
+// SupportedFields returns the fields supported in the field
+// selectors of resource, in the form resource[.group] (e.g. pods
+// or replicasets.apps). Only the metadata.name and metadata.namespace
+// fields are supported for the custom resources.
+func SupportedFields(resource string) []string {
+	result := append([]string{}, commonFields...)
+	result = append(result, supportedFields[resource]...)
+	sort.Strings(result)
+	return result
+}
+
+// validateField returns an error if field is not
+// supported in the field selectors of resource
+func validateField(resource, field string) error {
+	for _, supported := range SupportedFields(resource) {
+		if field == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("field %q is not supported for %s, supported fields: %s",
+		field, resource, strings.Join(SupportedFields(resource), ", "))
+}
+
+// fieldValue returns the value of field in obj, as used
+// by the API Server: its default value, or an empty
+// string, if the field is not set
+func fieldValue(obj map[string]interface{}, field string) string {
+	path := field
+	if p, found := fieldPaths[field]; found {
+		path = p
+	}
+	value, found, err := unstructured.NestedFieldNoCopy(obj, strings.Split(path, ".")...)
+	if !found || err != nil || value == nil {
+		return fieldDefaults[field]
+	}
+	return fmt.Sprint(value)
+}
+
+// toUnstructured returns the content of obj as a map
+func toUnstructured(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return u.UnstructuredContent(), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
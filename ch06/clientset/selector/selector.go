@@ -0,0 +1,237 @@
+// Package selector builds label and field selectors with a fluent
+// API, validates the fields against the ones supported by the
+// resources, converts label selectors to and from metav1.LabelSelector,
+// and matches selectors against objects locally
+package selector
+
+import (
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Builder builds a Selector. The errors of the methods
+// are returned by Build.
+type Builder struct {
+	resource     string
+	requirements []labels.Requirement
+	fields       []fields.Selector
+	errs         []error
+}
+
+// New returns a Builder for a selector matching all the objects
+func New() *Builder {
+	return &Builder{}
+}
+
+// ForResource makes Build validate the fields of the field
+// selector against the fields supported by resource, in the
+// form resource[.group], e.g. pods or replicasets.apps
+func (o *Builder) ForResource(resource string) *Builder {
+	o.resource = resource
+	return o
+}
+
+// Equals requires the label key to be value
+func (o *Builder) Equals(key, value string) *Builder {
+	return o.label(key, selection.Equals, value)
+}
+
+// NotEquals requires the label key to be absent or not be value
+func (o *Builder) NotEquals(key, value string) *Builder {
+	return o.label(key, selection.NotEquals, value)
+}
+
+// In requires the label key to be one of values
+func (o *Builder) In(key string, values ...string) *Builder {
+	return o.label(key, selection.In, values...)
+}
+
+// NotIn requires the label key to be absent or not be any of values
+func (o *Builder) NotIn(key string, values ...string) *Builder {
+	return o.label(key, selection.NotIn, values...)
+}
+
+// Exists requires the label key to be present
+func (o *Builder) Exists(key string) *Builder {
+	return o.label(key, selection.Exists)
+}
+
+// DoesNotExist requires the label key to be absent
+func (o *Builder) DoesNotExist(key string) *Builder {
+	return o.label(key, selection.DoesNotExist)
+}
+
+// Gt requires the label key to be an integer greater than value
+func (o *Builder) Gt(key string, value int64) *Builder {
+	return o.label(key, selection.GreaterThan, strconv.FormatInt(value, 10))
+}
+
+// Lt requires the label key to be an integer less than value
+func (o *Builder) Lt(key string, value int64) *Builder {
+	return o.label(key, selection.LessThan, strconv.FormatInt(value, 10))
+}
+
+// MatchLabelSelector adds the requirements of ls
+func (o *Builder) MatchLabelSelector(ls *metav1.LabelSelector) *Builder {
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil {
+		o.errs = append(o.errs, err)
+		return o
+	}
+	requirements, _ := selector.Requirements()
+	o.requirements = append(o.requirements, requirements...)
+	return o
+}
+
+// FieldEquals requires the field to be value
+func (o *Builder) FieldEquals(field, value string) *Builder {
+	o.fields = append(o.fields, fields.OneTermEqualSelector(field, value))
+	return o
+}
+
+// FieldNotEquals requires the field not to be value
+func (o *Builder) FieldNotEquals(field, value string) *Builder {
+	o.fields = append(o.fields, fields.OneTermNotEqualSelector(field, value))
+	return o
+}
+
+func (o *Builder) label(key string, op selection.Operator, values ...string) *Builder {
+	requirement, err := labels.NewRequirement(key, op, values)
+	if err != nil {
+		o.errs = append(o.errs, err)
+		return o
+	}
+	o.requirements = append(o.requirements, *requirement)
+	return o
+}
+
+// Build returns the selector, or the errors
+// of the requirements added to the builder
+func (o *Builder) Build() (*Selector, error) {
+	errs := append([]error{}, o.errs...)
+	fieldSelector := fields.AndSelectors(o.fields...)
+	if o.resource != "" {
+		for _, requirement := range fieldSelector.Requirements() {
+			if err := validateField(o.resource, requirement.Field); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	if len(o.fields) == 0 {
+		fieldSelector = fields.Everything()
+	}
+	return &Selector{
+		Labels: labels.NewSelector().Add(o.requirements...),
+		Fields: fieldSelector,
+	}, nil
+}
+
+// Selector is a label selector and a field selector
+type Selector struct {
+	Labels labels.Selector
+	Fields fields.Selector
+}
+
+// Parse returns the selector for the labelSelector and fieldSelector
+// strings, with the fields validated for resource if not empty
+func Parse(labelSelector, fieldSelector, resource string) (*Selector, error) {
+	ls, err := labels.Parse(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	fs, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, err
+	}
+	if resource != "" {
+		var errs []error
+		for _, requirement := range fs.Requirements() {
+			if err = validateField(resource, requirement.Field); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			return nil, utilerrors.NewAggregate(errs)
+		}
+	}
+	return &Selector{Labels: ls, Fields: fs}, nil
+}
+
+// ListOptions returns list options with the label and field selectors
+func (o *Selector) ListOptions() metav1.ListOptions {
+	return metav1.ListOptions{
+		LabelSelector: o.Labels.String(),
+		FieldSelector: o.Fields.String(),
+	}
+}
+
+// LabelSelector returns the label selector as a metav1.LabelSelector,
+// or an error if it contains Gt or Lt requirements
+func (o *Selector) LabelSelector() (*metav1.LabelSelector, error) {
+	result := &metav1.LabelSelector{}
+	requirements, _ := o.Labels.Requirements()
+	for _, requirement := range requirements {
+		values := requirement.Values().List()
+		expression := metav1.LabelSelectorRequirement{
+			Key:    requirement.Key(),
+			Values: values,
+		}
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals:
+			if result.MatchLabels == nil {
+				result.MatchLabels = map[string]string{}
+			}
+			result.MatchLabels[requirement.Key()] = values[0]
+			continue
+		case selection.In:
+			expression.Operator = metav1.LabelSelectorOpIn
+		case selection.NotEquals, selection.NotIn:
+			expression.Operator = metav1.LabelSelectorOpNotIn
+		case selection.Exists:
+			expression.Operator = metav1.LabelSelectorOpExists
+		case selection.DoesNotExist:
+			expression.Operator = metav1.LabelSelectorOpDoesNotExist
+		default:
+			return nil, fmt.Errorf("operator %q of label %q cannot be expressed in a LabelSelector",
+				requirement.Operator(), requirement.Key())
+		}
+		result.MatchExpressions = append(result.MatchExpressions, expression)
+	}
+	return result, nil
+}
+
+// Matches returns true if the labels and fields of obj match
+// the selector, as the API Server would match them
+func (o *Selector) Matches(obj runtime.Object) (bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	if !o.Labels.Matches(labels.Set(accessor.GetLabels())) {
+		return false, nil
+	}
+	if o.Fields.Empty() {
+		return true, nil
+	}
+
+	content, err := toUnstructured(obj)
+	if err != nil {
+		return false, err
+	}
+	set := fields.Set{}
+	for _, requirement := range o.Fields.Requirements() {
+		set[requirement.Field] = fieldValue(content, requirement.Field)
+	}
+	return o.Fields.Matches(set), nil
+}
@@ -0,0 +1,200 @@
+package selector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name        string
+		builder     *Builder
+		wantLabels  string
+		wantFields  string
+		wantErrPart string
+	}{
+		{
+			name:       "empty",
+			builder:    New(),
+			wantLabels: "",
+			wantFields: "",
+		},
+		{
+			name: "all label operators",
+			builder: New().
+				Equals("app", "nginx").
+				NotEquals("tier", "db").
+				In("env", "prod", "staging").
+				NotIn("zone", "b").
+				Exists("team").
+				DoesNotExist("legacy").
+				Gt("priority", 5).
+				Lt("weight", 10),
+			wantLabels: "app=nginx,env in (prod,staging),!legacy,priority>5,team,tier!=db,weight<10,zone notin (b)",
+		},
+		{
+			name: "pod fields",
+			builder: New().ForResource("pods").
+				FieldEquals("spec.nodeName", "node1").
+				FieldNotEquals("status.phase", "Running"),
+			wantFields: "spec.nodeName=node1,status.phase!=Running",
+		},
+		{
+			name: "unsupported field",
+			builder: New().ForResource("deployments.apps").
+				FieldEquals("metadata.name", "nginx").
+				FieldEquals("spec.replicas", "3"),
+			wantErrPart: `field "spec.replicas" is not supported for deployments.apps`,
+		},
+		{
+			name:        "invalid label value",
+			builder:     New().Equals("app", "not a valid value"),
+			wantErrPart: "not a valid value",
+		},
+		{
+			name:        "In without values",
+			builder:     New().In("env"),
+			wantErrPart: "for 'in', 'notin' operators, values set can't be empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := tt.builder.Build()
+			if tt.wantErrPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrPart) {
+					t.Fatalf("Build() error = %v, want %q", err, tt.wantErrPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			options := selector.ListOptions()
+			if options.LabelSelector != tt.wantLabels {
+				t.Errorf("label selector = %q, want %q", options.LabelSelector, tt.wantLabels)
+			}
+			if options.FieldSelector != tt.wantFields {
+				t.Errorf("field selector = %q, want %q", options.FieldSelector, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	selector, err := Parse("app=nginx,env in (prod)", "spec.nodeName=node1", "pods")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := selector.ListOptions().LabelSelector; got != "app=nginx,env in (prod)" {
+		t.Errorf("label selector = %q", got)
+	}
+	if _, err = Parse("", "spec.nodeName=node1", "services"); err == nil {
+		t.Error("Parse() with a field unsupported for services: expected an error")
+	}
+}
+
+func TestSelector_LabelSelector(t *testing.T) {
+	selector, err := New().
+		Equals("app", "nginx").
+		NotEquals("tier", "db").
+		In("env", "staging", "prod").
+		Exists("team").
+		DoesNotExist("legacy").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls, err := selector.LabelSelector()
+	if err != nil {
+		t.Fatalf("LabelSelector() error = %v", err)
+	}
+	want := &metav1.LabelSelector{
+		MatchLabels: map[string]string{"app": "nginx"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod", "staging"}},
+			{Key: "legacy", Operator: metav1.LabelSelectorOpDoesNotExist, Values: []string{}},
+			{Key: "team", Operator: metav1.LabelSelectorOpExists, Values: []string{}},
+			{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"db"}},
+		},
+	}
+	if !reflect.DeepEqual(ls, want) {
+		t.Errorf("LabelSelector() = %+v, want %+v", ls, want)
+	}
+
+	// round trip
+	back, err := New().MatchLabelSelector(ls).Build()
+	if err != nil {
+		t.Fatalf("Build() from LabelSelector error = %v", err)
+	}
+	if back.Labels.String() != "app=nginx,env in (prod,staging),!legacy,team,tier notin (db)" {
+		t.Errorf("round trip = %q", back.Labels.String())
+	}
+
+	selector, err = New().Gt("priority", 5).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = selector.LabelSelector(); err == nil {
+		t.Error("LabelSelector() with Gt: expected an error")
+	}
+}
+
+func TestSelector_Matches(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "nginx",
+			Namespace: "project1",
+			Labels:    map[string]string{"app": "nginx", "priority": "7"},
+		},
+		Spec:   corev1.PodSpec{NodeName: "node1"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	unscheduled := pod.DeepCopy()
+	unscheduled.Spec.NodeName = ""
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "rs"}}
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "mygroup.example.com/v1alpha1",
+		"kind":       "MyResource",
+		"metadata": map[string]interface{}{
+			"name":   "myres",
+			"labels": map[string]interface{}{"app": "nginx"},
+		},
+	}}
+
+	tests := []struct {
+		name    string
+		builder *Builder
+		obj     runtime.Object
+		want    bool
+	}{
+		{name: "labels", builder: New().Equals("app", "nginx").Gt("priority", 5), obj: pod, want: true},
+		{name: "labels not matching", builder: New().Lt("priority", 5), obj: pod, want: false},
+		{name: "fields", builder: New().FieldEquals("spec.nodeName", "node1").FieldEquals("status.phase", "Running"), obj: pod, want: true},
+		{name: "fields not matching", builder: New().FieldNotEquals("metadata.namespace", "project1"), obj: pod, want: false},
+		{name: "unset field", builder: New().FieldEquals("spec.nodeName", ""), obj: unscheduled, want: true},
+		{name: "default value", builder: New().FieldEquals("status.replicas", "0"), obj: replicaSet, want: true},
+		{name: "unstructured", builder: New().Exists("app").FieldEquals("metadata.name", "myres"), obj: u, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := tt.builder.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := selector.Matches(tt.obj)
+			if err != nil {
+				t.Fatalf("Matches() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
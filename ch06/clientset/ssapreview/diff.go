@@ -0,0 +1,147 @@
+package ssapreview
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ignoredMetadata are the metadata fields changed by
+// the API Server at each write, excluded from the diff
+var ignoredMetadata = []string{
+	"managedFields",
+	"resourceVersion",
+	"generation",
+	"creationTimestamp",
+	"uid",
+}
+
+// Change is the change of a field
+type Change struct {
+	// Path is the path of the field, e.g. .spec.replicas
+	Path string
+	// Old and New are the values of the field before and after
+	// the change, nil when the field is added or removed
+	Old interface{}
+	New interface{}
+}
+
+func (o Change) String() string {
+	switch {
+	case o.Old == nil:
+		return fmt.Sprintf("+ %s: %s", o.Path, format(o.New))
+	case o.New == nil:
+		return fmt.Sprintf("- %s: %s", o.Path, format(o.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", o.Path, format(o.Old), format(o.New))
+}
+
+// Diff returns the changes of the fields from live to applied,
+// sorted by path. The metadata fields changed by the API Server
+// at each write, e.g. the resource version, are ignored. A nil
+// live means the object does not exist.
+func Diff(live, applied runtime.Object) ([]Change, error) {
+	before, err := toMap(live)
+	if err != nil {
+		return nil, err
+	}
+	after, err := toMap(applied)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	diffValues("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+func toMap(obj runtime.Object) (map[string]interface{}, error) {
+	if isNil(obj) {
+		return map[string]interface{}{}, nil
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	if metadata, ok := u["metadata"].(map[string]interface{}); ok {
+		for _, field := range ignoredMetadata {
+			delete(metadata, field)
+		}
+	}
+	return u, nil
+}
+
+// diffValues appends the changes from before to after, at path
+func diffValues(path string, before, after interface{}, changes *[]Change) {
+	// the fields of an added or removed object are compared
+	// one by one with the fields of an empty object
+	if before == nil {
+		before = emptyLike(after)
+	}
+	if after == nil {
+		after = emptyLike(before)
+	}
+
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		for key, value := range beforeMap {
+			diffValues(path+"."+key, value, afterMap[key], changes)
+		}
+		for key, value := range afterMap {
+			if _, found := beforeMap[key]; !found {
+				diffValues(path+"."+key, nil, value, changes)
+			}
+		}
+		return
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList {
+		for i := 0; i < len(beforeList) || i < len(afterList); i++ {
+			var beforeItem, afterItem interface{}
+			if i < len(beforeList) {
+				beforeItem = beforeList[i]
+			}
+			if i < len(afterList) {
+				afterItem = afterList[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), beforeItem, afterItem, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, Change{Path: path, Old: before, New: after})
+	}
+}
+
+// isNil returns true if obj is nil or a nil pointer
+func isNil(obj interface{}) bool {
+	v := reflect.ValueOf(obj)
+	return !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+func emptyLike(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}:
+		return map[string]interface{}{}
+	case []interface{}:
+		return []interface{}{}
+	}
+	return nil
+}
+
+func format(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
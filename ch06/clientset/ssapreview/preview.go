@@ -0,0 +1,217 @@
+// Package ssapreview previews the effects of a server-side apply
+// before applying it: the changes of the fields, computed from a
+// dry-run apply, and the fields whose ownership would be taken from
+// other field managers by forcing the apply
+package ssapreview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrAborted is returned by Apply when the preview is not confirmed
+var ErrAborted = errors.New("apply aborted")
+
+// GetFunc is a get call, e.g. clientset.AppsV1().Deployments("ns").Get
+type GetFunc[T client.Object] func(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+
+// PatchFunc is a patch call, e.g. clientset.AppsV1().Deployments("ns").Patch
+type PatchFunc[T client.Object] func(
+	ctx context.Context,
+	name string,
+	pt types.PatchType,
+	data []byte,
+	opts metav1.PatchOptions,
+	subresources ...string,
+) (T, error)
+
+// Conflict is a field owned by another field manager,
+// whose ownership would be taken by a forced apply
+type Conflict struct {
+	// Manager is the field manager owning the field,
+	// e.g. "kubectl" or "kube-controller-manager"
+	Manager string
+	// Field is the path of the field, e.g. .spec.replicas
+	Field string
+}
+
+// Preview describes the effects of an apply
+type Preview struct {
+	// Created is true if the object does not exist yet
+	Created bool
+	// Changes are the changes of the fields of the object
+	Changes []Change
+	// Conflicts are the fields owned by other managers
+	Conflicts []Conflict
+}
+
+// Managers returns the field managers losing
+// the ownership of fields, sorted by name
+func (o *Preview) Managers() []string {
+	seen := map[string]bool{}
+	var managers []string
+	for _, conflict := range o.Conflicts {
+		if !seen[conflict.Manager] {
+			seen[conflict.Manager] = true
+			managers = append(managers, conflict.Manager)
+		}
+	}
+	sort.Strings(managers)
+	return managers
+}
+
+// Print writes the changes, and the
+// conflicts if any, in a human-readable form
+func (o *Preview) Print(w io.Writer) error {
+	if o.Created {
+		fmt.Fprintln(w, "object created")
+	}
+	for _, change := range o.Changes {
+		fmt.Fprintln(w, change)
+	}
+	if len(o.Changes) == 0 {
+		fmt.Fprintln(w, "no changes")
+	}
+	if len(o.Conflicts) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tOWNERSHIP LOST BY")
+	for _, conflict := range o.Conflicts {
+		fmt.Fprintf(tw, "%s\t%s\n", conflict.Field, conflict.Manager)
+	}
+	return tw.Flush()
+}
+
+// Conflicts returns the conflicts described by err, a Conflict
+// error returned by a server-side apply without force, or nil
+func Conflicts(err error) []Conflict {
+	var status apierrors.APIStatus
+	if !apierrors.IsConflict(err) || !errors.As(err, &status) {
+		return nil
+	}
+	details := status.Status().Details
+	if details == nil {
+		return nil
+	}
+	var conflicts []Conflict
+	for _, cause := range details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		// the message is: conflict with "manager" [using apiVersion]
+		var manager string
+		if _, err := fmt.Sscanf(cause.Message, "conflict with %q", &manager); err != nil {
+			manager = cause.Message
+		}
+		conflicts = append(conflicts, Conflict{Manager: manager, Field: cause.Field})
+	}
+	return conflicts
+}
+
+// Applier applies objects with server-side apply, after a preview
+type Applier[T client.Object] struct {
+	get   GetFunc[T]
+	patch PatchFunc[T]
+
+	// FieldManager is the field manager of the applies
+	FieldManager string
+	// Confirm, if not nil, is called with the preview before
+	// the apply, which is aborted if it returns false. The apply
+	// is forced only if Confirm approves the conflicts of the
+	// preview.
+	Confirm func(*Preview) bool
+}
+
+// New returns an Applier using the get and patch calls
+// of a resource, and the field manager fieldManager, e.g.:
+//
+//	ssapreview.New(
+//		clientset.AppsV1().Deployments("ns").Get,
+//		clientset.AppsV1().Deployments("ns").Patch,
+//		"my-program",
+//	)
+func New[T client.Object](get GetFunc[T], patch PatchFunc[T], fieldManager string) *Applier[T] {
+	return &Applier[T]{get: get, patch: patch, FieldManager: fieldManager}
+}
+
+// Preview returns the effects of applying obj, using dry-run
+// applies. The apiVersion and kind of obj must be set.
+func (o *Applier[T]) Preview(ctx context.Context, obj T) (*Preview, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	preview := &Preview{}
+	live, err := o.get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		// the typed clients return an empty object with the error
+		var zero T
+		live = zero
+		preview.Created = true
+	} else if err != nil {
+		return nil, err
+	}
+
+	// the apply without force fails with the conflicts, if any
+	opts := metav1.PatchOptions{
+		FieldManager: o.FieldManager,
+		DryRun:       []string{metav1.DryRunAll},
+	}
+	applied, err := o.patch(ctx, obj.GetName(), types.ApplyPatchType, data, opts)
+	if apierrors.IsConflict(err) {
+		preview.Conflicts = Conflicts(err)
+		force := true
+		opts.Force = &force
+		applied, err = o.patch(ctx, obj.GetName(), types.ApplyPatchType, data, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Changes, err = Diff(live, applied)
+	return preview, err
+}
+
+// Apply previews the apply of obj, calls Confirm with the preview
+// if not nil, and applies obj. The apply is forced only when the
+// preview has conflicts approved by Confirm: without Confirm, or
+// for a conflict appearing after the preview, Apply returns the
+// Conflict error of the server. It returns the applied object
+// and the preview.
+func (o *Applier[T]) Apply(ctx context.Context, obj T) (T, *Preview, error) {
+	var zero T
+	preview, err := o.Preview(ctx, obj)
+	if err != nil {
+		return zero, nil, err
+	}
+	if o.Confirm != nil && !o.Confirm(preview) {
+		return zero, preview, ErrAborted
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return zero, preview, err
+	}
+	opts := metav1.PatchOptions{FieldManager: o.FieldManager}
+	if len(preview.Conflicts) > 0 && o.Confirm != nil {
+		force := true
+		opts.Force = &force
+	}
+	applied, err := o.patch(ctx, obj.GetName(), types.ApplyPatchType, data, opts)
+	if err != nil {
+		return zero, preview, err
+	}
+	return applied, preview, nil
+}
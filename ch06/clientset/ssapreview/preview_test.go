@@ -0,0 +1,241 @@
+package ssapreview
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+func deployment(replicas int32, image string) *appsv1.Deployment {
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "ns"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32(replicas),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "main", Image: image}},
+				},
+			},
+		},
+	}
+	dep.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	return dep
+}
+
+func TestDiff(t *testing.T) {
+	live := deployment(1, "nginx:1.22")
+	live.ResourceVersion = "10"
+	live.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+	applied := deployment(3, "nginx:1.23")
+	applied.ResourceVersion = "11"
+	applied.Labels = map[string]string{"app": "nginx"}
+	applied.Spec.Template.Spec.Containers = append(applied.Spec.Template.Spec.Containers,
+		corev1.Container{Name: "sidecar", Image: "busybox"})
+
+	changes, err := Diff(live, applied)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	want := []string{
+		`+ .metadata.labels.app: "nginx"`,
+		`~ .spec.replicas: 1 -> 3`,
+		`~ .spec.template.spec.containers[0].image: "nginx:1.22" -> "nginx:1.23"`,
+		`+ .spec.template.spec.containers[1].image: "busybox"`,
+		`+ .spec.template.spec.containers[1].name: "sidecar"`,
+	}
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Diff() =\n%v\nwant\n%v", got, want)
+	}
+
+	changes, err = Diff(nil, deployment(1, "nginx"))
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if len(changes) == 0 || changes[0].Old != nil {
+		t.Errorf("Diff(nil) = %v, want added fields", changes)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	err := apierrors.NewApplyConflict([]metav1.StatusCause{
+		{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "kubectl-client-side-apply" using apps/v1`,
+			Field:   ".spec.replicas",
+		},
+		{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "hpa"`,
+			Field:   ".spec.template.spec.containers[name=\"main\"].image",
+		},
+	}, "Apply failed with 2 conflicts")
+
+	got := Conflicts(err)
+	want := []Conflict{
+		{Manager: "kubectl-client-side-apply", Field: ".spec.replicas"},
+		{Manager: "hpa", Field: `.spec.template.spec.containers[name="main"].image`},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Conflicts() = %v, want %v", got, want)
+	}
+	if conflicts := Conflicts(apierrors.NewBadRequest("bad")); conflicts != nil {
+		t.Errorf("Conflicts() = %v, want nil", conflicts)
+	}
+}
+
+// fakeServer serves a deployment, replaced by the applies.
+// The applies without force fail with a conflict on
+// .spec.replicas. The options of the applies are recorded.
+type fakeServer struct {
+	live    *appsv1.Deployment
+	applies []metav1.PatchOptions
+}
+
+func (o *fakeServer) get(_ context.Context, name string, _ metav1.GetOptions) (*appsv1.Deployment, error) {
+	if o.live == nil || o.live.Name != name {
+		return &appsv1.Deployment{}, apierrors.NewNotFound(appsv1.Resource("deployments"), name)
+	}
+	return o.live.DeepCopy(), nil
+}
+
+func (o *fakeServer) patch(
+	_ context.Context,
+	_ string,
+	pt types.PatchType,
+	data []byte,
+	opts metav1.PatchOptions,
+	_ ...string,
+) (*appsv1.Deployment, error) {
+	o.applies = append(o.applies, opts)
+	if pt != types.ApplyPatchType {
+		return nil, apierrors.NewBadRequest("unexpected patch type")
+	}
+	if o.live != nil && (opts.Force == nil || !*opts.Force) {
+		return nil, apierrors.NewApplyConflict([]metav1.StatusCause{{
+			Type:    metav1.CauseTypeFieldManagerConflict,
+			Message: `conflict with "kubectl"`,
+			Field:   ".spec.replicas",
+		}}, "Apply failed with 1 conflict")
+	}
+	applied := &appsv1.Deployment{}
+	if err := json.Unmarshal(data, applied); err != nil {
+		return nil, err
+	}
+	if len(opts.DryRun) == 0 {
+		o.live = applied
+	}
+	return applied, nil
+}
+
+func TestApplier_Preview(t *testing.T) {
+	server := &fakeServer{}
+	preview, err := New(server.get, server.patch, "my-program").
+		Preview(context.Background(), deployment(1, "nginx"))
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if !preview.Created || len(preview.Conflicts) != 0 || len(preview.Changes) == 0 {
+		t.Errorf("Preview() = %+v, want a creation without conflict", preview)
+	}
+	if server.live != nil || len(server.applies) != 1 {
+		t.Errorf("%d applies, want 1 dry-run apply", len(server.applies))
+	}
+}
+
+func TestApplier_Apply(t *testing.T) {
+	for _, confirm := range []bool{false, true} {
+		t.Run(fmt.Sprintf("confirm %v", confirm), func(t *testing.T) {
+			server := &fakeServer{live: deployment(1, "nginx:1.22")}
+			applier := New(server.get, server.patch, "my-program")
+			var preview *Preview
+			applier.Confirm = func(p *Preview) bool {
+				preview = p
+				return confirm
+			}
+
+			dep, got, err := applier.Apply(context.Background(), deployment(3, "nginx:1.22"))
+			if got != preview {
+				t.Errorf("Apply() returned a different preview")
+			}
+			if preview == nil || preview.Created || fmt.Sprint(preview.Managers()) != "[kubectl]" ||
+				len(preview.Changes) != 1 || preview.Changes[0].Path != ".spec.replicas" {
+				t.Fatalf("preview = %+v", preview)
+			}
+			if !confirm {
+				if err != ErrAborted {
+					t.Errorf("Apply() error = %v, want ErrAborted", err)
+				}
+				if len(server.applies) != 2 {
+					t.Errorf("%d applies, want the 2 dry-run applies", len(server.applies))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if *dep.Spec.Replicas != 3 {
+				t.Errorf("replicas = %d, want 3", *dep.Spec.Replicas)
+			}
+			if len(server.applies) != 3 {
+				t.Fatalf("%d applies, want 2 dry-run applies and 1 apply", len(server.applies))
+			}
+			for i, opts := range server.applies {
+				dryRun := i < 2
+				if (len(opts.DryRun) > 0) != dryRun || opts.FieldManager != "my-program" {
+					t.Errorf("apply %d options = %+v, want dry run %v", i, opts, dryRun)
+				}
+			}
+			if opts := server.applies[2]; opts.Force == nil || !*opts.Force {
+				t.Errorf("apply options = %+v, want a forced apply", opts)
+			}
+
+			var buf bytes.Buffer
+			if err = preview.Print(&buf); err != nil {
+				t.Fatalf("Print() error = %v", err)
+			}
+			want := "~ .spec.replicas: 1 -> 3\n\nFIELD           OWNERSHIP LOST BY\n.spec.replicas  kubectl\n"
+			if buf.String() != want {
+				t.Errorf("Print() =\n%s\nwant\n%s", buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestApplier_ApplyWithoutForce(t *testing.T) {
+	// no conflicts: the apply is not forced
+	server := &fakeServer{}
+	applier := New(server.get, server.patch, "my-program")
+	applier.Confirm = func(*Preview) bool { return true }
+	if _, _, err := applier.Apply(context.Background(), deployment(1, "nginx")); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(server.applies) != 2 || server.applies[1].Force != nil {
+		t.Errorf("applies = %+v, want 1 dry-run apply and 1 apply without force", server.applies)
+	}
+
+	// conflicts without Confirm: the apply is not forced,
+	// and fails with the conflicts
+	server = &fakeServer{live: deployment(1, "nginx:1.22")}
+	applier = New(server.get, server.patch, "my-program")
+	_, _, err := applier.Apply(context.Background(), deployment(3, "nginx:1.22"))
+	if !apierrors.IsConflict(err) {
+		t.Errorf("Apply() error = %v, want a Conflict", err)
+	}
+	if len(server.applies) != 3 || server.applies[2].Force != nil {
+		t.Errorf("applies = %+v, want 2 dry-run applies and 1 apply without force", server.applies)
+	}
+}
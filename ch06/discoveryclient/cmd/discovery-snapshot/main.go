@@ -0,0 +1,123 @@
+// Command discovery-snapshot saves the discovery information
+// of the cluster of the current kubeconfig context to a file,
+// and resolves resources and kinds offline from such a file.
+//
+// Usage:
+//
+//	discovery-snapshot -o discovery.json
+//	discovery-snapshot -f discovery.json deploy Namespace apps/v1/Deployment
+//
+// Each argument of the resolve mode is a resource name (plural,
+// singular or short name) or a kind, optionally prefixed
+// with its group and version.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kprogo/ch6/discoveryclient/snapshot"
+)
+
+func main() {
+	output := flag.String("o", "", "file to save the discovery information of the cluster to")
+	input := flag.String("f", "", "discovery snapshot file to resolve the arguments from")
+	flag.Parse()
+
+	var err error
+	switch {
+	case *output != "" && *input == "":
+		err = dump(*output)
+	case *input != "" && *output == "":
+		err = resolve(*input, flag.Args())
+	default:
+		err = fmt.Errorf("exactly one of -o and -f is required")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func dump(path string) error {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		nil,
+	).ClientConfig()
+	if err != nil {
+		return err
+	}
+	client, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return err
+	}
+	snap, err := snapshot.Take(client)
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	return snap.Save(path)
+}
+
+func resolve(path string, args []string) error {
+	snap, err := snapshot.Load(path)
+	if err != nil {
+		return err
+	}
+	mapper, err := snap.RESTMapper()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ARGUMENT\tRESOURCE\tKIND\tNAMESPACED")
+	for _, arg := range args {
+		mapping, err := resolveArg(mapper, arg)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\terror: %v\t\t\n", arg, err)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\n",
+			arg,
+			mapping.Resource.GroupResource(),
+			mapping.GroupVersionKind,
+			mapping.Scope.Name() == meta.RESTScopeNameNamespace,
+		)
+	}
+	return tw.Flush()
+}
+
+// resolveArg returns the mapping of arg, a resource or a kind,
+// in the form [[group/]version/]name
+func resolveArg(mapper meta.RESTMapper, arg string) (*meta.RESTMapping, error) {
+	var gv schema.GroupVersion
+	name := arg
+	if i := strings.LastIndex(arg, "/"); i >= 0 {
+		var err error
+		gv, err = schema.ParseGroupVersion(arg[:i])
+		if err != nil {
+			return nil, err
+		}
+		name = arg[i+1:]
+	}
+
+	gvk, err := mapper.KindFor(gv.WithResource(strings.ToLower(name)))
+	if err != nil {
+		gvk = gv.WithKind(name)
+	}
+	versions := []string{}
+	if gvk.Version != "" {
+		versions = append(versions, gvk.Version)
+	}
+	return mapper.RESTMapping(gvk.GroupKind(), versions...)
+}
@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"errors"
+
+	openapi_v2 "github.com/google/gnostic/openapiv2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/openapi"
+	"k8s.io/client-go/rest"
+)
+
+// ErrOffline is returned for the information
+// not contained into a snapshot
+var ErrOffline = errors.New("not available from a discovery snapshot")
+
+// offlineClient is a discovery client
+// serving the content of a snapshot
+type offlineClient struct {
+	snapshot *Snapshot
+}
+
+var _ discovery.DiscoveryInterface = offlineClient{}
+
+// DiscoveryClient returns a discovery client serving the
+// groups, resources and server version of the snapshot.
+// The OpenAPI schemas are not available.
+func (o *Snapshot) DiscoveryClient() discovery.DiscoveryInterface {
+	return offlineClient{snapshot: o}
+}
+
+func (o offlineClient) RESTClient() rest.Interface {
+	return nil
+}
+
+func (o offlineClient) ServerGroups() (*metav1.APIGroupList, error) {
+	return &metav1.APIGroupList{
+		Groups: append([]metav1.APIGroup(nil), o.snapshot.Groups...),
+	}, nil
+}
+
+func (o offlineClient) ServerResourcesForGroupVersion(
+	groupVersion string,
+) (*metav1.APIResourceList, error) {
+	for i := range o.snapshot.Resources {
+		if o.snapshot.Resources[i].GroupVersion == groupVersion {
+			return o.snapshot.Resources[i].DeepCopy(), nil
+		}
+	}
+	gv, err := schema.ParseGroupVersion(groupVersion)
+	if err != nil {
+		return nil, err
+	}
+	return nil, apierrors.NewNotFound(
+		gv.WithResource("").GroupResource(),
+		groupVersion,
+	)
+}
+
+func (o offlineClient) ServerGroupsAndResources() (
+	[]*metav1.APIGroup,
+	[]*metav1.APIResourceList,
+	error,
+) {
+	groups := make([]*metav1.APIGroup, 0, len(o.snapshot.Groups))
+	for i := range o.snapshot.Groups {
+		groups = append(groups, o.snapshot.Groups[i].DeepCopy())
+	}
+	resources := make([]*metav1.APIResourceList, 0, len(o.snapshot.Resources))
+	for i := range o.snapshot.Resources {
+		resources = append(resources, o.snapshot.Resources[i].DeepCopy())
+	}
+	return groups, resources, nil
+}
+
+func (o offlineClient) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	return discovery.ServerPreferredResources(o)
+}
+
+func (o offlineClient) ServerPreferredNamespacedResources() ([]*metav1.APIResourceList, error) {
+	return discovery.ServerPreferredNamespacedResources(o)
+}
+
+func (o offlineClient) ServerVersion() (*version.Info, error) {
+	if o.snapshot.ServerVersion == nil {
+		return nil, ErrOffline
+	}
+	info := *o.snapshot.ServerVersion
+	return &info, nil
+}
+
+func (o offlineClient) OpenAPISchema() (*openapi_v2.Document, error) {
+	return nil, ErrOffline
+}
+
+func (o offlineClient) OpenAPIV3() openapi.Client {
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package snapshot saves the discovery information of a cluster
+// (groups, versions, resources with their scope and short names)
+// to a file, and builds from it a discovery client and a RESTMapper
+// working offline, to resolve kinds and resources without a cluster
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+)
+
+// Snapshot is the discovery information of a cluster
+type Snapshot struct {
+	// ServerVersion is the version of the API Server, if known
+	ServerVersion *version.Info `json:"serverVersion,omitempty"`
+	// Groups are the API groups served, with their versions
+	// and preferred version, in the order returned by the server
+	Groups []metav1.APIGroup `json:"groups"`
+	// Resources are the resources served, for each group version
+	Resources []metav1.APIResourceList `json:"resources"`
+}
+
+// Take returns the snapshot of the discovery information
+// served by client. If some group versions cannot be discovered,
+// Take returns the partial snapshot and an
+// *discovery.ErrGroupDiscoveryFailed error.
+func Take(client discovery.DiscoveryInterface) (*Snapshot, error) {
+	serverVersion, err := client.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("getting server version: %w", err)
+	}
+	groups, resources, err := client.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("getting groups and resources: %w", err)
+	}
+
+	snapshot := &Snapshot{
+		ServerVersion: serverVersion,
+		Groups:        make([]metav1.APIGroup, 0, len(groups)),
+		Resources:     make([]metav1.APIResourceList, 0, len(resources)),
+	}
+	for _, group := range groups {
+		snapshot.Groups = append(snapshot.Groups, *group)
+	}
+	for _, list := range resources {
+		snapshot.Resources = append(snapshot.Resources, *list)
+	}
+	return snapshot, err
+}
+
+// Write writes the snapshot as indented JSON to w
+func (o *Snapshot) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(o)
+}
+
+// Save writes the snapshot to the file at path
+func (o *Snapshot) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = o.Write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Read reads a snapshot written by Write from r
+func Read(r io.Reader) (*Snapshot, error) {
+	snapshot := &Snapshot{}
+	err := json.NewDecoder(r).Decode(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("decoding discovery snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// Load reads the snapshot saved by Save to the file at path
+func Load(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// RESTMapper returns a RESTMapper for the groups and resources
+// of the snapshot, with their scope, and expanding the short
+// names of the resources (e.g. deploy for deployments)
+func (o *Snapshot) RESTMapper() (meta.RESTMapper, error) {
+	client := o.DiscoveryClient()
+	groupResources, err := restmapper.GetAPIGroupResources(client)
+	if err != nil {
+		return nil, err
+	}
+	return restmapper.NewShortcutExpander(
+		restmapper.NewDiscoveryRESTMapper(groupResources),
+		client,
+	), nil
+}
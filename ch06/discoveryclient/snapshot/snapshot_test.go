@@ -0,0 +1,139 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newFakeDiscovery() *fakediscovery.FakeDiscovery {
+	return &fakediscovery.FakeDiscovery{
+		Fake: &clienttesting.Fake{
+			Resources: []*metav1.APIResourceList{
+				{
+					GroupVersion: "v1",
+					APIResources: []metav1.APIResource{
+						{Name: "pods", SingularName: "pod", Namespaced: true, Kind: "Pod", ShortNames: []string{"po"}},
+						{Name: "pods/log", Namespaced: true, Kind: "Pod"},
+						{Name: "namespaces", SingularName: "namespace", Namespaced: false, Kind: "Namespace", ShortNames: []string{"ns"}},
+					},
+				},
+				{
+					GroupVersion: "apps/v1",
+					APIResources: []metav1.APIResource{
+						{Name: "deployments", SingularName: "deployment", Namespaced: true, Kind: "Deployment", ShortNames: []string{"deploy"}},
+					},
+				},
+			},
+		},
+		FakedServerVersion: &version.Info{Major: "1", Minor: "25", GitVersion: "v1.25.3"},
+	}
+}
+
+func takeSnapshot(t *testing.T) *Snapshot {
+	t.Helper()
+	snapshot, err := Take(newFakeDiscovery())
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	return snapshot
+}
+
+func TestTake_SaveLoad(t *testing.T) {
+	snapshot := takeSnapshot(t)
+	if len(snapshot.Groups) != 2 || len(snapshot.Resources) != 2 {
+		t.Fatalf("Take() = %d groups and %d resource lists, want 2 and 2",
+			len(snapshot.Groups), len(snapshot.Resources))
+	}
+
+	path := filepath.Join(t.TempDir(), "discovery.json")
+	if err := snapshot.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.ServerVersion.GitVersion != "v1.25.3" {
+		t.Errorf("server version = %+v, want v1.25.3", loaded.ServerVersion)
+	}
+	resources, err := loaded.DiscoveryClient().ServerResourcesForGroupVersion("apps/v1")
+	if err != nil {
+		t.Fatalf("ServerResourcesForGroupVersion() error = %v", err)
+	}
+	if got := resources.APIResources[0].ShortNames; len(got) != 1 || got[0] != "deploy" {
+		t.Errorf("short names = %v, want [deploy]", got)
+	}
+	if _, err = loaded.DiscoveryClient().ServerResourcesForGroupVersion("batch/v1"); err == nil {
+		t.Error("ServerResourcesForGroupVersion(batch/v1): expected an error")
+	}
+}
+
+func TestSnapshot_RESTMapper(t *testing.T) {
+	mapper, err := takeSnapshot(t).RESTMapper()
+	if err != nil {
+		t.Fatalf("RESTMapper() error = %v", err)
+	}
+
+	tests := []struct {
+		kind     schema.GroupKind
+		resource schema.GroupVersionResource
+		scope    meta.RESTScopeName
+	}{
+		{
+			kind:     schema.GroupKind{Group: "apps", Kind: "Deployment"},
+			resource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			scope:    meta.RESTScopeNameNamespace,
+		},
+		{
+			kind:     schema.GroupKind{Kind: "Namespace"},
+			resource: schema.GroupVersionResource{Version: "v1", Resource: "namespaces"},
+			scope:    meta.RESTScopeNameRoot,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind.String(), func(t *testing.T) {
+			mapping, err := mapper.RESTMapping(tt.kind)
+			if err != nil {
+				t.Fatalf("RESTMapping() error = %v", err)
+			}
+			if mapping.Resource != tt.resource {
+				t.Errorf("resource = %v, want %v", mapping.Resource, tt.resource)
+			}
+			if mapping.Scope == nil || mapping.Scope.Name() != tt.scope {
+				t.Errorf("scope = %v, want %s", mapping.Scope, tt.scope)
+			}
+		})
+	}
+}
+
+func TestSnapshot_RESTMapperShortNames(t *testing.T) {
+	mapper, err := takeSnapshot(t).RESTMapper()
+	if err != nil {
+		t.Fatalf("RESTMapper() error = %v", err)
+	}
+	tests := []struct {
+		resource string
+		want     schema.GroupVersionKind
+	}{
+		{resource: "deploy", want: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		{resource: "deployment", want: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		{resource: "ns", want: schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}},
+	}
+	for _, tt := range tests {
+		gvk, err := mapper.KindFor(schema.GroupVersionResource{Resource: tt.resource})
+		if err != nil {
+			t.Errorf("KindFor(%s) error = %v", tt.resource, err)
+			continue
+		}
+		if gvk != tt.want {
+			t.Errorf("KindFor(%s) = %v, want %v", tt.resource, gvk, tt.want)
+		}
+	}
+}
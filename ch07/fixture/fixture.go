@@ -0,0 +1,160 @@
+// Package fixture records the HTTP exchanges between a client
+// and an API Server into fixture files, and replays them
+// deterministically in tests, without a cluster
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Fixture is a list of recorded exchanges
+type Fixture struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Exchange is a request and the response of the server
+type Exchange struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. The host, and the headers
+// containing credentials, are not recorded.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Query is the encoded query, with the parameters sorted by key
+	Query string `json:"query,omitempty"`
+	Body  Body   `json:"body,omitempty"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        Body   `json:"body,omitempty"`
+}
+
+// Body is a request or response body. JSON bodies are saved
+// as JSON with sorted keys, other bodies as base64 strings.
+type Body []byte
+
+// MarshalJSON implements json.Marshaler
+func (o Body) MarshalJSON() ([]byte, error) {
+	if len(o) == 0 {
+		return []byte("null"), nil
+	}
+	if normalized, ok := normalizeJSON(o); ok {
+		return normalized, nil
+	}
+	return json.Marshal(struct {
+		Base64 []byte `json:"base64"`
+	}{Base64: o})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (o *Body) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Base64 []byte `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &raw); err == nil && raw.Base64 != nil {
+		*o = raw.Base64
+		return nil
+	}
+	if string(data) == "null" {
+		*o = nil
+		return nil
+	}
+	// the indentation of the file is removed
+	if normalized, ok := normalizeJSON(data); ok {
+		data = normalized
+	}
+	*o = append((*o)[:0], data...)
+	return nil
+}
+
+// normalizeJSON returns data re-encoded with sorted keys
+// and without spaces, and false if data is not JSON
+func normalizeJSON(data []byte) ([]byte, bool) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, false
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	return normalized, true
+}
+
+// equalBodies returns true if a and b are the same JSON
+// value, or the same bytes if one of them is not JSON
+func equalBodies(a, b []byte) bool {
+	normalizedA, okA := normalizeJSON(a)
+	normalizedB, okB := normalizeJSON(b)
+	if okA && okB {
+		return bytes.Equal(normalizedA, normalizedB)
+	}
+	return bytes.Equal(a, b)
+}
+
+// normalizeQuery returns the encoded query
+// with the parameters sorted by key
+func normalizeQuery(query url.Values) string {
+	return query.Encode()
+}
+
+// newRequest returns the recorded version of req, with its body
+func newRequest(req *http.Request, body []byte) Request {
+	return Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  normalizeQuery(req.URL.Query()),
+		Body:   body,
+	}
+}
+
+// matches returns true if the recorded request o matches req,
+// i.e. has the same method, path, query and body
+func (o Request) matches(req Request) bool {
+	return o.Method == req.Method &&
+		o.Path == req.Path &&
+		o.Query == req.Query &&
+		equalBodies(o.Body, req.Body)
+}
+
+// String returns the method, path and query of the request
+func (o Request) String() string {
+	result := o.Method + " " + o.Path
+	if o.Query != "" {
+		result += "?" + o.Query
+	}
+	return result
+}
+
+// Load reads the fixture saved to the file at path
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fixture := &Fixture{}
+	if err = json.Unmarshal(data, fixture); err != nil {
+		return nil, fmt.Errorf("decoding fixture %s: %w", path, err)
+	}
+	return fixture, nil
+}
+
+// Save writes the fixture as indented JSON to the file at path
+func (o *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,330 @@
+package fixture
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newServer returns a server storing the ConfigMaps
+// created in memory, and counting the requests
+func newServer(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		created  = map[string]string{}
+		requests int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			created["cm1"] = string(body)
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, string(body))
+		case http.MethodGet:
+			body, found := created[filepath.Base(r.URL.Path)]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+				return
+			}
+			io.WriteString(w, body)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// scenario gets, creates and gets again a ConfigMap
+func scenario(t *testing.T, config *rest.Config) {
+	t.Helper()
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configMaps := clientset.CoreV1().ConfigMaps("project1")
+	ctx := context.Background()
+
+	_, err = configMaps.Get(ctx, "cm1", metav1.GetOptions{})
+	if !kerrors.IsNotFound(err) {
+		t.Fatalf("first Get() error = %v, want NotFound", err)
+	}
+	_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm1"},
+		Data:       map[string]string{"key": "value"},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	cm, err := configMaps.Get(ctx, "cm1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("second Get() error = %v", err)
+	}
+	if cm.Data["key"] != "value" {
+		t.Errorf("Get() = %+v, want the created ConfigMap", cm)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	server, requests := newServer(t)
+	config := &rest.Config{Host: server.URL}
+	recorder := NewRecorder()
+	recorder.WrapConfig(config)
+	scenario(t, config)
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	fixture, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(fixture.Exchanges) != 3 {
+		t.Fatalf("fixture has %d exchanges, want 3", len(fixture.Exchanges))
+	}
+	if got := fixture.Exchanges[1].Request; got.Method != "POST" ||
+		got.Path != "/api/v1/namespaces/project1/configmaps" ||
+		!strings.Contains(string(got.Body), `"key":"value"`) {
+		t.Errorf("second request = %+v", got)
+	}
+
+	// the same scenario is replayed without the server,
+	// the two Gets being answered in order
+	recorded := *requests
+	replayer := NewReplayer(fixture, Options{Strict: true})
+	scenario(t, replayer.Config())
+	if err = replayer.Verify(); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if *requests != recorded {
+		t.Errorf("the server received %d requests during the replay", *requests-recorded)
+	}
+}
+
+func TestReplayer_Unrecorded(t *testing.T) {
+	fixture := &Fixture{Exchanges: []Exchange{{
+		Request: Request{Method: "GET", Path: "/api/v1/namespaces/project1/configmaps/cm1"},
+		Response: Response{
+			Status:      200,
+			ContentType: "application/json",
+			Body:        Body(`{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"cm1"}}`),
+		},
+	}}}
+
+	tests := []struct {
+		name    string
+		options Options
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name:    "strict",
+			options: Options{Strict: true},
+			check: func(t *testing.T, err error) {
+				if err == nil || !strings.Contains(err.Error(), "no recorded exchange for GET /api/v1/namespaces/project1/configmaps/cm2") {
+					t.Errorf("Get() error = %v, want no recorded exchange", err)
+				}
+			},
+		},
+		{
+			name: "not strict",
+			check: func(t *testing.T, err error) {
+				if !kerrors.IsNotFound(err) {
+					t.Errorf("Get() error = %v, want NotFound", err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replayer := NewReplayer(fixture, tt.options)
+			clientset, err := kubernetes.NewForConfig(replayer.Config())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = clientset.CoreV1().ConfigMaps("project1").Get(context.Background(), "cm2", metav1.GetOptions{})
+			tt.check(t, err)
+
+			err = replayer.Verify()
+			if err == nil || !strings.Contains(err.Error(), "unrecorded request GET /api/v1/namespaces/project1/configmaps/cm2") {
+				t.Errorf("Verify() error = %v, want unrecorded request", err)
+			}
+			if unserved := strings.Contains(err.Error(), "unserved exchange"); unserved != tt.options.Strict {
+				t.Errorf("Verify() reports unserved exchanges = %v, want %v", unserved, tt.options.Strict)
+			}
+		})
+	}
+}
+
+func TestRequest_Matches(t *testing.T) {
+	recorded := Request{
+		Method: "PATCH",
+		Path:   "/apis/apps/v1/namespaces/ns/deployments/nginx",
+		Query:  "fieldManager=me&force=true",
+		Body:   Body(`{"spec":{"replicas":2},"metadata":{"name":"nginx"}}`),
+	}
+	tests := []struct {
+		name string
+		req  Request
+		want bool
+	}{
+		{name: "same", req: recorded, want: true},
+		{
+			name: "JSON keys in other order",
+			req:  Request{Method: "PATCH", Path: recorded.Path, Query: recorded.Query, Body: Body(`{"metadata": {"name": "nginx"}, "spec": {"replicas": 2}}`)},
+			want: true,
+		},
+		{
+			name: "other body",
+			req:  Request{Method: "PATCH", Path: recorded.Path, Query: recorded.Query, Body: Body(`{"spec":{"replicas":3}}`)},
+		},
+		{
+			name: "other query",
+			req:  Request{Method: "PATCH", Path: recorded.Path, Query: "fieldManager=me", Body: recorded.Body},
+		},
+		{
+			name: "other method",
+			req:  Request{Method: "PUT", Path: recorded.Path, Query: recorded.Query, Body: recorded.Body},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recorded.matches(tt.req); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordReplay_Secrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"kind":"Secret","apiVersion":"v1","metadata":{"name":"creds"},"data":{"key":"c2VjcmV0"}}`)
+	}))
+	defer server.Close()
+	scenario := func(config *rest.Config) {
+		t.Helper()
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = clientset.CoreV1().Secrets("project1").Patch(context.Background(), "creds",
+			types.MergePatchType, []byte(`{"stringData":{"key":"secret"}}`), metav1.PatchOptions{})
+		if err != nil {
+			t.Fatalf("Patch() error = %v", err)
+		}
+	}
+
+	config := &rest.Config{Host: server.URL}
+	recorder := NewRecorder()
+	recorder.WrapConfig(config)
+	scenario(config)
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"secret"`) || strings.Contains(string(data), "c2VjcmV0") {
+		t.Errorf("the fixture contains the secret values:\n%s", data)
+	}
+
+	fixture, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	replayer := NewReplayer(fixture, Options{Strict: true})
+	scenario(replayer.Config())
+	if err = replayer.Verify(); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestScrubSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		want string
+	}{
+		{
+			name: "secret list",
+			path: "/api/v1/secrets",
+			body: `{"kind":"SecretList","items":[{"data":{"key":"dmFsdWU="}}]}`,
+			want: `{"items":[{"data":{"key":"REDACTED"}}],"kind":"SecretList"}`,
+		},
+		{
+			name: "JSON patch",
+			path: "/api/v1/namespaces/ns/secrets/creds",
+			body: `[{"op":"add","path":"/data/key","value":"dmFsdWU="},{"op":"remove","path":"/data/old"}]`,
+			want: `[{"op":"add","path":"/data/key","value":"REDACTED"},{"op":"remove","path":"/data/old"}]`,
+		},
+		{
+			name: "JSON patch of other fields",
+			path: "/api/v1/namespaces/ns/secrets/creds",
+			body: `[{"op":"replace","path":"/stringData","value":{"key":"value"}},{"op":"add","path":"/dataSource","value":"web"}]`,
+			want: `[{"op":"replace","path":"/stringData","value":{"key":"REDACTED"}},{"op":"add","path":"/dataSource","value":"web"}]`,
+		},
+		{
+			name: "watch events",
+			path: "/api/v1/namespaces/ns/secrets",
+			body: `{"type":"ADDED","object":{"data":{"key":"dmFsdWU="}}}` + "\n" + `{"type":"DELETED","object":{"data":{"key":"dmFsdWU="}}}`,
+			want: `{"object":{"data":{"key":"REDACTED"}},"type":"ADDED"}` + "\n" + `{"object":{"data":{"key":"REDACTED"}},"type":"DELETED"}`,
+		},
+		{
+			name: "protobuf secret",
+			path: "/api/v1/namespaces/ns/secrets/creds",
+			body: "k8s\x00\x0a",
+		},
+		{
+			name: "config map",
+			path: "/api/v1/namespaces/secrets/configmaps/cm",
+			body: `{"data":{"key":"value"}}`,
+			want: `{"data":{"key":"value"}}`,
+		},
+		{
+			name: "token request",
+			path: "/api/v1/namespaces/ns/serviceaccounts/default/token",
+			body: `{"status":{"token":"abc"}}`,
+			want: `{"status":{"token":"REDACTED"}}`,
+		},
+		{
+			name: "kubeconfig",
+			path: "/api/v1/namespaces/ns/configmaps/kubeconfig",
+			body: `{"users":[{"user":{"client-key-data":"a2V5","client-certificate-data":"Y2VydA=="}}]}`,
+			want: `{"users":[{"user":{"client-certificate-data":"REDACTED","client-key-data":"REDACTED"}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exchange := Exchange{
+				Request:  Request{Method: "GET", Path: tt.path},
+				Response: Response{Body: Body(tt.body)},
+			}
+			ScrubSecrets(&exchange)
+			if got := strings.TrimSpace(string(exchange.Response.Body)); got != tt.want {
+				t.Errorf("ScrubSecrets() body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
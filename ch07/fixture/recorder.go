@@ -0,0 +1,105 @@
+package fixture
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// Recorder records the exchanges made through
+// the transports it wraps. It is safe for concurrent use.
+type Recorder struct {
+	scrubbers []Scrubber
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// NewRecorder returns an empty Recorder. The exchanges are
+// scrubbed by ScrubSecrets, then by scrubbers, before being
+// recorded: the Replayers of the fixture need the same scrubbers.
+func NewRecorder(scrubbers ...Scrubber) *Recorder {
+	return &Recorder{scrubbers: append([]Scrubber{ScrubSecrets}, scrubbers...)}
+}
+
+// WrapConfig records the exchanges of the clients created from config
+func (o *Recorder) WrapConfig(config *rest.Config) {
+	config.WrapTransport = transport.Wrappers(config.WrapTransport, o.Wrap)
+}
+
+// Wrap returns a RoundTripper recording the exchanges
+// made through rt. It is a transport.WrapperFunc.
+func (o *Recorder) Wrap(rt http.RoundTripper) http.RoundTripper {
+	return &recordingRoundTripper{recorder: o, delegate: rt}
+}
+
+// Fixture returns the exchanges recorded so far
+func (o *Recorder) Fixture() *Fixture {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return &Fixture{
+		Exchanges: append([]Exchange(nil), o.exchanges...),
+	}
+}
+
+// Save writes the exchanges recorded so far to the file at path
+func (o *Recorder) Save(path string) error {
+	return o.Fixture().Save(path)
+}
+
+type recordingRoundTripper struct {
+	recorder *Recorder
+	delegate http.RoundTripper
+}
+
+func (o *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		reqBody, err = io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := o.delegate.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// the body is read entirely before being returned to the
+	// client: a watch is recorded until the server closes it,
+	// e.g. at the end of its timeoutSeconds
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	// the scrubbers modify a copy of the response body,
+	// the client receives the body sent by the server
+	exchange := Exchange{
+		Request: newRequest(req, reqBody),
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        append(Body(nil), respBody...),
+		},
+	}
+	for _, scrub := range o.recorder.scrubbers {
+		scrub(&exchange)
+	}
+
+	o.recorder.mu.Lock()
+	defer o.recorder.mu.Unlock()
+	o.recorder.exchanges = append(o.recorder.exchanges, exchange)
+	return resp, nil
+}
@@ -0,0 +1,175 @@
+package fixture
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/rest"
+)
+
+// Options are the options of a Replayer
+type Options struct {
+	// Strict fails the requests not matching any recorded
+	// exchange with an error, instead of answering them
+	// with a 404 NotFound status
+	Strict bool
+	// Scrubbers are the scrubbers passed to the Recorder of
+	// the fixture, applied to the requests before matching
+	// them, after ScrubSecrets
+	Scrubbers []Scrubber
+}
+
+// Replayer is a RoundTripper answering the requests with
+// the responses of the recorded exchanges. A request matches
+// an exchange with the same method, path, query and scrubbed
+// body (compared as JSON values for JSON bodies).
+//
+// The exchanges are served in the order they are recorded:
+// a request is answered with the first matching exchange not
+// served yet, or the last matching exchange when all
+// have been served, e.g. for a polling loop.
+//
+// A Replayer is safe for concurrent use.
+type Replayer struct {
+	options Options
+
+	mu        sync.Mutex
+	exchanges []Exchange
+	served    []bool
+	unmatched []Request
+}
+
+// NewReplayer returns a Replayer for the exchanges of fixture
+func NewReplayer(fixture *Fixture, options Options) *Replayer {
+	return &Replayer{
+		options:   options,
+		exchanges: fixture.Exchanges,
+		served:    make([]bool, len(fixture.Exchanges)),
+	}
+}
+
+// Config returns a configuration for clients sending
+// their requests to the Replayer
+func (o *Replayer) Config() *rest.Config {
+	return &rest.Config{
+		Host:      "http://replayer.fixture",
+		Transport: o,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (o *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	scrubbed := Exchange{Request: newRequest(req, body)}
+	ScrubSecrets(&scrubbed)
+	for _, scrub := range o.options.Scrubbers {
+		scrub(&scrubbed)
+	}
+	recorded := scrubbed.Request
+
+	exchange, found := o.match(recorded)
+	if !found {
+		if o.options.Strict {
+			return nil, fmt.Errorf("no recorded exchange for %s", recorded)
+		}
+		return newResponse(req, Response{
+			Status:      http.StatusNotFound,
+			ContentType: "application/json",
+			Body: Body(fmt.Sprintf(
+				`{"kind":"Status","apiVersion":"v1","status":"Failure","message":%q,"reason":"NotFound","code":404}`,
+				"no recorded exchange for "+recorded.String(),
+			)),
+		}), nil
+	}
+	return newResponse(req, exchange.Response), nil
+}
+
+// match returns the exchange to answer req with
+func (o *Replayer) match(req Request) (Exchange, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	last := -1
+	for i, exchange := range o.exchanges {
+		if !exchange.Request.matches(req) {
+			continue
+		}
+		if !o.served[i] {
+			o.served[i] = true
+			return exchange, true
+		}
+		last = i
+	}
+	if last >= 0 {
+		return o.exchanges[last], true
+	}
+	o.unmatched = append(o.unmatched, req)
+	return Exchange{}, false
+}
+
+// Unmatched returns the requests not matching any exchange
+func (o *Replayer) Unmatched() []Request {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Request(nil), o.unmatched...)
+}
+
+// Unserved returns the exchanges not served yet
+func (o *Replayer) Unserved() []Exchange {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var result []Exchange
+	for i, exchange := range o.exchanges {
+		if !o.served[i] {
+			result = append(result, exchange)
+		}
+	}
+	return result
+}
+
+// Verify returns an error if some requests did not match any
+// exchange, or, in strict mode, if some exchanges were not served
+func (o *Replayer) Verify() error {
+	var problems []string
+	for _, req := range o.Unmatched() {
+		problems = append(problems, "unrecorded request "+req.String())
+	}
+	if o.options.Strict {
+		for _, exchange := range o.Unserved() {
+			problems = append(problems, "unserved exchange "+exchange.Request.String())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("fixture mismatch:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func newResponse(req *http.Request, recorded Response) *http.Response {
+	header := http.Header{}
+	if recorded.ContentType != "" {
+		header.Set("Content-Type", recorded.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}
+}
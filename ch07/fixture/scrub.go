@@ -0,0 +1,139 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// Redacted replaces the scrubbed values. It is valid base64,
+// so the data of the scrubbed Secrets can still be decoded.
+const Redacted = "REDACTED"
+
+// Scrubber modifies an exchange before it is recorded, e.g. to
+// remove the sensitive values of its bodies. The Replayer applies
+// the same scrubbers to the requests before matching them.
+type Scrubber func(exchange *Exchange)
+
+// sensitiveKeys are the keys of the values redacted in any object,
+// the same as in the request inspector of chapter 6
+var sensitiveKeys = map[string]bool{
+	"token":                   true,
+	"password":                true,
+	"client-key-data":         true,
+	"client-certificate-data": true,
+}
+
+// ScrubSecrets is applied by all the Recorders and Replayers.
+// In the JSON bodies of the requests to the secrets resource, it
+// redacts the values of the data and stringData fields, whether
+// the body is a Secret, a list, a watch event or a patch. It
+// redacts the token, password, client-key-data and
+// client-certificate-data fields of the JSON bodies of all the
+// requests, e.g. of TokenRequests. The other bodies of the
+// requests to the secrets resource, e.g. Protobuf, are dropped:
+// their fixtures must be recorded with JSON clients.
+func ScrubSecrets(exchange *Exchange) {
+	secrets := isSecretsPath(exchange.Request.Path)
+	exchange.Request.Body = scrubBody(exchange.Request.Body, secrets)
+	exchange.Response.Body = scrubBody(exchange.Response.Body, secrets)
+}
+
+// scrubBody returns a scrubbed copy of body, which can be a
+// stream of JSON values, e.g. the events of a watch
+func scrubBody(body Body, secrets bool) Body {
+	if len(body) == 0 {
+		return body
+	}
+	var scrubbed bytes.Buffer
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	encoder := json.NewEncoder(&scrubbed)
+	for {
+		var value interface{}
+		err := decoder.Decode(&value)
+		if err == io.EOF {
+			return scrubbed.Bytes()
+		}
+		if err != nil {
+			if secrets {
+				return nil
+			}
+			return body
+		}
+		if err = encoder.Encode(scrubValue(value, secrets)); err != nil {
+			return nil
+		}
+	}
+}
+
+// scrubValue scrubs value, and the values it contains, in place
+func scrubValue(value interface{}, secrets bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			_, isString := child.(string)
+			switch {
+			case secrets && (key == "data" || key == "stringData"):
+				v[key] = redactData(child)
+			case isString && sensitiveKeys[key]:
+				v[key] = Redacted
+			default:
+				v[key] = scrubValue(child, secrets)
+			}
+		}
+		// a JSON patch sets the data with the path of its
+		// operations, e.g. {"op":"add","path":"/data/key"}
+		path, isString := v["path"].(string)
+		if _, found := v["value"]; found && isString && secrets && isDataPath(path) {
+			v["value"] = redactData(v["value"])
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = scrubValue(v[i], secrets)
+		}
+	}
+	return value
+}
+
+// redactData redacts the values of data, or data itself if it
+// is not an object. The null values, removing keys, are kept.
+func redactData(data interface{}) interface{} {
+	values, isObject := data.(map[string]interface{})
+	if !isObject {
+		if data == nil {
+			return nil
+		}
+		return Redacted
+	}
+	for key, value := range values {
+		if value != nil {
+			values[key] = Redacted
+		}
+	}
+	return values
+}
+
+// isDataPath returns true if path is the path of the data or
+// stringData field, or of one of their keys, in a JSON patch
+func isDataPath(path string) bool {
+	for _, field := range []string{"/data", "/stringData"} {
+		if path == field || strings.HasPrefix(path, field+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecretsPath returns true if path is the path of the
+// Secrets, in a namespace or in all the namespaces
+func isSecretsPath(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "api" {
+		return false
+	}
+	if parts[2] == "namespaces" {
+		return len(parts) >= 5 && parts[4] == "secrets"
+	}
+	return parts[2] == "secrets"
+}
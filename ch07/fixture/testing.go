@@ -0,0 +1,51 @@
+package fixture
+
+import (
+	"os"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+// RecordEnvVar is the environment variable enabling
+// the recording of the fixtures used by Setup
+const RecordEnvVar = "RECORD_FIXTURES"
+
+// Setup returns the configuration for the clients of a test.
+//
+// When the RECORD_FIXTURES environment variable is set to a non-empty
+// value, the clients connect to the cluster configured by live (e.g. a
+// test cluster or envtest), and their exchanges are saved to the file at
+// path when the test ends. Otherwise, the clients are answered by a strict
+// Replayer of the fixture saved at path, and the test fails if the
+// requests do not match exactly the exchanges of the fixture.
+func Setup(t testing.TB, path string, live func() (*rest.Config, error)) *rest.Config {
+	t.Helper()
+	if os.Getenv(RecordEnvVar) != "" {
+		config, err := live()
+		if err != nil {
+			t.Fatalf("getting configuration to record %s: %v", path, err)
+		}
+		config = rest.CopyConfig(config)
+		recorder := NewRecorder()
+		recorder.WrapConfig(config)
+		t.Cleanup(func() {
+			if err := recorder.Save(path); err != nil {
+				t.Errorf("saving fixture: %v", err)
+			}
+		})
+		return config
+	}
+
+	fixture, err := Load(path)
+	if err != nil {
+		t.Fatalf("loading fixture: %v", err)
+	}
+	replayer := NewReplayer(fixture, Options{Strict: true})
+	t.Cleanup(func() {
+		if err := replayer.Verify(); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	})
+	return replayer.Config()
+}
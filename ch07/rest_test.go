@@ -10,7 +10,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/rest/fake"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/kprogo/ch7/fixture"
 )
 
 func Test_getPods1(t *testing.T) {
@@ -62,3 +66,33 @@ func Test_getPods2(t *testing.T) {
 		t.Errorf("Error code must be %d but is %d\n", http.StatusNotFound, code)
 	}
 }
+
+func Test_getPods3(t *testing.T) {
+	// run with RECORD_FIXTURES=1 to record the
+	// fixture from the cluster of the kubeconfig
+	config := fixture.Setup(t, "testdata/getpods.json", func() (*rest.Config, error) {
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			clientcmd.NewDefaultClientConfigLoadingRules(),
+			nil,
+		).ClientConfig()
+	})
+	config.APIPath = "/api"
+	config.GroupVersion = &corev1.SchemeGroupVersion
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	restClient, err := rest.RESTClientFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pods, err := getPods(
+		context.Background(),
+		restClient,
+		"default",
+	)
+	if err != nil {
+		t.Fatalf("getPods() error = %v", err)
+	}
+	if len(pods) != 1 || pods[0].GetName() != "nginx" {
+		t.Errorf("pods = %+v, want the nginx pod", pods)
+	}
+}
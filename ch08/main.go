@@ -20,15 +20,25 @@ func main() {
 		panic(err)
 	}
 	ctx := context.Background()
-	list, err := clientset.ApiextensionsV1().
-		CustomResourceDefinitions().
-		List(ctx, metav1.ListOptions{})
-	if err != nil {
-		panic(err)
-	}
+	// the CRDs are listed page by page, the next
+	// pages being part of the snapshot of the first one
+	opts := metav1.ListOptions{Limit: 100}
+	for {
+		list, err := clientset.ApiextensionsV1().
+			CustomResourceDefinitions().
+			List(ctx, opts)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, crd := range list.Items {
+			fmt.Printf("%s\n", crd.GetName())
+		}
 
-	for _, crd := range list.Items {
-		fmt.Printf("%s\n", crd.GetName())
+		if list.Continue == "" {
+			break
+		}
+		opts.Continue = list.Continue
 	}
 }
 
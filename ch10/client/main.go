@@ -36,7 +36,7 @@ func main() {
 		ControllerManagedBy(mgr).
 		For(&mygroupv1alpha1.MyResource{}).
 		Owns(&corev1.Pod{}).
-		Complete(&MyReconciler{})
+		Complete(NewMyReconciler(mgr))
 	panicIf(err)
 
 	err = mgr.Start(context.Background())
@@ -47,6 +47,13 @@ type MyReconciler struct {
 	client client.Client
 }
 
+// NewMyReconciler returns a reconciler using the client of mgr
+func NewMyReconciler(mgr manager.Manager) *MyReconciler {
+	return &MyReconciler{
+		client: mgr.GetClient(),
+	}
+}
+
 func (a *MyReconciler) Reconcile(
 	ctx context.Context,
 	req reconcile.Request,
@@ -169,13 +176,6 @@ func (a *MyReconciler) Reconcile(
 	return reconcile.Result{}, nil
 }
 
-func (a *MyReconciler) InjectClient(
-	c client.Client,
-) error {
-	a.client = c
-	return nil
-}
-
 func panicIf(err error) {
 	if err != nil {
 		panic(err)
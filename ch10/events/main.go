@@ -31,17 +31,11 @@ func main() {
 	)
 	panicIf(err)
 
-	eventRecorder := mgr.GetEventRecorderFor(
-		"MyResource",
-	)
-
 	err = builder.
 		ControllerManagedBy(mgr).
 		For(&mygroupv1alpha1.MyResource{}).
 		Owns(&corev1.Pod{}).
-		Complete(&MyReconciler{
-			EventRecorder: eventRecorder,
-		})
+		Complete(NewMyReconciler(mgr))
 	panicIf(err)
 
 	err = mgr.Start(context.Background())
@@ -53,6 +47,15 @@ type MyReconciler struct {
 	EventRecorder record.EventRecorder
 }
 
+// NewMyReconciler returns a reconciler using the
+// client and an event recorder of mgr
+func NewMyReconciler(mgr manager.Manager) *MyReconciler {
+	return &MyReconciler{
+		client:        mgr.GetClient(),
+		EventRecorder: mgr.GetEventRecorderFor("MyResource"),
+	}
+}
+
 func (a *MyReconciler) Reconcile(
 	ctx context.Context,
 	req reconcile.Request,
@@ -75,13 +78,6 @@ func (a *MyReconciler) Reconcile(
 	return reconcile.Result{}, nil
 }
 
-func (a *MyReconciler) InjectClient(
-	c client.Client,
-) error {
-	a.client = c
-	return nil
-}
-
 func panicIf(err error) {
 	if err != nil {
 		panic(err)
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -15,9 +17,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	mygroupv1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+
+	"github.com/kprogo/ch10/ex1/trigger"
 )
 
 func main() {
+	triggerFile := flag.String("trigger-file", "",
+		"file containing a signal, in JSON, selecting the MyResources to reconcile when the file changes")
+	triggerAddr := flag.String("trigger-bind-address", "127.0.0.1:8082",
+		"address the trigger endpoint binds to, without authentication, empty to disable it")
+	flag.Parse()
+
 	scheme := runtime.NewScheme() // ❶
 	clientgoscheme.AddToScheme(scheme)
 	mygroupv1alpha1.AddToScheme(scheme)
@@ -56,6 +66,25 @@ func main() {
 	)
 	panicIf(err)
 
+	// reconciles the MyResources selected by external signals
+	triggers := trigger.New(mgr.GetClient())
+	err = controller.Watch(
+		triggers,
+		&handler.EnqueueRequestForObject{},
+	)
+	panicIf(err)
+	// the signals are POSTed to a separate server listening on
+	// localhost, because the metrics server listens on all the
+	// interfaces without authentication
+	if *triggerAddr != "" {
+		err = mgr.Add(triggers.Serve(*triggerAddr))
+		panicIf(err)
+	}
+	if *triggerFile != "" {
+		err = mgr.Add(triggers.FromFile(*triggerFile, 5*time.Second))
+		panicIf(err)
+	}
+
 	err = mgr.Start(context.Background()) // ❻
 	panicIf(err)
 }
@@ -0,0 +1,136 @@
+package trigger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// maxSignalSize is the maximum size of a signal, in JSON
+const maxSignalSize = 64 * 1024
+
+// sendTimeout is the maximum duration the Handler waits for
+// the source to receive a signal, e.g. while the manager is not
+// the leader. It is a variable to be shortened by the tests.
+var sendTimeout = 10 * time.Second
+
+// FromChannel returns a runnable sending the signals
+// received from ch to the source, until ch is closed
+// or the manager is stopped
+func (o *Source) FromChannel(ch <-chan Signal) manager.Runnable {
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		for {
+			select {
+			case signal, ok := <-ch:
+				if !ok {
+					return nil
+				}
+				if err := o.Send(ctx, signal); err != nil {
+					log.FromContext(ctx).Error(err, "sending the signal", "signal", signal)
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+}
+
+// Handler returns an HTTP handler sending to the source the
+// signals POSTed in JSON. It has no authentication, see Serve.
+// It answers 503 if the source is not started within 10s.
+func (o *Source) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxSignalSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		signal, err := ParseSignal(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), sendTimeout)
+		defer cancel()
+		if err = o.Send(ctx, signal); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// Serve returns a runnable serving the Handler at /trigger on
+// addr, until the manager is stopped. Anyone reaching addr can
+// enqueue the MyResources: it should be bound to localhost,
+// e.g. 127.0.0.1:8082, or be protected by an authenticating
+// proxy.
+func (o *Source) Serve(addr string) manager.Runnable {
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		mux := http.NewServeMux()
+		mux.Handle("/trigger", o.Handler())
+		server := &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+}
+
+// FromFile returns a runnable sending to the source the signal
+// read in JSON from the file at path, each time the content of
+// the file changes, checked every interval. The signal is
+// not sent for the initial content of the file.
+func (o *Source) FromFile(path string, interval time.Duration) manager.Runnable {
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		logger := log.FromContext(ctx).WithValues("path", path)
+		// the content is compared, not the modification time,
+		// as the files of the ConfigMaps mounted in a pod are
+		// updated by replacing a symlink
+		last, _ := os.ReadFile(path)
+		wait.UntilWithContext(ctx, func(ctx context.Context) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					logger.Error(err, "reading the signal file")
+				}
+				return
+			}
+			if bytes.Equal(data, last) {
+				return
+			}
+			last = data
+			signal, err := ParseSignal(data)
+			if err != nil {
+				logger.Error(err, "parsing the signal file")
+				return
+			}
+			if err = o.Send(ctx, signal); err != nil && ctx.Err() == nil {
+				logger.Error(err, "sending the signal", "signal", signal)
+			}
+		}, interval)
+		return nil
+	})
+}
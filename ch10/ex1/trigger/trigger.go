@@ -0,0 +1,216 @@
+// Package trigger provides a controller source turning external
+// signals into reconcile requests for the MyResources selected
+// by the signals, by label or annotation. The signals are sent
+// from Go code, received on an HTTP endpoint, or read from a
+// file when it changes.
+//
+// For example, to reconcile all the MyResources annotated with
+// mygroup.myid.dev/image=nginx when an nginx image is pushed,
+// the registry notifies the endpoint served by the manager on
+// localhost (see Serve):
+//
+//	curl -X POST http://localhost:8082/trigger \
+//		-d '{"annotations": {"mygroup.myid.dev/image": "nginx"}}'
+//
+// A signal must have criteria, or explicitly select all the
+// MyResources with {"all": true}.
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	mygroupv1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+)
+
+// Signal selects the MyResources to reconcile. All the criteria
+// must match. A signal without criteria is invalid, unless All
+// is set to select all the MyResources.
+type Signal struct {
+	// All allows a signal without criteria
+	All bool `json:"all,omitempty"`
+	// Namespace of the MyResources, all the namespaces if empty
+	Namespace string `json:"namespace,omitempty"`
+	// Labels is a label selector, e.g. "app=nginx,tier!=test"
+	Labels string `json:"labels,omitempty"`
+	// Annotations are the annotations the MyResources must
+	// have, with the same values
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ParseSignal decodes a signal from JSON and validates it
+func ParseSignal(data []byte) (Signal, error) {
+	signal := Signal{}
+	if err := json.Unmarshal(data, &signal); err != nil {
+		return Signal{}, fmt.Errorf("decoding the signal: %w", err)
+	}
+	if err := signal.validate(); err != nil {
+		return Signal{}, err
+	}
+	return signal, nil
+}
+
+// ErrNoCriteria is returned for a signal without
+// criteria, when All is not set
+var ErrNoCriteria = errors.New("the signal has no criteria, set all to select all the MyResources")
+
+func (o Signal) validate() error {
+	if o.Namespace == "" && o.Labels == "" && len(o.Annotations) == 0 && !o.All {
+		return ErrNoCriteria
+	}
+	if _, err := labels.Parse(o.Labels); err != nil {
+		return fmt.Errorf("invalid labels: %w", err)
+	}
+	return nil
+}
+
+// ErrStarted is returned when the source is started twice
+var ErrStarted = errors.New("the trigger source is already started")
+
+// Source is a controller source emitting a generic event for each
+// MyResource selected by the signals sent to the source. It can
+// be watched by a single controller.
+type Source struct {
+	reader  client.Reader
+	signals chan Signal
+
+	mu      sync.Mutex
+	started bool
+}
+
+var _ source.Source = &Source{}
+
+// New returns a source listing the MyResources with
+// reader, e.g. the client of the manager
+func New(reader client.Reader) *Source {
+	return &Source{
+		reader:  reader,
+		signals: make(chan Signal),
+	}
+}
+
+// Send sends signal to the source, if valid. It blocks until
+// the signal is received by the started source, or ctx is done.
+func (o *Source) Send(ctx context.Context, signal Signal) error {
+	if err := signal.validate(); err != nil {
+		return err
+	}
+	select {
+	case o.signals <- signal:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Start receives the signals until ctx is done, and passes the
+// events of the selected MyResources to handler, if accepted by
+// the Generic func of all the predicates. It is called by the
+// controller watching the source.
+func (o *Source) Start(
+	ctx context.Context,
+	h handler.EventHandler,
+	queue workqueue.RateLimitingInterface,
+	prct ...predicate.Predicate,
+) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.started {
+		return ErrStarted
+	}
+	o.started = true
+
+	go func() {
+		for {
+			select {
+			case signal := <-o.signals:
+				o.handle(ctx, signal, h, queue, prct)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (o *Source) handle(
+	ctx context.Context,
+	signal Signal,
+	h handler.EventHandler,
+	queue workqueue.RateLimitingInterface,
+	prct []predicate.Predicate,
+) {
+	logger := log.FromContext(ctx).WithValues("signal", signal)
+	myresources, err := o.selected(ctx, signal)
+	if err != nil {
+		logger.Error(err, "listing the myresources selected by the signal")
+		return
+	}
+	logger.Info("signal received", "myresources", len(myresources))
+	for i := range myresources {
+		evt := event.GenericEvent{Object: &myresources[i]}
+		if accepted(evt, prct) {
+			h.Generic(evt, queue)
+		}
+	}
+}
+
+// selected returns the MyResources selected by signal
+func (o *Source) selected(
+	ctx context.Context,
+	signal Signal,
+) ([]mygroupv1alpha1.MyResource, error) {
+	selector, err := labels.Parse(signal.Labels)
+	if err != nil {
+		return nil, err
+	}
+	list := mygroupv1alpha1.MyResourceList{}
+	err = o.reader.List(ctx, &list,
+		client.InNamespace(signal.Namespace),
+		client.MatchingLabelsSelector{Selector: selector},
+	)
+	if err != nil {
+		return nil, err
+	}
+	var myresources []mygroupv1alpha1.MyResource
+	for _, myres := range list.Items {
+		if hasAnnotations(myres.GetAnnotations(), signal.Annotations) {
+			myresources = append(myresources, myres)
+		}
+	}
+	return myresources, nil
+}
+
+func hasAnnotations(annotations map[string]string, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if actual, found := annotations[key]; !found || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+func accepted(evt event.GenericEvent, prct []predicate.Predicate) bool {
+	for _, p := range prct {
+		if !p.Generic(evt) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Source) String() string {
+	return fmt.Sprintf("trigger source: %p", o)
+}
@@ -0,0 +1,241 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	mygroupv1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+)
+
+func myresource(namespace, name string, labels, annotations map[string]string) client.Object {
+	return &mygroupv1alpha1.MyResource{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   namespace,
+		Name:        name,
+		Labels:      labels,
+		Annotations: annotations,
+	}}
+}
+
+// startSource returns a started source listing the
+// MyResources of a fake client, and its queue
+func startSource(t *testing.T, prct ...predicate.Predicate) (*Source, workqueue.RateLimitingInterface) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := mygroupv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		myresource("ns1", "web", map[string]string{"app": "web"},
+			map[string]string{"mygroup.myid.dev/image": "nginx"}),
+		myresource("ns2", "web", map[string]string{"app": "web"},
+			map[string]string{"mygroup.myid.dev/image": "nginx"}),
+		myresource("ns1", "api", map[string]string{"app": "api"},
+			map[string]string{"mygroup.myid.dev/image": "golang"}),
+		myresource("ns1", "test", map[string]string{"app": "web", "tier": "test"}, nil),
+	).Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	t.Cleanup(queue.ShutDown)
+	src := New(c)
+	if err := src.Start(ctx, &handler.EnqueueRequestForObject{}, queue, prct...); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := src.Start(ctx, &handler.EnqueueRequestForObject{}, queue); err != ErrStarted {
+		t.Errorf("second Start() error = %v, want ErrStarted", err)
+	}
+	return src, queue
+}
+
+// requests returns the n requests of queue, sorted
+func requests(t *testing.T, queue workqueue.RateLimitingInterface, n int) []string {
+	t.Helper()
+	var keys []string
+	for i := 0; i < n; i++ {
+		item, shutdown := queue.Get()
+		if shutdown {
+			t.Fatalf("queue shut down")
+		}
+		keys = append(keys, item.(reconcile.Request).String())
+		queue.Done(item)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestSource_Send(t *testing.T) {
+	tests := []struct {
+		name   string
+		signal Signal
+		want   []string
+	}{
+		{
+			name:   "annotation",
+			signal: Signal{Annotations: map[string]string{"mygroup.myid.dev/image": "nginx"}},
+			want:   []string{"ns1/web", "ns2/web"},
+		},
+		{
+			name:   "labels and namespace",
+			signal: Signal{Namespace: "ns1", Labels: "app=web"},
+			want:   []string{"ns1/test", "ns1/web"},
+		},
+		{
+			name:   "labels and annotation",
+			signal: Signal{Labels: "tier!=test", Annotations: map[string]string{"mygroup.myid.dev/image": "golang"}},
+			want:   []string{"ns1/api"},
+		},
+		{
+			name:   "all",
+			signal: Signal{All: true},
+			want:   []string{"ns1/api", "ns1/test", "ns1/web", "ns2/web"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, queue := startSource(t)
+			if err := src.Send(context.Background(), tt.signal); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			got := requests(t, queue, len(tt.want))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("requests = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_Predicates(t *testing.T) {
+	src, queue := startSource(t, predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == "ns2"
+	}))
+	if err := src.Send(context.Background(), Signal{Labels: "app=web"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := requests(t, queue, 1); fmt.Sprint(got) != "[ns2/web]" {
+		t.Errorf("requests = %v, want [ns2/web]", got)
+	}
+	if err := src.Send(context.Background(), Signal{Labels: "app in (web"}); err == nil {
+		t.Errorf("Send() with invalid labels: expected an error")
+	}
+	if err := src.Send(context.Background(), Signal{}); err != ErrNoCriteria {
+		t.Errorf("Send() without criteria error = %v, want ErrNoCriteria", err)
+	}
+}
+
+func TestSource_Handler(t *testing.T) {
+	src, queue := startSource(t)
+	server := httptest.NewServer(src.Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json",
+		strings.NewReader(`{"annotations": {"mygroup.myid.dev/image": "nginx"}}`))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("POST status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	if got := requests(t, queue, 2); fmt.Sprint(got) != "[ns1/web ns2/web]" {
+		t.Errorf("requests = %v, want [ns1/web ns2/web]", got)
+	}
+
+	for body, status := range map[string]int{
+		`{"labels": "app in (web"}`: http.StatusBadRequest,
+		`not json`:                  http.StatusBadRequest,
+		`{}`:                        http.StatusBadRequest,
+	} {
+		resp, err = http.Post(server.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("POST %s status = %d, want %d", body, resp.StatusCode, status)
+		}
+	}
+	resp, err = http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestSource_HandlerNotStarted(t *testing.T) {
+	timeout := sendTimeout
+	sendTimeout = 50 * time.Millisecond
+	defer func() { sendTimeout = timeout }()
+
+	server := httptest.NewServer(New(nil).Handler())
+	defer server.Close()
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"namespace": "ns1"}`))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("POST status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestSource_FromChannel(t *testing.T) {
+	src, queue := startSource(t)
+	ch := make(chan Signal, 1)
+	ch <- Signal{Namespace: "ns2"}
+	close(ch)
+	if err := src.FromChannel(ch).Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if got := requests(t, queue, 1); fmt.Sprint(got) != "[ns2/web]" {
+		t.Errorf("requests = %v, want [ns2/web]", got)
+	}
+}
+
+func TestSource_FromFile(t *testing.T) {
+	src, queue := startSource(t)
+	path := filepath.Join(t.TempDir(), "signal.json")
+	if err := os.WriteFile(path, []byte(`{"namespace": "ns2"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- src.FromFile(path, 10*time.Millisecond).Start(ctx) }()
+
+	// the initial content is not sent
+	time.Sleep(50 * time.Millisecond)
+	if queue.Len() != 0 {
+		t.Errorf("%d requests for the initial content, want 0", queue.Len())
+	}
+	if err := os.WriteFile(path, []byte(`{"labels": "app=api"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests(t, queue, 1); fmt.Sprint(got) != "[ns1/api]" {
+		t.Errorf("requests = %v, want [ns1/api]", got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+}
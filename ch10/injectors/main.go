@@ -7,6 +7,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -35,7 +36,7 @@ func main() {
 		ControllerManagedBy(mgr).
 		For(&mygroupv1alpha1.MyResource{}).
 		Owns(&corev1.Pod{}).
-		Complete(&MyReconciler{})
+		Complete(NewMyReconciler(mgr))
 	panicIf(err)
 
 	err = mgr.Start(context.Background())
@@ -43,9 +44,25 @@ func main() {
 }
 
 type MyReconciler struct {
-	client client.Client
-	cache  cache.Cache
-	scheme *runtime.Scheme
+	client    client.Client
+	apiReader client.Reader
+	cache     cache.Cache
+	scheme    *runtime.Scheme
+	recorder  record.EventRecorder
+}
+
+// NewMyReconciler returns a reconciler using the dependencies
+// provided by mgr, instead of having them injected
+func NewMyReconciler(mgr manager.Manager) *MyReconciler {
+	return &MyReconciler{
+		// reads from the cache, writes to the API Server
+		client: mgr.GetClient(),
+		// reads from the API Server directly
+		apiReader: mgr.GetAPIReader(),
+		cache:     mgr.GetCache(),
+		scheme:    mgr.GetScheme(),
+		recorder:  mgr.GetEventRecorderFor("MyResource"),
+	}
 }
 
 func (a *MyReconciler) Reconcile(
@@ -54,32 +71,13 @@ func (a *MyReconciler) Reconcile(
 ) (reconcile.Result, error) {
 	fmt.Printf("reconcile %v\n", req)
 	fmt.Printf("client: %p\n", a.client)
+	fmt.Printf("apiReader: %p\n", a.apiReader)
 	fmt.Printf("cache: %p\n", a.cache)
 	fmt.Printf("scheme: %p\n", a.scheme)
+	fmt.Printf("recorder: %p\n", a.recorder)
 	return reconcile.Result{}, nil
 }
 
-func (a *MyReconciler) InjectClient(
-	c client.Client,
-) error {
-	a.client = c
-	return nil
-}
-
-func (a *MyReconciler) InjectCache(
-	c cache.Cache,
-) error {
-	a.cache = c
-	return nil
-}
-
-func (a *MyReconciler) InjectScheme(
-	s *runtime.Scheme,
-) error {
-	a.scheme = s
-	return nil
-}
-
 func panicIf(err error) {
 	if err != nil {
 		panic(err)
@@ -2,22 +2,53 @@ package main
 
 import (
 	"context"
+	"flag"
+	"time"
 
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	mygroupv1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
 )
 
+// correlationIDAnnotation is the annotation of the events
+// containing the correlation ID of the reconcile
+const correlationIDAnnotation = "mygroup.myid.dev/correlation-id"
+
 func main() {
-	log.SetLogger(zap.New())
+	production := flag.Bool("production", false, "log with the JSON encoder, at info level, with ISO8601 times")
+	samplingFirst := flag.Int("sampling-first", 0, "log the first logs of each message each second, then sample them, disabled when 0")
+	samplingThereafter := flag.Int("sampling-thereafter", 100, "log 1 of this number of logs of each message, after the first ones")
+	flag.Parse()
+
+	opts := []crzap.Opts{crzap.UseDevMode(!*production)}
+	if *production {
+		// the production logger samples the logs as well,
+		// 100 per message per second, then 1 of 100
+		opts = append(opts, func(o *crzap.Options) {
+			o.TimeEncoder = zapcore.ISO8601TimeEncoder
+		})
+	}
+	if *samplingFirst > 0 {
+		// sample the logs of the messages logged in hot loops
+		opts = append(opts, crzap.RawZapOpts(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(core, time.Second, *samplingFirst, *samplingThereafter)
+		})))
+	}
+	log.SetLogger(crzap.New(opts...))
 	log.Log.Info("starting")
 
 	scheme := runtime.NewScheme()
@@ -36,21 +67,57 @@ func main() {
 		ControllerManagedBy(mgr).
 		For(&mygroupv1alpha1.MyResource{}).
 		Owns(&corev1.Pod{}).
-		Complete(&MyReconciler{})
+		Complete(NewMyReconciler(mgr))
 	panicIf(err)
 
 	err = mgr.Start(context.Background())
 	panicIf(err)
 }
 
-type MyReconciler struct{}
+type MyReconciler struct {
+	client        client.Client
+	EventRecorder record.EventRecorder
+}
+
+// NewMyReconciler returns a reconciler using the
+// client and an event recorder of mgr
+func NewMyReconciler(mgr manager.Manager) *MyReconciler {
+	return &MyReconciler{
+		client:        mgr.GetClient(),
+		EventRecorder: mgr.GetEventRecorderFor("MyResource"),
+	}
+}
 
 func (a *MyReconciler) Reconcile(
 	ctx context.Context,
 	req reconcile.Request,
 ) (reconcile.Result, error) {
-	log := log.FromContext(ctx).WithName("reconcile")
+	myresource := mygroupv1alpha1.MyResource{}
+	err := a.client.Get(ctx, req.NamespacedName, &myresource)
+	if errors.IsNotFound(err) {
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// the reconcileID logged by controller-runtime is not
+	// exposed to the reconciler, a distinct ID is generated
+	// to find the events emitted during this reconcile
+	correlationID := string(uuid.NewUUID())
+	log := log.FromContext(ctx).WithName("reconcile").WithValues(
+		"correlationID", correlationID,
+		"key", req.NamespacedName.String(),
+		"generation", myresource.GetGeneration(),
+		"resourceVersion", myresource.GetResourceVersion(),
+	)
 	log.Info("reconciling")
+
+	a.EventRecorder.AnnotatedEventf(
+		&myresource,
+		map[string]string{correlationIDAnnotation: correlationID},
+		corev1.EventTypeNormal, "Reconcile", "reconciling",
+	)
 	return reconcile.Result{}, nil
 }
 
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -16,6 +17,9 @@ import (
 )
 
 func main() {
+	dryRun := flag.Bool("dry-run", false,
+		"send the writes with the dry-run option, and report the changes instead")
+	flag.Parse()
 	log.SetLogger(zap.New())
 
 	scheme := runtime.NewScheme()
@@ -30,17 +34,11 @@ func main() {
 	)
 	panicIf(err)
 
-	eventRecorder := mgr.GetEventRecorderFor(
-		"MyResource",
-	)
-
 	err = builder.
 		ControllerManagedBy(mgr).
 		For(&mygroupv1alpha1.MyResource{}).
 		Owns(&appsv1.Deployment{}).
-		Complete(&MyReconciler{
-			EventRecorder: eventRecorder,
-		})
+		Complete(NewMyReconciler(mgr, *dryRun))
 	panicIf(err)
 
 	err = mgr.Start(context.Background())
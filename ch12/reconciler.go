@@ -11,6 +11,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	mygroupv1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
@@ -26,13 +27,29 @@ const (
 type MyReconciler struct {
 	client        client.Client
 	EventRecorder record.EventRecorder
+	// DryRun sends the writes with the dry-run option and
+	// skips the status update, logging and emitting as
+	// events the changes which would have been made
+	DryRun bool
 }
 
-func (a *MyReconciler) InjectClient(
-	c client.Client,
-) error {
-	a.client = c
-	return nil
+// NewMyReconciler returns a reconciler using the client
+// and an event recorder of mgr
+func NewMyReconciler(
+	mgr manager.Manager,
+	dryRun bool,
+) *MyReconciler {
+	c := mgr.GetClient()
+	if dryRun {
+		// all the writes are sent with the dry-run option,
+		// including the ones not reported by the reconciler
+		c = client.NewDryRunClient(c)
+	}
+	return &MyReconciler{
+		client:        c,
+		EventRecorder: mgr.GetEventRecorderFor("MyResource"),
+		DryRun:        dryRun,
+	}
 }
 
 func (a *MyReconciler) Reconcile(
@@ -77,6 +94,15 @@ func (a *MyReconciler) Reconcile(
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if a.DryRun {
+		if status.State != myresource.Status.State {
+			log.Info("dry run: state would be updated",
+				"from", myresource.Status.State, "to", status.State)
+			a.EventRecorder.Eventf(&myresource, corev1.EventTypeNormal, "DryRun",
+				"State would be updated from %q to %q", myresource.Status.State, status.State)
+		}
+		return reconcile.Result{}, nil
+	}
 	myresource.Status = *status
 	log.Info("updating status", "state", status.State)
 	err = a.client.Status().Update(ctx, &myresource) // ❻
@@ -93,14 +119,37 @@ func (a *MyReconciler) applyDeployment(
 	ownerref *metav1.OwnerReference,
 ) error {
 	deploy := createDeployment(myres, ownerref)
+	opts := []client.PatchOption{
+		client.FieldOwner(Name),
+		client.ForceOwnership,
+	}
+	live := appsv1.Deployment{}
+	if a.DryRun {
+		err := a.client.Get(ctx, client.ObjectKeyFromObject(deploy), &live)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		opts = append(opts, client.DryRunAll)
+	}
 	err := a.client.Patch( // ❼
 		ctx,
 		deploy,
 		client.Apply,
-		client.FieldOwner(Name),
-		client.ForceOwnership,
+		opts...,
 	)
-	return err
+	if err != nil || !a.DryRun {
+		return err
+	}
+	// the generation of the deployment returned by the dry-run
+	// apply is incremented if the spec would change, and is 1
+	// if the deployment would be created
+	if deploy.GetGeneration() != live.GetGeneration() {
+		log.FromContext(ctx).Info("dry run: deployment would be applied",
+			"deployment", deploy.GetName(), "generation", deploy.GetGeneration())
+		a.EventRecorder.Eventf(myres, corev1.EventTypeNormal, "DryRun",
+			"Deployment %q would be applied", deploy.GetName())
+	}
+	return nil
 }
 
 func createDeployment(
@@ -75,7 +75,7 @@ var _ = BeforeSuite(func() {
 			Named(Name).
 			For(&mygroupv1alpha1.MyResource{}).
 			Owns(&appsv1.Deployment{}).
-			Complete(&MyReconciler{})
+			Complete(NewMyReconciler(mgr, false))
 
 	go func() {
 		defer GinkgoRecover()
@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -28,11 +29,38 @@ import (
 type MyResourceSpec struct {
 	Image  string            `json:"image"`
 	Memory resource.Quantity `json:"memory"`
+	// Replicas is the number of pods to run, 1 by default
+	//+kubebuilder:validation:Minimum=0
+	//+optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Rollout configures the rolling update of the pods
+	//+optional
+	Rollout *RolloutSpec `json:"rollout,omitempty"`
+}
+
+// RolloutSpec configures the rolling update of the pods
+type RolloutSpec struct {
+	// MaxSurge is the maximum number of pods that can be created
+	// over the number of replicas during an update, as an absolute
+	// number or a percentage of replicas rounded up, 25% by default
+	//+optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// MaxUnavailable is the maximum number of pods that can be
+	// unavailable during an update, as an absolute number
+	// or a percentage of replicas rounded down, 25% by default
+	//+optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
 }
 
 // MyResourceStatus defines the observed state of MyResource
 type MyResourceStatus struct {
 	State string `json:"state"`
+	// Conditions are the latest observations of the state
+	// of the resource, e.g. whether its reconciliation is paused
+	//+optional
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 //+kubebuilder:object:root=true
@@ -13,7 +13,15 @@ func (src *MyResource) ConvertTo(
 	// Copy other fields
 	dst.ObjectMeta = src.ObjectMeta
 	dst.Spec.Image = src.Spec.Image
+	dst.Spec.Replicas = src.Spec.Replicas
+	if src.Spec.Rollout != nil {
+		dst.Spec.Rollout = &v1alpha1.RolloutSpec{
+			MaxSurge:       src.Spec.Rollout.MaxSurge,
+			MaxUnavailable: src.Spec.Rollout.MaxUnavailable,
+		}
+	}
 	dst.Status.State = src.Status.State
+	dst.Status.Conditions = src.Status.Conditions
 	return nil
 }
 
@@ -25,6 +33,14 @@ func (dst *MyResource) ConvertFrom(
 	// Copy other fields
 	dst.ObjectMeta = src.ObjectMeta
 	dst.Spec.Image = src.Spec.Image
+	dst.Spec.Replicas = src.Spec.Replicas
+	if src.Spec.Rollout != nil {
+		dst.Spec.Rollout = &RolloutSpec{
+			MaxSurge:       src.Spec.Rollout.MaxSurge,
+			MaxUnavailable: src.Spec.Rollout.MaxUnavailable,
+		}
+	}
 	dst.Status.State = src.Status.State
+	dst.Status.Conditions = src.Status.Conditions
 	return nil
 }
@@ -17,17 +17,136 @@ limitations under the License.
 package v1beta1
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/myid/myresource/pkg/intorpercent"
+	"github.com/myid/myresource/pkg/quantity"
 )
 
 // log is for logging in this package.
 var myresourcelog = logf.Log.WithName("myresource-resource")
 
 func (r *MyResource) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(
+		"/mutate-mygroup-myid-dev-v1beta1-myresource",
+		&webhook.Admission{Handler: &defaulter{}},
+	)
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
 }
 
-// TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+//+kubebuilder:webhook:path=/mutate-mygroup-myid-dev-v1beta1-myresource,mutating=true,failurePolicy=fail,sideEffects=None,groups=mygroup.myid.dev,resources=myresources,verbs=create;update,versions=v1beta1,name=mmyresource.kb.io,admissionReviewVersions=v1
+
+// defaulter normalizes the memory request written in human forms,
+// like "1.5GB" or "1536 MiB", to Kubernetes quantities, before
+// the object is validated against the schema of the CRD.
+// The object is handled as raw JSON, as these forms
+// cannot be decoded into a resource.Quantity.
+type defaulter struct{}
+
+var memoryRequestPath = field.NewPath("spec", "memoryRequest")
+
+func (d *defaulter) Handle(
+	ctx context.Context,
+	req admission.Request,
+) admission.Response {
+	obj := map[string]interface{}{}
+	err := json.Unmarshal(req.Object.Raw, &obj)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	memory, found, err := unstructured.NestedFieldNoCopy(
+		obj, "spec", "memoryRequest",
+	)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	raw, isString := memory.(string)
+	if !found || !isString {
+		// numbers are valid quantities
+		return admission.Allowed("")
+	}
+
+	normalized, err := quantity.Normalize(raw)
+	if err != nil {
+		return admission.Denied(
+			fmt.Sprintf("%s: %v", memoryRequestPath, err),
+		)
+	}
+	if normalized == raw {
+		return admission.Allowed("")
+	}
+
+	myresourcelog.Info("normalizing memory request",
+		"name", req.Name, "namespace", req.Namespace,
+		"from", raw, "to", normalized)
+	err = unstructured.SetNestedField(
+		obj, normalized, "spec", "memoryRequest",
+	)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+//+kubebuilder:webhook:path=/validate-mygroup-myid-dev-v1beta1-myresource,mutating=false,failurePolicy=fail,sideEffects=None,groups=mygroup.myid.dev,resources=myresources,verbs=create;update,versions=v1beta1,name=vmyresource.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &MyResource{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *MyResource) ValidateCreate() error {
+	myresourcelog.Info("validate create", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *MyResource) ValidateUpdate(old runtime.Object) error {
+	myresourcelog.Info("validate update", "name", r.Name)
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *MyResource) ValidateDelete() error {
+	return nil
+}
+
+// validate validates the rollout configuration,
+// as the CRD schema accepts any string
+// for the maxSurge and maxUnavailable fields
+func (r *MyResource) validate() error {
+	rollout := r.Spec.Rollout
+	if rollout == nil {
+		return nil
+	}
+	errs := intorpercent.ValidateRollingUpdate(
+		field.NewPath("spec", "rollout"),
+		rollout.MaxSurge,
+		rollout.MaxUnavailable,
+	)
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(
+		GroupVersion.WithKind("MyResource").GroupKind(),
+		r.Name,
+		errs,
+	)
+}
@@ -0,0 +1,129 @@
+package v1beta1
+
+import (
+	"context"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestDefaulter_Handle(t *testing.T) {
+	tests := []struct {
+		name    string
+		object  string
+		allowed bool
+		patch   string
+	}{
+		{
+			name:    "human form is normalized",
+			object:  `{"spec":{"image":"nginx","memoryRequest":"1.5GB"}}`,
+			allowed: true,
+			patch:   "1500M",
+		},
+		{
+			name:    "binary human form is normalized",
+			object:  `{"spec":{"image":"nginx","memoryRequest":"1536 MiB"}}`,
+			allowed: true,
+			patch:   "1536Mi",
+		},
+		{
+			name:    "canonical form is not modified",
+			object:  `{"spec":{"image":"nginx","memoryRequest":"512Mi"}}`,
+			allowed: true,
+		},
+		{
+			name:    "number is not modified",
+			object:  `{"spec":{"image":"nginx","memoryRequest":1024}}`,
+			allowed: true,
+		},
+		{
+			name:    "missing memory request",
+			object:  `{"spec":{"image":"nginx"}}`,
+			allowed: true,
+		},
+		{
+			name:    "invalid memory request",
+			object:  `{"spec":{"image":"nginx","memoryRequest":"lots"}}`,
+			allowed: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := (&defaulter{}).Handle(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Object: runtime.RawExtension{Raw: []byte(tt.object)},
+				},
+			})
+			if resp.Allowed != tt.allowed {
+				t.Fatalf("allowed = %v, want %v (%v)", resp.Allowed, tt.allowed, resp.Result)
+			}
+			if tt.patch == "" {
+				if len(resp.Patches) != 0 {
+					t.Errorf("patches = %v, want none", resp.Patches)
+				}
+				return
+			}
+			if len(resp.Patches) != 1 ||
+				resp.Patches[0].Path != "/spec/memoryRequest" ||
+				resp.Patches[0].Value != tt.patch {
+				t.Errorf("patches = %v, want /spec/memoryRequest = %s",
+					resp.Patches, tt.patch)
+			}
+		})
+	}
+}
+
+func TestMyResource_ValidateCreate(t *testing.T) {
+	percent := func(s string) *intstr.IntOrString {
+		v := intstr.FromString(s)
+		return &v
+	}
+	tests := []struct {
+		name    string
+		rollout *RolloutSpec
+		wantErr string
+	}{
+		{
+			name: "no rollout",
+		},
+		{
+			name:    "valid rollout",
+			rollout: &RolloutSpec{MaxSurge: percent("150%"), MaxUnavailable: percent("50%")},
+		},
+		{
+			name:    "max unavailable above 100%",
+			rollout: &RolloutSpec{MaxUnavailable: percent("150%")},
+			wantErr: `MyResource.mygroup.myid.dev "myres" is invalid: spec.rollout.maxUnavailable: Invalid value: "150%": must not be greater than 100%`,
+		},
+		{
+			name:    "malformed max surge",
+			rollout: &RolloutSpec{MaxSurge: percent("abc")},
+			wantErr: `MyResource.mygroup.myid.dev "myres" is invalid: spec.rollout.maxSurge: Invalid value: "abc": must be an integer or a percentage, e.g. 1 or 25%`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			myres := &MyResource{
+				ObjectMeta: metav1.ObjectMeta{Name: "myres"},
+				Spec: MyResourceSpec{
+					Image:   "nginx",
+					Rollout: tt.rollout,
+				},
+			}
+			err := myres.ValidateCreate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateCreate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateCreate() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,55 +2,174 @@ package controllers
 
 import (
 	"context"
+	"strings"
 
 	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	"github.com/myid/myresource/pkg/applabels"
+	"github.com/myid/myresource/pkg/intorpercent"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// applyDeployment applies the deployment of myres with a
+// server-side apply, previewed by a dry-run apply without force.
+// The deployment is not applied if the preview shows no change
+// from the live deployment. When the preview is rejected because
+// fields are owned by other field managers, the deployment is
+// applied with force, or, with PreserveOwnership, not applied:
+// the conflict is reported with an event, and the reconcile goes
+// on without error, not to be retried until myres changes.
 func (a *MyResourceReconciler) applyDeployment(
 	ctx context.Context,
 	myres *mygroupv1alpha1.MyResource,
 	ownerref *metav1.OwnerReference,
 ) error {
-	deploy := createDeployment(myres, ownerref)
-	err := a.Client.Patch(
-		ctx,
-		deploy,
-		client.Apply,
-		client.FieldOwner(Name),
-		client.ForceOwnership,
-	)
+	logger := log.FromContext(ctx)
+	live, err := a.liveDeployment(ctx, myres)
+	if err != nil {
+		return err
+	}
+	if conflicts := recommendedLabels(myres).Conflicts(myres.GetLabels()); len(conflicts) > 0 {
+		// the recommended labels identify the deployment,
+		// the labels of myres can't override them
+		logger.Info("labels conflicting with the recommended labels, not applied",
+			"labels", conflicts)
+		a.recordEvent(ctx, myres, corev1.EventTypeWarning, "LabelConflict",
+			"Labels %s conflict with the recommended labels, not applied", strings.Join(conflicts, ", "))
+	}
+	deploy, err := createDeployment(myres, ownerref, live)
+	if err != nil {
+		return err
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(Name)}
+	preview := deploy.DeepCopy()
+	err = a.Client.Patch(ctx, preview, client.Apply, append(opts, client.DryRunAll)...)
+	if apierrors.IsConflict(err) {
+		// the message of the error lists the fields
+		// in conflict, and their managers
+		if a.PreserveOwnership {
+			logger.Info("deployment fields owned by other managers, not applied",
+				"deployment", deploy.GetName(), "conflicts", err.Error())
+			a.recordEvent(ctx, myres, corev1.EventTypeWarning, "OwnershipConflict",
+				"Deployment %q not applied: %v", deploy.GetName(), err)
+			return nil
+		}
+		logger.Info("taking the ownership of deployment fields owned by other managers",
+			"deployment", deploy.GetName(), "conflicts", err.Error())
+		opts = append(opts, client.ForceOwnership)
+		preview = deploy.DeepCopy()
+		err = a.Client.Patch(ctx, preview, client.Apply, append(opts, client.DryRunAll)...)
+	}
+	if err != nil {
+		return err
+	}
+
+	change := deploymentChange(live, preview)
+	if change == "" {
+		return nil
+	}
+	if a.DryRun {
+		logger.Info("dry run: deployment would be "+change, "deployment", deploy.GetName())
+		a.recordEvent(ctx, myres, corev1.EventTypeNormal, "DryRun",
+			"Deployment %q would be %s", deploy.GetName(), change)
+		return nil
+	}
 	//generation := deploy.GetGeneration()
 	//if generation == 1 {
 	//	a.EventRecorder.Eventf(myres, corev1.EventTypeNormal, "DeploymentCreated", "The deployment %q has been created", deploy.GetName())
 	//}
-	return err
+	return a.Client.Patch(ctx, deploy, client.Apply, opts...)
 }
 
+// liveDeployment returns the deployment of myres
+// in the cluster, or nil if it does not exist
+func (a *MyResourceReconciler) liveDeployment(
+	ctx context.Context,
+	myres *mygroupv1alpha1.MyResource,
+) (*appsv1.Deployment, error) {
+	live := &appsv1.Deployment{}
+	key := client.ObjectKey{Namespace: myres.GetNamespace(), Name: deploymentName(myres)}
+	err := a.Client.Get(ctx, key, live)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return live, err
+}
+
+// deploymentChange returns the change of the live deployment made
+// by the apply previewed by preview, or "" if it changes nothing
+func deploymentChange(live, preview *appsv1.Deployment) string {
+	switch {
+	case live == nil:
+		return "created"
+	case !equality.Semantic.DeepEqual(preview.Spec, live.Spec):
+		return "updated"
+	case !equality.Semantic.DeepEqual(preview.GetLabels(), live.GetLabels()) ||
+		!equality.Semantic.DeepEqual(preview.GetOwnerReferences(), live.GetOwnerReferences()):
+		return "updated (metadata only)"
+	}
+	return ""
+}
+
+// legacySelectorKey is the label selecting the pods of the
+// deployments created before the recommended labels were used
+const legacySelectorKey = "myresource"
+
+// createDeployment returns the deployment of myres, labeled with
+// the recommended labels and the labels of myres not conflicting
+// with them. The pods are labeled with the recommended labels
+// only, so the changes of the labels of myres do not restart them.
+//
+// The selector of a deployment is immutable: live, the existing
+// deployment if any, keeps its legacy selector `myresource: <name>`
+// if it has been created with it. Its pods are labeled with the
+// legacy label in addition to the recommended labels, so they are
+// rolled out once after the upgrade of the controller.
 func createDeployment(
 	myres *mygroupv1alpha1.MyResource,
 	ownerref *metav1.OwnerReference,
-) *appsv1.Deployment {
+	live *appsv1.Deployment,
+) (*appsv1.Deployment, error) {
+	recommended := recommendedLabels(myres)
+	user := map[string]string{}
+	for key, value := range myres.GetLabels() {
+		user[key] = value
+	}
+	for _, key := range recommended.Conflicts(user) {
+		delete(user, key)
+	}
+	labels, err := recommended.Merge(user)
+	if err != nil {
+		return nil, err
+	}
+	selector := recommended.Selector()
+	podLabels := recommended.Labels()
+	if hasLegacySelector(myres, live) {
+		selector = live.Spec.Selector
+		podLabels[legacySelectorKey] = myres.GetName()
+	}
+	strategy, err := deploymentStrategy(myres)
+	if err != nil {
+		return nil, err
+	}
 	deploy := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels: map[string]string{
-				"myresource": myres.GetName(),
-			},
+			Labels: labels,
 		},
 		Spec: appsv1.DeploymentSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"myresource": myres.GetName(),
-				},
-			},
+			Replicas: myres.Spec.Replicas,
+			Strategy: strategy,
+			Selector: selector,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"myresource": myres.GetName(),
-					},
+					Labels: podLabels,
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -68,7 +187,7 @@ func createDeployment(
 			},
 		},
 	}
-	deploy.SetName(myres.GetName() + "-deployment")
+	deploy.SetName(deploymentName(myres))
 	deploy.SetNamespace(myres.GetNamespace())
 	deploy.SetGroupVersionKind(
 		appsv1.SchemeGroupVersion.WithKind("Deployment"),
@@ -76,5 +195,64 @@ func createDeployment(
 	deploy.SetOwnerReferences([]metav1.OwnerReference{
 		*ownerref,
 	})
-	return deploy
+	return deploy, nil
+}
+
+func deploymentName(myres *mygroupv1alpha1.MyResource) string {
+	return myres.GetName() + "-deployment"
+}
+
+// hasLegacySelector returns true if live is the deployment
+// of myres, created with the legacy selector
+func hasLegacySelector(
+	myres *mygroupv1alpha1.MyResource,
+	live *appsv1.Deployment,
+) bool {
+	if live == nil || live.Spec.Selector == nil {
+		return false
+	}
+	selector := live.Spec.Selector
+	return len(selector.MatchExpressions) == 0 &&
+		len(selector.MatchLabels) == 1 &&
+		selector.MatchLabels[legacySelectorKey] == myres.GetName()
+}
+
+// deploymentStrategy returns the rolling update strategy
+// configured in the rollout of myres. The values are validated
+// by the webhook, they are validated again here
+// in case the webhook is not deployed.
+func deploymentStrategy(
+	myres *mygroupv1alpha1.MyResource,
+) (appsv1.DeploymentStrategy, error) {
+	rollout := myres.Spec.Rollout
+	if rollout == nil {
+		return appsv1.DeploymentStrategy{}, nil
+	}
+	errs := intorpercent.ValidateRollingUpdate(
+		field.NewPath("spec", "rollout"),
+		rollout.MaxSurge,
+		rollout.MaxUnavailable,
+	)
+	if len(errs) > 0 {
+		return appsv1.DeploymentStrategy{}, errs.ToAggregate()
+	}
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       rollout.MaxSurge,
+			MaxUnavailable: rollout.MaxUnavailable,
+		},
+	}, nil
+}
+
+// recommendedLabels returns the recommended labels
+// of the resources created for myres
+func recommendedLabels(
+	myres *mygroupv1alpha1.MyResource,
+) applabels.RecommendedLabels {
+	return applabels.RecommendedLabels{
+		Name:      "myresource",
+		Instance:  myres.GetName(),
+		ManagedBy: Name,
+	}
 }
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	"github.com/myid/myresource/pkg/applabels"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("createDeployment", func() {
+	var (
+		myres    mygroupv1alpha1.MyResource
+		ownerref *metav1.OwnerReference
+	)
+
+	BeforeEach(func() {
+		myres = mygroupv1alpha1.MyResource{}
+		myres.SetName("myres")
+		myres.SetNamespace("default")
+		myres.SetLabels(map[string]string{
+			"team":                "a-team",
+			applabels.InstanceKey: "another",
+		})
+		ownerref = metav1.NewControllerRef(
+			&myres,
+			mygroupv1alpha1.GroupVersion.WithKind("MyResource"),
+		)
+	})
+
+	It("should label the pods with the recommended labels only", func() {
+		deploy, err := createDeployment(&myres, ownerref, nil)
+		Expect(err).NotTo(HaveOccurred())
+		recommended := recommendedLabels(&myres)
+		Expect(deploy.Spec.Template.GetLabels()).To(Equal(recommended.Labels()))
+		Expect(deploy.Spec.Selector).To(Equal(recommended.Selector()))
+	})
+
+	It("should drop the labels conflicting with the recommended labels", func() {
+		deploy, err := createDeployment(&myres, ownerref, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deploy.GetLabels()).To(HaveKeyWithValue("team", "a-team"))
+		Expect(deploy.GetLabels()).To(HaveKeyWithValue(applabels.InstanceKey, "myres"))
+	})
+
+	It("should keep the legacy selector of an existing deployment", func() {
+		legacy := &metav1.LabelSelector{
+			MatchLabels: map[string]string{legacySelectorKey: "myres"},
+		}
+		live := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Selector: legacy}}
+		deploy, err := createDeployment(&myres, ownerref, live)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deploy.Spec.Selector).To(Equal(legacy))
+		Expect(deploy.Spec.Template.GetLabels()).To(HaveKeyWithValue(legacySelectorKey, "myres"))
+		Expect(deploy.Spec.Template.GetLabels()).To(HaveKeyWithValue(applabels.InstanceKey, "myres"))
+	})
+})
+
+var _ = Describe("deploymentChange", func() {
+	var live *appsv1.Deployment
+
+	BeforeEach(func() {
+		live = &appsv1.Deployment{}
+		live.SetLabels(map[string]string{"team": "a-team"})
+		live.Spec.Replicas = pointer.Int32(1)
+	})
+
+	It("should report a new deployment as created", func() {
+		Expect(deploymentChange(nil, live)).To(Equal("created"))
+	})
+
+	It("should report no change when the preview equals the live deployment", func() {
+		Expect(deploymentChange(live, live.DeepCopy())).To(BeEmpty())
+	})
+
+	It("should report the changes of the spec and of the metadata", func() {
+		preview := live.DeepCopy()
+		preview.Spec.Replicas = pointer.Int32(2)
+		Expect(deploymentChange(live, preview)).To(Equal("updated"))
+		preview = live.DeepCopy()
+		preview.SetLabels(nil)
+		Expect(deploymentChange(live, preview)).To(Equal("updated (metadata only)"))
+	})
+})
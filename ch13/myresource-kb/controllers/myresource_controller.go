@@ -19,15 +19,25 @@ package controllers
 import (
 	"context"
 
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	"github.com/myid/myresource/pkg/reconcilelog"
+	"github.com/myid/myresource/pkg/tracing"
 )
 
 const (
@@ -38,12 +48,31 @@ const (
 type MyResourceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// PreserveOwnership does not apply the deployments whose
+	// preview shows fields owned by other field managers,
+	// instead of forcing their ownership: the fields in
+	// conflict are logged and emitted as an event
+	PreserveOwnership bool
+	// DryRun sends the writes with the dry-run option and
+	// skips the status update, logging and emitting as
+	// events the changes which would have been made
+	DryRun        bool
+	EventRecorder record.EventRecorder
+	// PauseConfigMap is the ConfigMap pausing the reconciliation
+	// of all the MyResources when its PauseConfigMapKey is "true",
+	// the cluster-wide pause is disabled when the name is empty
+	PauseConfigMap types.NamespacedName
+	// Tracer starts a Reconcile span for each reconcile,
+	// the reconciles are not traced when nil
+	Tracer trace.Tracer
 }
 
 //+kubebuilder:rbac:groups=mygroup.myid.dev,resources=myresources,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=mygroup.myid.dev,resources=myresources/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=mygroup.myid.dev,resources=myresources/finalizers,verbs=update
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -72,21 +101,43 @@ func (r *MyResourceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 		return reconcile.Result{}, err
 	}
+	ctx, log = reconcilelog.WithObject(ctx, &myresource)
 
 	ownerReference := metav1.NewControllerRef(
 		&myresource,
 		mygroupv1alpha1.GroupVersion.WithKind("MyResource"),
 	)
 
-	err = r.applyDeployment(ctx, &myresource, ownerReference)
+	pauseReason, err := r.pauseReason(ctx, &myresource)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if pauseReason == "" {
+		err = r.applyDeployment(ctx, &myresource, ownerReference)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	} else {
+		log.Info("reconciliation paused, deployment not applied", "reason", pauseReason)
+	}
 
 	status, err := r.computeStatus(ctx, &myresource)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	// the conditions are copied, not to update the ones of myresource
+	// and keep the transition time of the unchanged conditions
+	status.Conditions = append([]metav1.Condition(nil), myresource.Status.Conditions...)
+	meta.SetStatusCondition(&status.Conditions, pausedCondition(&myresource, pauseReason))
+	if r.DryRun {
+		if !equality.Semantic.DeepEqual(myresource.Status, *status) {
+			log.Info("dry run: status would be updated",
+				"from", myresource.Status.State, "to", status.State)
+			r.recordEvent(ctx, &myresource, corev1.EventTypeNormal, "DryRun",
+				"State would be updated from %q to %q", myresource.Status.State, status.State)
+		}
+		return ctrl.Result{}, nil
+	}
 	myresource.Status = *status
 	log.Info("updating status", "state", status.State)
 	err = r.Client.Status().Update(ctx, &myresource)
@@ -99,7 +150,30 @@ func (r *MyResourceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MyResourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&mygroupv1alpha1.MyResource{}).
-		Complete(r)
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&mygroupv1alpha1.MyResource{})
+	if r.PauseConfigMap.Name != "" {
+		builder = builder.Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.myresourcesForPause),
+		)
+	}
+	if r.Tracer != nil {
+		return builder.Complete(tracing.Reconciler(r, r.Tracer))
+	}
+	return builder.Complete(r)
+}
+
+// recordEvent emits an event for myres, if the
+// reconciler has an EventRecorder
+func (r *MyResourceReconciler) recordEvent(
+	ctx context.Context,
+	myres *mygroupv1alpha1.MyResource,
+	eventtype, reason, messageFmt string,
+	args ...interface{},
+) {
+	if r.EventRecorder == nil {
+		return
+	}
+	reconcilelog.Eventf(ctx, r.EventRecorder, myres, eventtype, reason, messageFmt, args...)
 }
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// PausedAnnotation pauses the reconciliation of
+	// a MyResource when its value is "true"
+	PausedAnnotation = "mygroup.myid.dev/paused"
+	// PauseConfigMapKey pauses the reconciliation of all
+	// the MyResources when its value in the pause
+	// ConfigMap of the reconciler is "true"
+	PauseConfigMapKey = "paused"
+	// PausedCondition is the type of the condition
+	// indicating whether the reconciliation is paused
+	PausedCondition = "Paused"
+
+	_pausedByAnnotationReason = "PausedByAnnotation"
+	_pausedByConfigMapReason  = "PausedByConfigMap"
+	_notPausedReason          = "NotPaused"
+)
+
+// pauseReason returns the reason why the reconciliation of myres
+// is paused, i.e. the deployment is not applied, or an empty
+// string if the reconciliation is not paused
+func (a *MyResourceReconciler) pauseReason(
+	ctx context.Context,
+	myres *mygroupv1alpha1.MyResource,
+) (string, error) {
+	if myres.GetAnnotations()[PausedAnnotation] == "true" {
+		return _pausedByAnnotationReason, nil
+	}
+	if a.PauseConfigMap.Name == "" {
+		return "", nil
+	}
+	cm := corev1.ConfigMap{}
+	err := a.Client.Get(ctx, a.PauseConfigMap, &cm)
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if cm.Data[PauseConfigMapKey] == "true" {
+		return _pausedByConfigMapReason, nil
+	}
+	return "", nil
+}
+
+// pausedCondition returns the Paused condition
+// of myres, for the reason returned by pauseReason
+func pausedCondition(
+	myres *mygroupv1alpha1.MyResource,
+	reason string,
+) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               PausedCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: myres.GetGeneration(),
+		Reason:             reason,
+	}
+	switch reason {
+	case _pausedByAnnotationReason:
+		condition.Message = "The reconciliation is paused by the " + PausedAnnotation + " annotation"
+	case _pausedByConfigMapReason:
+		condition.Message = "The reconciliation of all the resources is paused by the pause ConfigMap"
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = _notPausedReason
+		condition.Message = "The reconciliation is not paused"
+	}
+	return condition
+}
+
+// myresourcesForPause returns the requests to reconcile all the
+// MyResources, when the pause ConfigMap of the reconciler changes
+func (a *MyResourceReconciler) myresourcesForPause(obj client.Object) []reconcile.Request {
+	if client.ObjectKeyFromObject(obj) != a.PauseConfigMap {
+		return nil
+	}
+	ctx := context.Background()
+	list := mygroupv1alpha1.MyResourceList{}
+	if err := a.Client.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "listing myresources for the pause configmap")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, myres := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: myres.GetNamespace(),
+				Name:      myres.GetName(),
+			},
+		})
+	}
+	return requests
+}
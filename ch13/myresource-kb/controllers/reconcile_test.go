@@ -8,6 +8,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -88,6 +89,65 @@ var _ = Describe("MyResource controller", func() {
 			})
 		})
 	})
+
+	When("When creating a paused MyResource instance", func() {
+
+		var (
+			myres      mygroupv1alpha1.MyResource
+			name       string
+			namespace  = "default"
+			deployName string
+		)
+
+		BeforeEach(func() {
+			myres = mygroupv1alpha1.MyResource{
+				Spec: mygroupv1alpha1.MyResourceSpec{
+					Image: "myimage",
+				},
+			}
+			name = fmt.Sprintf("myres%d", rand.Intn(1000))
+			myres.SetName(name)
+			myres.SetNamespace(namespace)
+			myres.SetAnnotations(map[string]string{PausedAnnotation: "true"})
+			err := k8sClient.Create(ctx, &myres)
+			Expect(err).NotTo(HaveOccurred())
+			deployName = fmt.Sprintf("%s-deployment", name)
+		})
+
+		AfterEach(func() {
+			k8sClient.Delete(ctx, &myres)
+		})
+
+		It("should set the Paused condition", func() {
+			Eventually(getMyResourcePaused(name, namespace), 10, 1).
+				Should(Equal(metav1.ConditionTrue))
+		})
+
+		It("should not create a deployment", func() {
+			var dep appsv1.Deployment
+			Consistently(deploymentExists(deployName, namespace, &dep), 3, 1).
+				Should(BeFalse())
+		})
+
+		When("the annotation is removed", func() {
+			BeforeEach(func() {
+				Eventually(getMyResourcePaused(name, namespace), 10, 1).
+					Should(Equal(metav1.ConditionTrue))
+				patch := client.MergeFrom(myres.DeepCopy())
+				myres.SetAnnotations(nil)
+				err := k8sClient.Patch(ctx, &myres, patch)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should create a deployment", func() {
+				var dep appsv1.Deployment
+				Eventually(deploymentExists(deployName, namespace, &dep), 10, 1).
+					Should(BeTrue())
+				Eventually(getMyResourcePaused(name, namespace), 10, 1).
+					Should(Equal(metav1.ConditionFalse))
+			})
+		})
+	})
 })
 
 func deploymentExists(name, namespace string, dep *appsv1.Deployment) func() bool {
@@ -113,3 +173,21 @@ func getMyResourceState(name, namespace string) func() (string, error) {
 		return myres.Status.State, nil
 	}
 }
+
+func getMyResourcePaused(name, namespace string) func() (metav1.ConditionStatus, error) {
+	return func() (metav1.ConditionStatus, error) {
+		myres := mygroupv1alpha1.MyResource{}
+		err := k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: namespace,
+			Name:      name,
+		}, &myres)
+		if err != nil {
+			return "", err
+		}
+		condition := meta.FindStatusCondition(myres.Status.Conditions, PausedCondition)
+		if condition == nil {
+			return "", nil
+		}
+		return condition.Status, nil
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 
 	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	"github.com/myid/myresource/pkg/intorpercent"
 	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -30,9 +33,7 @@ func (a *MyResourceReconciler) computeStatus(
 		ctx,
 		&deployList,
 		client.InNamespace(myres.GetNamespace()),
-		client.MatchingLabels{
-			"myresource": myres.GetName(),
-		},
+		client.MatchingLabels(recommendedLabels(myres).SelectorLabels()),
 	)
 	if err != nil {
 		return nil, err
@@ -52,9 +53,39 @@ func (a *MyResourceReconciler) computeStatus(
 
 	status := deployList.Items[0].Status
 	logger.Info("got deployment status", "status", status)
-	if status.ReadyReplicas == 1 {
+	minReady, err := minReadyReplicas(myres)
+	if err != nil {
+		return nil, err
+	}
+	if status.ReadyReplicas >= minReady {
 		result.State = _readyState
 	}
 
 	return &result, nil
 }
+
+// minReadyReplicas returns the minimum number of ready replicas
+// for myres to be Ready: the number of replicas minus the
+// maximum number of unavailable replicas during a rollout,
+// as done by the deployment controller to consider
+// a Deployment available
+func minReadyReplicas(
+	myres *mygroupv1alpha1.MyResource,
+) (int32, error) {
+	replicas := pointer.Int32Deref(myres.Spec.Replicas, 1)
+	var maxSurge, maxUnavailable *intstr.IntOrString
+	if rollout := myres.Spec.Rollout; rollout != nil {
+		maxSurge = rollout.MaxSurge
+		maxUnavailable = rollout.MaxUnavailable
+	}
+	_, unavailable, err := intorpercent.ResolveRollingUpdate(
+		maxSurge, maxUnavailable, replicas,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if unavailable > replicas {
+		return 0, nil
+	}
+	return replicas - unavailable, nil
+}
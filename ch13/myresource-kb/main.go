@@ -17,23 +17,39 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
 	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
 	mygroupv1beta1 "github.com/myid/myresource/api/v1beta1"
 	"github.com/myid/myresource/controllers"
+	"github.com/myid/myresource/pkg/reconcilelog"
+	"github.com/myid/myresource/pkg/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -54,18 +70,65 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var preserveOwnership bool
+	var dryRun bool
+	var pauseConfigMap string
+	var logProduction bool
+	var logSamplingFirst, logSamplingThereafter int
+	var traceFile string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&preserveOwnership, "preserve-ownership", false,
+		"Do not take the ownership of the deployment fields owned by other field managers.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Send the writes with the dry-run option, and report the changes as logs and events instead.")
+	flag.StringVar(&pauseConfigMap, "pause-configmap", "",
+		"The namespace/name of the ConfigMap pausing the reconciliation of all the resources "+
+			"when its \""+controllers.PauseConfigMapKey+"\" key is \"true\". Disabled when empty.")
+	flag.BoolVar(&logProduction, "log-production", false,
+		"Use the production logger: JSON encoder, info level and ISO8601 times. "+
+			"Overrides --zap-devel and --zap-time-encoding.")
+	flag.IntVar(&logSamplingFirst, "log-sampling-first", 0,
+		"Sample the logs of each message: log the first ones each second, then 1 of --log-sampling-thereafter. "+
+			"Disabled when 0.")
+	flag.IntVar(&logSamplingThereafter, "log-sampling-thereafter", 100,
+		"The sampling rate of the logs of each message, after the first ones each second.")
+	flag.StringVar(&traceFile, "trace-file", "",
+		"The file the traces of the reconciles, API calls and conversions are written to, as JSON lines. "+
+			"Tracing is disabled when empty.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	if logProduction {
+		reconcilelog.Production(&opts)
+	}
+	if logSamplingFirst > 0 {
+		if opts.Level != nil && opts.Level.Enabled(zapcore.Level(-2)) {
+			// the zap sampler does not support these levels
+			setupLog.Error(nil, "log sampling is not supported with levels more verbose than debug")
+			os.Exit(1)
+		}
+		ctrl.SetLogger(reconcilelog.NewSampled(time.Second, logSamplingFirst, logSamplingThereafter,
+			zap.UseFlagOptions(&opts)))
+	} else {
+		ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	}
+
+	var pauseKey types.NamespacedName
+	if pauseConfigMap != "" {
+		namespace, name, found := strings.Cut(pauseConfigMap, "/")
+		if !found || namespace == "" || name == "" {
+			setupLog.Error(nil, "invalid pause configmap, expected namespace/name", "pause-configmap", pauseConfigMap)
+			os.Exit(1)
+		}
+		pauseKey = types.NamespacedName{Namespace: namespace, Name: name}
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
@@ -74,6 +137,18 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "ab35bae8.myid.dev",
+		// only the pause ConfigMap is read by the reconciler,
+		// the other ConfigMaps are not cached
+		NewCache: cache.BuilderWithOptions(cache.Options{
+			SelectorsByObject: cache.SelectorsByObject{
+				&corev1.ConfigMap{}: {
+					Field: fields.SelectorFromSet(fields.Set{
+						"metadata.namespace": pauseKey.Namespace,
+						"metadata.name":      pauseKey.Name,
+					}),
+				},
+			},
+		}),
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -91,31 +166,84 @@ func main() {
 		os.Exit(1)
 	}
 
+	var tracerProvider trace.TracerProvider = trace.NewNoopTracerProvider()
+	// flushTraces exports the spans still batched, it is called
+	// before exiting as os.Exit does not run the deferred calls
+	flushTraces := func() {}
+	if traceFile != "" {
+		f, err := os.Create(traceFile)
+		if err != nil {
+			setupLog.Error(err, "unable to create the trace file")
+			os.Exit(1)
+		}
+		sdkProvider := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(tracing.NewFileExporter(f)),
+			sdktrace.WithResource(resource.NewSchemaless(
+				attribute.String("service.name", controllers.Name),
+			)),
+		)
+		flushTraces = func() {
+			if err := sdkProvider.Shutdown(context.Background()); err != nil {
+				setupLog.Error(err, "unable to export the traces")
+			}
+		}
+		tracerProvider = sdkProvider
+	}
+	tracer := tracerProvider.Tracer(tracing.TracerName)
+
+	reconcilerClient := tracing.Client(mgr.GetClient(), tracer)
+	if dryRun {
+		// all the writes are sent with the dry-run option,
+		// including the ones not reported by the reconciler
+		reconcilerClient = client.NewDryRunClient(reconcilerClient)
+	}
 	if err = (&controllers.MyResourceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:            reconcilerClient,
+		Scheme:            mgr.GetScheme(),
+		PreserveOwnership: preserveOwnership,
+		DryRun:            dryRun,
+		EventRecorder:     mgr.GetEventRecorderFor(controllers.Name),
+		PauseConfigMap:    pauseKey,
+		Tracer:            tracer,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MyResource")
+		flushTraces()
+		os.Exit(1)
+	}
+	// registered before the webhooks, the builder
+	// does not register its own conversion webhook
+	convertWebhook := &conversion.Webhook{}
+	// the conversion.Webhook of controller-runtime 0.13 has no constructor
+	// taking a scheme, InjectScheme is the only way to set it
+	if err = convertWebhook.InjectScheme(mgr.GetScheme()); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "conversion")
+		flushTraces()
 		os.Exit(1)
 	}
+	mgr.GetWebhookServer().Register("/convert", tracing.ConversionHandler(convertWebhook, tracer))
 	if err = (&mygroupv1beta1.MyResource{}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "MyResource")
+		flushTraces()
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
+		flushTraces()
 		os.Exit(1)
 	}
 	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
+		flushTraces()
 		os.Exit(1)
 	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
+		flushTraces()
 		os.Exit(1)
 	}
+	flushTraces()
 }
@@ -0,0 +1,172 @@
+// Package applabels manages the recommended labels
+// app.kubernetes.io/* shared by all the tools operating
+// on an application.
+package applabels
+
+import (
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	NameKey      = "app.kubernetes.io/name"
+	InstanceKey  = "app.kubernetes.io/instance"
+	VersionKey   = "app.kubernetes.io/version"
+	ComponentKey = "app.kubernetes.io/component"
+	PartOfKey    = "app.kubernetes.io/part-of"
+	ManagedByKey = "app.kubernetes.io/managed-by"
+)
+
+// RecommendedLabels contains the values of the recommended labels.
+// Name and Instance are mandatory, other values are optional
+// and omitted from the labels when empty.
+type RecommendedLabels struct {
+	// Name of the application, e.g. "mysql"
+	Name string
+	// Instance is a unique name identifying the instance of the application
+	Instance string
+	// Version of the application, e.g. "5.7.21"
+	Version string
+	// Component within the architecture, e.g. "database"
+	Component string
+	// PartOf is the name of a higher level application this one is part of
+	PartOf string
+	// ManagedBy is the tool used to manage the operation of the application
+	ManagedBy string
+}
+
+// Validate checks that the mandatory values are set
+// and that all values are valid label values
+func (o RecommendedLabels) Validate() error {
+	var errs field.ErrorList
+	for _, key := range []string{NameKey, InstanceKey} {
+		if o.value(key) == "" {
+			errs = append(errs, field.Required(field.NewPath(key), ""))
+		}
+	}
+	errs = append(errs, validateLabels(o.Labels())...)
+	return errs.ToAggregate()
+}
+
+// Labels returns the non-empty recommended labels
+func (o RecommendedLabels) Labels() map[string]string {
+	result := map[string]string{}
+	for _, key := range keys {
+		if v := o.value(key); v != "" {
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// SelectorLabels returns the minimal set of labels identifying
+// the instance of the application. These labels never change during
+// the life of the instance, so they can be used in immutable
+// selectors, like the selector of a Deployment.
+func (o RecommendedLabels) SelectorLabels() map[string]string {
+	return map[string]string{
+		NameKey:     o.Name,
+		InstanceKey: o.Instance,
+	}
+}
+
+// Selector returns a LabelSelector matching the SelectorLabels
+func (o RecommendedLabels) Selector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: o.SelectorLabels(),
+	}
+}
+
+// Merge returns the recommended labels merged with the user labels.
+// The user labels are validated, and an error is returned if a user label
+// sets a recommended label to a different value.
+func (o RecommendedLabels) Merge(user map[string]string) (map[string]string, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	result := o.Labels()
+
+	errs := validateLabels(user)
+	for _, key := range sortedKeys(user) {
+		value := user[key]
+		if existing, found := result[key]; found && existing != value {
+			errs = append(errs, field.Invalid(
+				field.NewPath(key),
+				value,
+				fmt.Sprintf("conflicts with recommended value %q", existing),
+			))
+			continue
+		}
+		result[key] = value
+	}
+	if len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+	return result, nil
+}
+
+// Conflicts returns the sorted keys of the user labels
+// setting a recommended label to a different value
+func (o RecommendedLabels) Conflicts(user map[string]string) []string {
+	var result []string
+	for _, key := range sortedKeys(user) {
+		if value := o.value(key); value != "" && value != user[key] {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+var keys = []string{
+	NameKey,
+	InstanceKey,
+	VersionKey,
+	ComponentKey,
+	PartOfKey,
+	ManagedByKey,
+}
+
+func (o RecommendedLabels) value(key string) string {
+	switch key {
+	case NameKey:
+		return o.Name
+	case InstanceKey:
+		return o.Instance
+	case VersionKey:
+		return o.Version
+	case ComponentKey:
+		return o.Component
+	case PartOfKey:
+		return o.PartOf
+	case ManagedByKey:
+		return o.ManagedBy
+	}
+	return ""
+}
+
+func validateLabels(labels map[string]string) field.ErrorList {
+	var errs field.ErrorList
+	for _, key := range sortedKeys(labels) {
+		path := field.NewPath(key)
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, field.Invalid(path, key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(labels[key]) {
+			errs = append(errs, field.Invalid(path, labels[key], msg))
+		}
+	}
+	return errs
+}
+
+func sortedKeys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
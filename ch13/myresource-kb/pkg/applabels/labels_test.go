@@ -0,0 +1,169 @@
+package applabels
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecommendedLabels_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels RecommendedLabels
+		err    bool
+	}{
+		{
+			name:   "valid",
+			labels: RecommendedLabels{Name: "myapp", Instance: "myapp-1", Version: "1.2.3"},
+		},
+		{
+			name:   "missing name",
+			labels: RecommendedLabels{Instance: "myapp-1"},
+			err:    true,
+		},
+		{
+			name:   "missing instance",
+			labels: RecommendedLabels{Name: "myapp"},
+			err:    true,
+		},
+		{
+			name:   "invalid characters",
+			labels: RecommendedLabels{Name: "my app", Instance: "myapp-1"},
+			err:    true,
+		},
+		{
+			name:   "value too long",
+			labels: RecommendedLabels{Name: "myapp", Instance: strings.Repeat("a", 64)},
+			err:    true,
+		},
+		{
+			name:   "value must start with an alphanumeric character",
+			labels: RecommendedLabels{Name: "myapp", Instance: "myapp", PartOf: "-app"},
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.labels.Validate()
+			if (err != nil) != tt.err {
+				t.Errorf("Validate() = %v, want error: %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRecommendedLabels_Labels(t *testing.T) {
+	labels := RecommendedLabels{
+		Name:      "myapp",
+		Instance:  "myapp-1",
+		Component: "frontend",
+	}
+	want := map[string]string{
+		NameKey:      "myapp",
+		InstanceKey:  "myapp-1",
+		ComponentKey: "frontend",
+	}
+	if got := labels.Labels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Labels() = %v, want %v", got, want)
+	}
+
+	wantSelector := map[string]string{
+		NameKey:     "myapp",
+		InstanceKey: "myapp-1",
+	}
+	if got := labels.Selector().MatchLabels; !reflect.DeepEqual(got, wantSelector) {
+		t.Errorf("Selector() = %v, want %v", got, wantSelector)
+	}
+}
+
+func TestRecommendedLabels_Merge(t *testing.T) {
+	recommended := RecommendedLabels{
+		Name:     "myapp",
+		Instance: "myapp-1",
+	}
+	tests := []struct {
+		name string
+		user map[string]string
+		want map[string]string
+		err  bool
+	}{
+		{
+			name: "no user labels",
+			want: map[string]string{
+				NameKey:     "myapp",
+				InstanceKey: "myapp-1",
+			},
+		},
+		{
+			name: "user labels are added",
+			user: map[string]string{
+				"team":                  "a-team",
+				"example.com/cost-unit": "42",
+			},
+			want: map[string]string{
+				NameKey:                 "myapp",
+				InstanceKey:             "myapp-1",
+				"team":                  "a-team",
+				"example.com/cost-unit": "42",
+			},
+		},
+		{
+			name: "user label with same value",
+			user: map[string]string{
+				NameKey: "myapp",
+			},
+			want: map[string]string{
+				NameKey:     "myapp",
+				InstanceKey: "myapp-1",
+			},
+		},
+		{
+			name: "user label conflicting with recommended label",
+			user: map[string]string{
+				NameKey: "another",
+			},
+			err: true,
+		},
+		{
+			name: "invalid user label key",
+			user: map[string]string{
+				"a/b/c": "value",
+			},
+			err: true,
+		},
+		{
+			name: "invalid user label value",
+			user: map[string]string{
+				"key": "a value",
+			},
+			err: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := recommended.Merge(tt.user)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error: %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendedLabels_Conflicts(t *testing.T) {
+	recommended := RecommendedLabels{
+		Name:     "myapp",
+		Instance: "myapp-1",
+	}
+	user := map[string]string{
+		InstanceKey: "other",
+		NameKey:     "myapp",
+		VersionKey:  "1.0",
+		"team":      "a-team",
+	}
+	if got := recommended.Conflicts(user); !reflect.DeepEqual(got, []string{InstanceKey}) {
+		t.Errorf("Conflicts() = %v, want [%s]", got, InstanceKey)
+	}
+}
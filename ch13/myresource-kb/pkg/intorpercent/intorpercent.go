@@ -0,0 +1,204 @@
+// Package intorpercent validates and resolves IntOrString values,
+// either absolute numbers or percentages, like the maxSurge and
+// maxUnavailable fields of Deployments or the minAvailable field
+// of PodDisruptionBudgets, against a number of replicas.
+package intorpercent
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var percentRegexp = regexp.MustCompile(`^([0-9]+)%$`)
+
+// Rounding defines how a percentage of replicas
+// is rounded to an integer number of replicas
+type Rounding int
+
+const (
+	// RoundDown rounds a percentage of replicas down,
+	// e.g. 25% of 3 replicas is 0
+	RoundDown Rounding = iota
+	// RoundUp rounds a percentage of replicas up,
+	// e.g. 25% of 3 replicas is 1
+	RoundUp
+)
+
+// Policy defines how the values of a field
+// are validated and resolved
+type Policy struct {
+	// Field is the name of the field, used when no path
+	// is given to Validate and in the errors of Resolve
+	Field string
+	// Rounding defines how percentages are rounded
+	Rounding Rounding
+	// AllowAbove100 accepts percentages greater than 100%
+	AllowAbove100 bool
+}
+
+var (
+	// MaxSurge is the policy of the maxSurge field of Deployments:
+	// percentages are rounded up and can be greater than 100%
+	MaxSurge = Policy{Field: "maxSurge", Rounding: RoundUp, AllowAbove100: true}
+	// MaxUnavailable is the policy of the maxUnavailable field
+	// of Deployments: percentages are rounded down
+	// and cannot be greater than 100%
+	MaxUnavailable = Policy{Field: "maxUnavailable", Rounding: RoundDown}
+	// MinAvailable is the policy of the minAvailable field
+	// of PodDisruptionBudgets: percentages are rounded up
+	// and cannot be greater than 100%
+	MinAvailable = Policy{Field: "minAvailable", Rounding: RoundUp}
+	// BudgetMaxUnavailable is the policy of the maxUnavailable field
+	// of PodDisruptionBudgets: unlike for Deployments, percentages
+	// are rounded up and cannot be greater than 100%
+	BudgetMaxUnavailable = Policy{Field: "maxUnavailable", Rounding: RoundUp}
+)
+
+// Validate validates value, and returns errors if it is
+// a negative number, a string not of the form "<integer>%",
+// or a percentage greater than 100% when not allowed by the policy.
+// A nil value is valid. If path is nil, the name
+// of the field of the policy is used.
+func (o Policy) Validate(path *field.Path, value *intstr.IntOrString) field.ErrorList {
+	if path == nil {
+		path = field.NewPath(o.Field)
+	}
+	if value == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	switch value.Type {
+	case intstr.Int:
+		if value.IntVal < 0 {
+			errs = append(errs, field.Invalid(path, value.IntVal,
+				"must be greater than or equal to 0"))
+		}
+	case intstr.String:
+		percent, ok := parsePercent(value.StrVal)
+		if !ok {
+			errs = append(errs, field.Invalid(path, value.StrVal,
+				"must be an integer or a percentage, e.g. 1 or 25%"))
+			break
+		}
+		if percent > 100 && !o.AllowAbove100 {
+			errs = append(errs, field.Invalid(path, value.StrVal,
+				"must not be greater than 100%"))
+		}
+	default:
+		errs = append(errs, field.Invalid(path, value,
+			"must be an integer or a percentage"))
+	}
+	return errs
+}
+
+// Resolve validates value and returns the number of replicas it
+// represents: an absolute number is returned as is, a percentage
+// is applied to replicas and rounded as defined by the policy.
+func (o Policy) Resolve(value *intstr.IntOrString, replicas int32) (int32, error) {
+	if value == nil {
+		return 0, fmt.Errorf("%s: value is nil", o.Field)
+	}
+	if errs := o.Validate(nil, value); len(errs) > 0 {
+		return 0, errs.ToAggregate()
+	}
+	if value.Type == intstr.Int {
+		return value.IntVal, nil
+	}
+	percent, _ := parsePercent(value.StrVal)
+	product := percent * int64(replicas)
+	result := product / 100
+	if o.Rounding == RoundUp && product%100 != 0 {
+		result++
+	}
+	if result > math.MaxInt32 {
+		result = math.MaxInt32
+	}
+	return int32(result), nil
+}
+
+// ResolveOrDefault resolves value, or defaultValue
+// if value is nil
+func (o Policy) ResolveOrDefault(
+	value *intstr.IntOrString,
+	defaultValue intstr.IntOrString,
+	replicas int32,
+) (int32, error) {
+	return o.Resolve(intstr.ValueOrDefault(value, defaultValue), replicas)
+}
+
+// DefaultRollingUpdate is the default value of the maxSurge
+// and maxUnavailable fields of Deployments
+var DefaultRollingUpdate = intstr.FromString("25%")
+
+// ValidateRollingUpdate validates the maxSurge and maxUnavailable
+// values of a rolling update, which cannot be both zero.
+// path is the path of the structure containing both fields.
+func ValidateRollingUpdate(
+	path *field.Path,
+	maxSurge, maxUnavailable *intstr.IntOrString,
+) field.ErrorList {
+	errs := MaxSurge.Validate(path.Child(MaxSurge.Field), maxSurge)
+	errs = append(errs,
+		MaxUnavailable.Validate(path.Child(MaxUnavailable.Field), maxUnavailable)...)
+	if len(errs) > 0 {
+		return errs
+	}
+	if isZero(maxSurge) && isZero(maxUnavailable) {
+		errs = append(errs, field.Invalid(
+			path.Child(MaxUnavailable.Field), maxUnavailable.String(),
+			"must not be 0 when maxSurge is 0"))
+	}
+	return errs
+}
+
+// ResolveRollingUpdate returns the number of replicas
+// represented by the maxSurge and maxUnavailable values
+// of a rolling update, defaulting to 25% when nil.
+// As done by the deployment controller, if both resolve to 0,
+// maxUnavailable is set to 1 so the rollout can progress.
+func ResolveRollingUpdate(
+	maxSurge, maxUnavailable *intstr.IntOrString,
+	replicas int32,
+) (surge int32, unavailable int32, err error) {
+	surge, err = MaxSurge.ResolveOrDefault(maxSurge, DefaultRollingUpdate, replicas)
+	if err != nil {
+		return 0, 0, err
+	}
+	unavailable, err = MaxUnavailable.ResolveOrDefault(maxUnavailable, DefaultRollingUpdate, replicas)
+	if err != nil {
+		return 0, 0, err
+	}
+	if surge == 0 && unavailable == 0 {
+		unavailable = 1
+	}
+	return surge, unavailable, nil
+}
+
+// isZero returns true if value is explicitly 0 or 0%
+func isZero(value *intstr.IntOrString) bool {
+	if value == nil {
+		return false
+	}
+	if value.Type == intstr.Int {
+		return value.IntVal == 0
+	}
+	percent, ok := parsePercent(value.StrVal)
+	return ok && percent == 0
+}
+
+func parsePercent(s string) (int64, bool) {
+	matches := percentRegexp.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, false
+	}
+	percent, err := strconv.ParseInt(matches[1], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return percent, true
+}
@@ -0,0 +1,158 @@
+package intorpercent
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func ptr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestPolicy_Resolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		value    *intstr.IntOrString
+		replicas int32
+		want     int32
+		wantErr  string
+	}{
+		{name: "surge int", policy: MaxSurge, value: ptr(intstr.FromInt(2)), replicas: 10, want: 2},
+		{name: "surge int greater than replicas", policy: MaxSurge, value: ptr(intstr.FromInt(20)), replicas: 10, want: 20},
+		{name: "surge percent exact", policy: MaxSurge, value: ptr(intstr.FromString("50%")), replicas: 10, want: 5},
+		{name: "surge percent rounded up", policy: MaxSurge, value: ptr(intstr.FromString("25%")), replicas: 3, want: 1},
+		{name: "surge above 100%", policy: MaxSurge, value: ptr(intstr.FromString("150%")), replicas: 3, want: 5},
+		{name: "surge zero replicas", policy: MaxSurge, value: ptr(intstr.FromString("25%")), replicas: 0, want: 0},
+		{name: "unavailable percent rounded down", policy: MaxUnavailable, value: ptr(intstr.FromString("25%")), replicas: 3, want: 0},
+		{name: "unavailable percent exact", policy: MaxUnavailable, value: ptr(intstr.FromString("25%")), replicas: 8, want: 2},
+		{name: "unavailable 100%", policy: MaxUnavailable, value: ptr(intstr.FromString("100%")), replicas: 7, want: 7},
+		{name: "unavailable above 100%", policy: MaxUnavailable, value: ptr(intstr.FromString("150%")), replicas: 3, wantErr: "must not be greater than 100%"},
+		{name: "min available rounded up", policy: MinAvailable, value: ptr(intstr.FromString("50%")), replicas: 5, want: 3},
+		{name: "budget unavailable rounded up", policy: BudgetMaxUnavailable, value: ptr(intstr.FromString("10%")), replicas: 5, want: 1},
+		{name: "not a percentage", policy: MaxSurge, value: ptr(intstr.FromString("abc")), replicas: 3, wantErr: "must be an integer or a percentage"},
+		{name: "number as string", policy: MaxSurge, value: ptr(intstr.FromString("2")), replicas: 3, wantErr: "must be an integer or a percentage"},
+		{name: "decimal percentage", policy: MaxSurge, value: ptr(intstr.FromString("12.5%")), replicas: 3, wantErr: "must be an integer or a percentage"},
+		{name: "negative percentage", policy: MaxSurge, value: ptr(intstr.FromString("-10%")), replicas: 3, wantErr: "must be an integer or a percentage"},
+		{name: "negative int", policy: MaxUnavailable, value: ptr(intstr.FromInt(-1)), replicas: 3, wantErr: "must be greater than or equal to 0"},
+		{name: "nil", policy: MaxUnavailable, value: nil, replicas: 3, wantErr: "maxUnavailable: value is nil"},
+		{name: "clamped", policy: MaxSurge, value: ptr(intstr.FromString("2000000000%")), replicas: 1000, want: 2147483647},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Resolve(tt.value, tt.replicas)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	errs := MaxUnavailable.Validate(
+		field.NewPath("spec", "rollout", "maxUnavailable"),
+		ptr(intstr.FromString("150%")),
+	)
+	want := `spec.rollout.maxUnavailable: Invalid value: "150%": must not be greater than 100%`
+	if len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("Validate() = %v, want %s", errs, want)
+	}
+
+	errs = MinAvailable.Validate(nil, ptr(intstr.FromString("abc")))
+	want = `minAvailable: Invalid value: "abc": must be an integer or a percentage, e.g. 1 or 25%`
+	if len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("Validate() = %v, want %s", errs, want)
+	}
+
+	if errs = MinAvailable.Validate(nil, nil); len(errs) != 0 {
+		t.Errorf("Validate(nil) = %v, want no error", errs)
+	}
+}
+
+func TestValidateRollingUpdate(t *testing.T) {
+	path := field.NewPath("spec", "rollout")
+	tests := []struct {
+		name           string
+		maxSurge       *intstr.IntOrString
+		maxUnavailable *intstr.IntOrString
+		want           []string
+	}{
+		{name: "defaults"},
+		{name: "valid", maxSurge: ptr(intstr.FromString("200%")), maxUnavailable: ptr(intstr.FromInt(1))},
+		{name: "surge zero", maxSurge: ptr(intstr.FromInt(0))},
+		{
+			name:           "both zero",
+			maxSurge:       ptr(intstr.FromInt(0)),
+			maxUnavailable: ptr(intstr.FromString("0%")),
+			want:           []string{`spec.rollout.maxUnavailable: Invalid value: "0%": must not be 0 when maxSurge is 0`},
+		},
+		{
+			name:           "both invalid",
+			maxSurge:       ptr(intstr.FromString("abc")),
+			maxUnavailable: ptr(intstr.FromString("150%")),
+			want: []string{
+				`spec.rollout.maxSurge: Invalid value: "abc": must be an integer or a percentage, e.g. 1 or 25%`,
+				`spec.rollout.maxUnavailable: Invalid value: "150%": must not be greater than 100%`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateRollingUpdate(path, tt.maxSurge, tt.maxUnavailable)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("ValidateRollingUpdate() = %v, want %v", errs, tt.want)
+			}
+			for i := range errs {
+				if errs[i].Error() != tt.want[i] {
+					t.Errorf("error %d = %s, want %s", i, errs[i].Error(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestResolveRollingUpdate(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxSurge        *intstr.IntOrString
+		maxUnavailable  *intstr.IntOrString
+		replicas        int32
+		wantSurge       int32
+		wantUnavailable int32
+	}{
+		{name: "defaults", replicas: 10, wantSurge: 3, wantUnavailable: 2},
+		{name: "defaults one replica", replicas: 1, wantSurge: 1, wantUnavailable: 0},
+		{name: "defaults zero replicas", replicas: 0, wantSurge: 0, wantUnavailable: 1},
+		{name: "both resolve to zero", maxSurge: ptr(intstr.FromInt(0)), maxUnavailable: ptr(intstr.FromString("10%")), replicas: 5, wantSurge: 0, wantUnavailable: 1},
+		{name: "values", maxSurge: ptr(intstr.FromString("100%")), maxUnavailable: ptr(intstr.FromInt(2)), replicas: 4, wantSurge: 4, wantUnavailable: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			surge, unavailable, err := ResolveRollingUpdate(tt.maxSurge, tt.maxUnavailable, tt.replicas)
+			if err != nil {
+				t.Fatalf("ResolveRollingUpdate() error = %v", err)
+			}
+			if surge != tt.wantSurge || unavailable != tt.wantUnavailable {
+				t.Errorf("ResolveRollingUpdate() = (%d, %d), want (%d, %d)",
+					surge, unavailable, tt.wantSurge, tt.wantUnavailable)
+			}
+		})
+	}
+
+	_, _, err := ResolveRollingUpdate(nil, ptr(intstr.FromString("abc")), 3)
+	if err == nil {
+		t.Error("ResolveRollingUpdate() with invalid maxUnavailable: expected an error")
+	}
+}
@@ -0,0 +1,156 @@
+// Package quantity parses memory quantities written
+// in the forms commonly used by humans, like "1.5GB",
+// "1536 MiB" or "2g", and normalizes them to Kubernetes quantities.
+package quantity
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// megaUnits are the accepted forms of the mega unit, "m"
+// being the milli unit of the Kubernetes quantities
+var megaUnits = map[string]bool{"M": true, "MB": true}
+
+var humanRegexp = regexp.MustCompile(`^([0-9]+(?:\.[0-9]*)?|\.[0-9]+)\s*([a-zA-Z]*)$`)
+
+type unit struct {
+	multiplier int64
+	format     resource.Format
+}
+
+// units indexes the accepted units by their lower case form,
+// the mega units are accepted in upper case only, see megaUnits
+var units = map[string]unit{
+	"":    {1, resource.DecimalSI},
+	"b":   {1, resource.DecimalSI},
+	"k":   {1e3, resource.DecimalSI},
+	"kb":  {1e3, resource.DecimalSI},
+	"m":   {1e6, resource.DecimalSI},
+	"mb":  {1e6, resource.DecimalSI},
+	"g":   {1e9, resource.DecimalSI},
+	"gb":  {1e9, resource.DecimalSI},
+	"t":   {1e12, resource.DecimalSI},
+	"tb":  {1e12, resource.DecimalSI},
+	"p":   {1e15, resource.DecimalSI},
+	"pb":  {1e15, resource.DecimalSI},
+	"e":   {1e18, resource.DecimalSI},
+	"eb":  {1e18, resource.DecimalSI},
+	"ki":  {1 << 10, resource.BinarySI},
+	"kib": {1 << 10, resource.BinarySI},
+	"mi":  {1 << 20, resource.BinarySI},
+	"mib": {1 << 20, resource.BinarySI},
+	"gi":  {1 << 30, resource.BinarySI},
+	"gib": {1 << 30, resource.BinarySI},
+	"ti":  {1 << 40, resource.BinarySI},
+	"tib": {1 << 40, resource.BinarySI},
+	"pi":  {1 << 50, resource.BinarySI},
+	"pib": {1 << 50, resource.BinarySI},
+	"ei":  {1 << 60, resource.BinarySI},
+	"eib": {1 << 60, resource.BinarySI},
+}
+
+// ParseMemory parses a memory size expressed as a positive number,
+// optionally followed by spaces and a unit, and returns it
+// as a Quantity of bytes.
+//
+// Units are case insensitive, except "M", and the trailing "B" is optional:
+//   - "K", "M", "G", "T", "P" and "E" (or "KB", "MB"...) are decimal
+//     units, the returned Quantity uses the DecimalSI format.
+//     "m" is the milli unit of Kubernetes, not the mega unit;
+//   - "Ki", "Mi", "Gi", "Ti", "Pi" and "Ei" (or "KiB", "MiB"...)
+//     are binary units, the returned Quantity uses the BinarySI format;
+//   - a number without unit, or with the "B" unit, is a number of bytes,
+//     the returned Quantity uses the DecimalSI format.
+//
+// A fractional number of bytes is rounded up to the next byte,
+// so "1.5" is 2 bytes and "0.1Ki" is 103 bytes.
+//
+// Values not matching these forms, like "1e9" or "500m", are parsed
+// as Kubernetes quantities and rounded up to the next byte.
+func ParseMemory(s string) (resource.Quantity, error) {
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return resource.Quantity{}, fmt.Errorf("memory quantity is empty")
+	}
+	if strings.HasPrefix(str, "-") {
+		return resource.Quantity{}, fmt.Errorf(
+			"invalid memory quantity %q: must not be negative", s,
+		)
+	}
+
+	matches := humanRegexp.FindStringSubmatch(str)
+	if matches == nil {
+		return parseKubernetes(s, str)
+	}
+
+	if matches[2] == "m" {
+		return parseKubernetes(s, str)
+	}
+	u, found := units[strings.ToLower(matches[2])]
+	if u.multiplier == 1e6 && !megaUnits[matches[2]] {
+		found = false
+	}
+	if !found {
+		return resource.Quantity{}, fmt.Errorf(
+			"invalid memory quantity %q: unknown unit %q, "+
+				"expected one of B, K, M, G, T, P, E (decimal) "+
+				"or Ki, Mi, Gi, Ti, Pi, Ei (binary), optionally followed by B",
+			s, matches[2],
+		)
+	}
+
+	number, ok := new(big.Rat).SetString(matches[1])
+	if !ok {
+		return resource.Quantity{}, fmt.Errorf(
+			"invalid memory quantity %q: invalid number %q", s, matches[1],
+		)
+	}
+	bytes := number.Mul(number, new(big.Rat).SetInt64(u.multiplier))
+	value, err := ceil(bytes)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("invalid memory quantity %q: %w", s, err)
+	}
+	return *resource.NewQuantity(value, u.format), nil
+}
+
+// Normalize parses s with ParseMemory and returns the canonical
+// form of the quantity, e.g. "1536 MiB" is normalized to "1536Mi",
+// "1.5GB" to "1500M" and "1e9" to "1G".
+func Normalize(s string) (string, error) {
+	q, err := ParseMemory(s)
+	if err != nil {
+		return "", err
+	}
+	if q.Format == resource.DecimalExponent {
+		q = *resource.NewQuantity(q.Value(), resource.DecimalSI)
+	}
+	return q.String(), nil
+}
+
+func parseKubernetes(s, str string) (resource.Quantity, error) {
+	q, err := resource.ParseQuantity(str)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf(
+			"invalid memory quantity %q: expected a number followed by an optional unit, "+
+				"e.g. 512Mi, 1.5GB or 2g", s,
+		)
+	}
+	// Value rounds up to the next integer
+	return *resource.NewQuantity(q.Value(), q.Format), nil
+}
+
+func ceil(r *big.Rat) (int64, error) {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() > 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if !quo.IsInt64() {
+		return 0, fmt.Errorf("value is too large")
+	}
+	return quo.Int64(), nil
+}
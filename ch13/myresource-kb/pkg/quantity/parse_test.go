@@ -0,0 +1,144 @@
+package quantity
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		input  string
+		bytes  int64
+		format resource.Format
+		want   string
+		err    bool
+	}{
+		// decimal units
+		{input: "1.5GB", bytes: 1_500_000_000, format: resource.DecimalSI, want: "1500M"},
+		{input: "2g", bytes: 2_000_000_000, format: resource.DecimalSI, want: "2G"},
+		{input: "2G", bytes: 2_000_000_000, format: resource.DecimalSI, want: "2G"},
+		{input: "512 MB", bytes: 512_000_000, format: resource.DecimalSI, want: "512M"},
+		{input: "1kB", bytes: 1000, format: resource.DecimalSI, want: "1k"},
+		{input: "1TB", bytes: 1_000_000_000_000, format: resource.DecimalSI, want: "1T"},
+
+		// binary units
+		{input: "1536 MiB", bytes: 1536 << 20, format: resource.BinarySI, want: "1536Mi"},
+		{input: "1536Mi", bytes: 1536 << 20, format: resource.BinarySI, want: "1536Mi"},
+		{input: "1.5GiB", bytes: 1536 << 20, format: resource.BinarySI, want: "1536Mi"},
+		{input: "2gi", bytes: 2 << 30, format: resource.BinarySI, want: "2Gi"},
+		{input: "1024 kib", bytes: 1 << 20, format: resource.BinarySI, want: "1Mi"},
+		{input: "0.5Ki", bytes: 512, format: resource.BinarySI, want: "512"},
+
+		// bytes
+		{input: "1024", bytes: 1024, format: resource.DecimalSI, want: "1024"},
+		{input: "1000", bytes: 1000, format: resource.DecimalSI, want: "1k"},
+		{input: "100 B", bytes: 100, format: resource.DecimalSI, want: "100"},
+		{input: " 64Mi ", bytes: 64 << 20, format: resource.BinarySI, want: "64Mi"},
+		{input: ".5k", bytes: 500, format: resource.DecimalSI, want: "500"},
+		{input: "0", bytes: 0, format: resource.DecimalSI, want: "0"},
+
+		// rounding up to the next byte
+		{input: "1.5", bytes: 2, format: resource.DecimalSI, want: "2"},
+		{input: "0.1Ki", bytes: 103, format: resource.BinarySI, want: "103"},
+		{input: "1.0000000001GB", bytes: 1_000_000_001, format: resource.DecimalSI, want: "1000000001"},
+
+		// Kubernetes forms
+		{input: "1e9", bytes: 1_000_000_000, format: resource.DecimalExponent, want: "1e9"},
+		{input: "+1Gi", bytes: 1 << 30, format: resource.BinarySI, want: "1Gi"},
+		{input: "500m", bytes: 1, format: resource.DecimalSI, want: "1"},
+		{input: "1500m", bytes: 2, format: resource.DecimalSI, want: "2"},
+
+		// errors
+		{input: "", err: true},
+		{input: "   ", err: true},
+		{input: "abc", err: true},
+		{input: "-1Gi", err: true},
+		{input: "1XB", err: true},
+		{input: "1mb", err: true},
+		{input: "1mB", err: true},
+		{input: "1 GiBs", err: true},
+		{input: "1.2.3G", err: true},
+		{input: "1,5G", err: true},
+		{input: "20EiB", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMemory(tt.input)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error: %v", err, tt.err)
+			}
+			if tt.err {
+				return
+			}
+			if got.Value() != tt.bytes {
+				t.Errorf("value = %d, want %d", got.Value(), tt.bytes)
+			}
+			if got.Format != tt.format {
+				t.Errorf("format = %s, want %s", got.Format, tt.format)
+			}
+			if got.String() != tt.want {
+				t.Errorf("String() = %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMemoryErrorMessages(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			input: "abc",
+			want:  `invalid memory quantity "abc": expected a number followed by an optional unit, e.g. 512Mi, 1.5GB or 2g`,
+		},
+		{
+			input: "-1Gi",
+			want:  `invalid memory quantity "-1Gi": must not be negative`,
+		},
+		{
+			input: "1XB",
+			want: `invalid memory quantity "1XB": unknown unit "XB", ` +
+				`expected one of B, K, M, G, T, P, E (decimal) ` +
+				`or Ki, Mi, Gi, Ti, Pi, Ei (binary), optionally followed by B`,
+		},
+		{
+			input: "20EiB",
+			want:  `invalid memory quantity "20EiB": value is too large`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseMemory(tt.input)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "1536 MiB", want: "1536Mi"},
+		{input: "1.5GB", want: "1500M"},
+		{input: "512M", want: "512M"},
+		{input: "1e9", want: "1G"},
+		{input: "1.5e3", want: "1500"},
+		{input: "500m", want: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Normalize(tt.input)
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package reconcilelog attaches to the logger of a reconcile
+// a generated correlation ID and the identity of the reconciled
+// object, and annotates the events emitted during the reconcile
+// with the same correlation ID.
+package reconcilelog
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	// CorrelationIDKey is the key of the correlation ID in the
+	// logs. The reconcileID logged by controller-runtime is
+	// not exposed to the reconcilers, a distinct ID is used.
+	CorrelationIDKey = "correlationID"
+	// CorrelationIDAnnotation is the annotation of the events
+	// containing the correlation ID of the reconcile
+	CorrelationIDAnnotation = "mygroup.myid.dev/correlation-id"
+)
+
+type correlationIDKey struct{}
+
+// WithObject returns a copy of ctx carrying a new correlation ID,
+// and a logger with the correlation ID, and the key, generation
+// and resourceVersion of obj. The logger is returned as well.
+func WithObject(ctx context.Context, obj client.Object) (context.Context, logr.Logger) {
+	id := string(uuid.NewUUID())
+	logger := log.FromContext(ctx).WithValues(
+		CorrelationIDKey, id,
+		"key", client.ObjectKeyFromObject(obj).String(),
+		"generation", obj.GetGeneration(),
+		"resourceVersion", obj.GetResourceVersion(),
+	)
+	ctx = context.WithValue(ctx, correlationIDKey{}, id)
+	return log.IntoContext(ctx, logger), logger
+}
+
+// CorrelationID returns the correlation ID of ctx,
+// or an empty string if WithObject has not been called
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// Eventf emits an event for obj with recorder, annotated with
+// the correlation ID of ctx. The ID is not part of the message,
+// not to prevent the aggregation of the repeated events.
+func Eventf(
+	ctx context.Context,
+	recorder record.EventRecorder,
+	obj runtime.Object,
+	eventtype, reason, messageFmt string,
+	args ...interface{},
+) {
+	var annotations map[string]string
+	if id := CorrelationID(ctx); id != "" {
+		annotations = map[string]string{CorrelationIDAnnotation: id}
+	}
+	recorder.AnnotatedEventf(obj, annotations, eventtype, reason, messageFmt, args...)
+}
+
+// Production configures the options for a production logger:
+// JSON encoder, info level and ISO8601 times. The production
+// logger of controller-runtime samples the logs, 100 per message
+// per second, then 1 of 100, unless the level is V(2) or more
+// verbose. NewSampled replaces this sampler.
+func Production(o *crzap.Options) {
+	o.Development = false
+	o.TimeEncoder = zapcore.ISO8601TimeEncoder
+}
+
+// NewSampled returns a logger configured with opts, like crzap.New,
+// sampling the logs of each message, e.g. logged in a hot loop: the
+// first logs in each tick, then 1 of thereafter logs. The sampler
+// replaces the one of the production logger, so these rates are the
+// effective rates. NewSampled must not be used with the levels more
+// verbose than debug, i.e. V(2) and beyond.
+func NewSampled(tick time.Duration, first, thereafter int, opts ...crzap.Opts) logr.Logger {
+	// the first core wrapper receives the core built by
+	// crzap, before the default sampler wraps it
+	var core zapcore.Core
+	opts = append([]crzap.Opts{crzap.RawZapOpts(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		core = c
+		return c
+	}))}, opts...)
+	logger := crzap.NewRaw(opts...).WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, tick, first, thereafter)
+	}))
+	return zapr.NewLogger(logger)
+}
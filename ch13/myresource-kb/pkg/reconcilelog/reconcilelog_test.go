@@ -0,0 +1,110 @@
+package reconcilelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// annotatedRecorder records the annotations of the events,
+// dropped by record.FakeRecorder
+type annotatedRecorder struct {
+	annotations []map[string]string
+}
+
+func (o *annotatedRecorder) Event(runtime.Object, string, string, string) {}
+
+func (o *annotatedRecorder) Eventf(runtime.Object, string, string, string, ...interface{}) {}
+
+func (o *annotatedRecorder) AnnotatedEventf(
+	_ runtime.Object,
+	annotations map[string]string,
+	_, _, _ string,
+	_ ...interface{},
+) {
+	o.annotations = append(o.annotations, annotations)
+}
+
+func TestWithObject(t *testing.T) {
+	var buf bytes.Buffer
+	logger := crzap.New(crzap.WriteTo(&buf), Production)
+	obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "ns",
+		Name:            "name",
+		Generation:      3,
+		ResourceVersion: "42",
+	}}
+
+	ctx, ctxLogger := WithObject(log.IntoContext(context.Background(), logger), obj)
+	id := CorrelationID(ctx)
+	if id == "" {
+		t.Fatalf("CorrelationID() is empty")
+	}
+	if other, _ := WithObject(context.Background(), obj); CorrelationID(other) == id {
+		t.Errorf("CorrelationID() = %s for two reconciles, want distinct IDs", id)
+	}
+
+	log.FromContext(ctx).Info("reconciling")
+	ctxLogger.Info("reconciling")
+	want := map[string]interface{}{
+		CorrelationIDKey:  id,
+		"key":             "ns/name",
+		"generation":      float64(3),
+		"resourceVersion": "42",
+	}
+	// the logs of the context logger, and of the returned logger
+	decoder := json.NewDecoder(&buf)
+	for i := 0; i < 2; i++ {
+		var entry map[string]interface{}
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatalf("decoding log %d: %v", i, err)
+		}
+		for key, value := range want {
+			if entry[key] != value {
+				t.Errorf("log %d: %s = %v, want %v", i, key, entry[key], value)
+			}
+		}
+	}
+
+	recorder := &annotatedRecorder{}
+	Eventf(ctx, recorder, obj, corev1.EventTypeNormal, "Reason", "message")
+	Eventf(context.Background(), recorder, obj, corev1.EventTypeNormal, "Reason", "message")
+	if len(recorder.annotations) != 2 ||
+		recorder.annotations[0][CorrelationIDAnnotation] != id ||
+		recorder.annotations[1] != nil {
+		t.Errorf("events annotations = %v, want the correlation ID %s, then none", recorder.annotations, id)
+	}
+}
+
+func TestNewSampled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSampled(time.Hour, 2, 5, crzap.WriteTo(&buf))
+	for i := 0; i < 12; i++ {
+		logger.Info("hot loop")
+	}
+	// the 2 first logs, then the 5th and the 10th of the others
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 4 {
+		t.Errorf("%d logs, want 4", lines)
+	}
+}
+
+func TestNewSampled_Production(t *testing.T) {
+	var buf bytes.Buffer
+	// the default sampler of the production logger would
+	// keep 100 logs per second, then 1 of 100
+	logger := NewSampled(time.Hour, 150, 1000, crzap.WriteTo(&buf), Production)
+	for i := 0; i < 200; i++ {
+		logger.Info("hot loop")
+	}
+	if lines := bytes.Count(buf.Bytes(), []byte("\n")); lines != 150 {
+		t.Errorf("%d logs, want 150", lines)
+	}
+}
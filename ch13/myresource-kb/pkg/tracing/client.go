@@ -0,0 +1,142 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type tracedClient struct {
+	client.Client
+	tracer trace.Tracer
+}
+
+// Client returns a client starting a span for each call
+// to c, e.g. Client.Get or Client.Status.Update, with the
+// kind, namespace and name of the object
+func Client(c client.Client, tracer trace.Tracer) client.Client {
+	return &tracedClient{Client: c, tracer: tracer}
+}
+
+func (o *tracedClient) start(
+	ctx context.Context,
+	operation string,
+	obj client.Object,
+) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{}
+	if gvk, err := apiutil.GVKForObject(obj, o.Client.Scheme()); err == nil {
+		attributes = append(attributes, KindKey.String(gvk.Kind))
+	}
+	if namespace := obj.GetNamespace(); namespace != "" {
+		attributes = append(attributes, NamespaceKey.String(namespace))
+	}
+	if name := obj.GetName(); name != "" {
+		attributes = append(attributes, NameKey.String(name))
+	}
+	return o.tracer.Start(ctx, "Client."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+func (o *tracedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, span := o.tracer.Start(ctx, "Client.Get",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(NamespaceKey.String(key.Namespace), NameKey.String(key.Name)),
+	)
+	defer span.End()
+	if gvk, err := apiutil.GVKForObject(obj, o.Client.Scheme()); err == nil {
+		span.SetAttributes(KindKey.String(gvk.Kind))
+	}
+	err := o.Client.Get(ctx, key, obj, opts...)
+	endWithError(span, err)
+	return err
+}
+
+func (o *tracedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, span := o.tracer.Start(ctx, "Client.List", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	if gvk, err := apiutil.GVKForObject(list, o.Client.Scheme()); err == nil {
+		span.SetAttributes(KindKey.String(gvk.Kind))
+	}
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	if listOpts.Namespace != "" {
+		span.SetAttributes(NamespaceKey.String(listOpts.Namespace))
+	}
+	if listOpts.LabelSelector != nil {
+		span.SetAttributes(attribute.String("labelSelector", listOpts.LabelSelector.String()))
+	}
+	err := o.Client.List(ctx, list, opts...)
+	endWithError(span, err)
+	return err
+}
+
+func (o *tracedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, span := o.start(ctx, "Create", obj)
+	defer span.End()
+	err := o.Client.Create(ctx, obj, opts...)
+	endWithError(span, err)
+	return err
+}
+
+func (o *tracedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, span := o.start(ctx, "Delete", obj)
+	defer span.End()
+	err := o.Client.Delete(ctx, obj, opts...)
+	endWithError(span, err)
+	return err
+}
+
+func (o *tracedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, span := o.start(ctx, "Update", obj)
+	defer span.End()
+	err := o.Client.Update(ctx, obj, opts...)
+	endWithError(span, err)
+	return err
+}
+
+func (o *tracedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := o.start(ctx, "Patch", obj)
+	defer span.End()
+	span.SetAttributes(attribute.String("patchType", string(patch.Type())))
+	err := o.Client.Patch(ctx, obj, patch, opts...)
+	endWithError(span, err)
+	return err
+}
+
+func (o *tracedClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	ctx, span := o.start(ctx, "DeleteAllOf", obj)
+	defer span.End()
+	err := o.Client.DeleteAllOf(ctx, obj, opts...)
+	endWithError(span, err)
+	return err
+}
+
+func (o *tracedClient) Status() client.StatusWriter {
+	return &tracedStatusWriter{StatusWriter: o.Client.Status(), client: o}
+}
+
+type tracedStatusWriter struct {
+	client.StatusWriter
+	client *tracedClient
+}
+
+func (o *tracedStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, span := o.client.start(ctx, "Status.Update", obj)
+	defer span.End()
+	err := o.StatusWriter.Update(ctx, obj, opts...)
+	endWithError(span, err)
+	return err
+}
+
+func (o *tracedStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := o.client.start(ctx, "Status.Patch", obj)
+	defer span.End()
+	span.SetAttributes(attribute.String("patchType", string(patch.Type())))
+	err := o.StatusWriter.Patch(ctx, obj, patch, opts...)
+	endWithError(span, err)
+	return err
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// FileExporter exports the spans to a writer, e.g. a file,
+// as JSON objects separated by new lines
+type FileExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+var _ sdktrace.SpanExporter = &FileExporter{}
+
+// NewFileExporter returns an exporter writing the spans to w.
+// w is closed by Shutdown if it is an io.Closer.
+func NewFileExporter(w io.Writer) *FileExporter {
+	exporter := &FileExporter{encoder: json.NewEncoder(w)}
+	if closer, ok := w.(io.Closer); ok {
+		exporter.closer = closer
+	}
+	return exporter
+}
+
+// ExportSpans writes spans, as tracetest.SpanStub objects
+func (o *FileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.encoder == nil {
+		return nil
+	}
+	for _, stub := range tracetest.SpanStubsFromReadOnlySpans(spans) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := o.encoder.Encode(stub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown stops the export of the spans, and closes the writer
+func (o *FileExporter) Shutdown(context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.encoder = nil
+	if o.closer == nil {
+		return nil
+	}
+	closer := o.closer
+	o.closer = nil
+	return closer.Close()
+}
@@ -0,0 +1,82 @@
+// Package tracing emits OpenTelemetry spans for the reconciles,
+// the calls to the Kubernetes API and the conversion webhook
+// requests, and exports them to a file, to be inspected
+// without any collector.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TracerName is the name of the tracer
+// of the controller and the webhooks
+const TracerName = "github.com/myid/myresource"
+
+// Attributes of the spans
+const (
+	KindKey      = attribute.Key("k8s.kind")
+	NamespaceKey = attribute.Key("k8s.namespace")
+	NameKey      = attribute.Key("k8s.name")
+)
+
+type reconciler struct {
+	reconcile.Reconciler
+	tracer trace.Tracer
+}
+
+// Reconciler returns a reconciler starting a Reconcile span
+// before calling r. The trace and span IDs are added to the
+// logger of the context, and the spans of the calls made
+// with the context are children of the Reconcile span.
+func Reconciler(r reconcile.Reconciler, tracer trace.Tracer) reconcile.Reconciler {
+	return &reconciler{Reconciler: r, tracer: tracer}
+}
+
+func (o *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	ctx, span := o.tracer.Start(ctx, "Reconcile", trace.WithAttributes(
+		NamespaceKey.String(req.Namespace),
+		NameKey.String(req.Name),
+	))
+	defer span.End()
+	ctx = withSpanLogger(ctx, span)
+
+	result, err := o.Reconciler.Reconcile(ctx, req)
+	if result.Requeue || result.RequeueAfter > 0 {
+		span.SetAttributes(
+			attribute.Bool("requeue", true),
+			attribute.String("requeueAfter", result.RequeueAfter.String()),
+		)
+	}
+	endWithError(span, err)
+	return result, err
+}
+
+// withSpanLogger returns a copy of ctx with a logger
+// carrying the trace and span IDs of span
+func withSpanLogger(ctx context.Context, span trace.Span) context.Context {
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return ctx
+	}
+	logger := log.FromContext(ctx).WithValues(
+		"traceID", sc.TraceID().String(),
+		"spanID", sc.SpanID().String(),
+	)
+	return log.IntoContext(ctx, logger)
+}
+
+// endWithError records err, if not nil, on span
+// and sets the status of span to Error
+func endWithError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
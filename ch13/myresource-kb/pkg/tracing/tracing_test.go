@@ -0,0 +1,206 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	apix "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTracer() (trace.Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return provider.Tracer(TracerName), recorder
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]string {
+	values := map[attribute.Key]string{}
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value.Emit()
+	}
+	return values
+}
+
+func TestReconciler(t *testing.T) {
+	tracer, recorder := newTracer()
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "dep"}}
+	c := Client(
+		fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(dep).Build(),
+		tracer,
+	)
+
+	var logs bytes.Buffer
+	logger := funcr.New(func(prefix, args string) { logs.WriteString(args + "\n") }, funcr.Options{})
+	errReconcile := errors.New("reconcile failed")
+	r := Reconciler(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		log.FromContext(ctx).Info("reconciling")
+		got := &appsv1.Deployment{}
+		if err := c.Get(ctx, req.NamespacedName, got); err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := c.List(ctx, &appsv1.DeploymentList{}, client.InNamespace("ns")); err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := c.Status().Update(ctx, got); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, errReconcile
+	}), tracer)
+
+	req := reconcile.Request{}
+	req.Namespace, req.Name = "ns", "dep"
+	ctx := log.IntoContext(context.Background(), logger)
+	if _, err := r.Reconcile(ctx, req); err != errReconcile {
+		t.Fatalf("Reconcile() error = %v, want %v", err, errReconcile)
+	}
+
+	spans := recorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	want := "Client.Get Client.List Client.Status.Update Reconcile"
+	if strings.Join(names, " ") != want {
+		t.Fatalf("spans = %v, want %s", names, want)
+	}
+	root := spans[len(spans)-1]
+	for _, span := range spans[:len(spans)-1] {
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("span %s is not a child of the Reconcile span", span.Name())
+		}
+		if got := attributes(span)[KindKey]; !strings.HasPrefix(got, "Deployment") {
+			t.Errorf("span %s kind = %q, want Deployment*", span.Name(), got)
+		}
+	}
+	if got := attributes(spans[0]); got[NamespaceKey] != "ns" || got[NameKey] != "dep" {
+		t.Errorf("Client.Get attributes = %v", got)
+	}
+	if root.Status().Code != codes.Error || root.Status().Description != errReconcile.Error() {
+		t.Errorf("Reconcile status = %+v, want an error", root.Status())
+	}
+	if !strings.Contains(logs.String(), `"traceID"="`+root.SpanContext().TraceID().String()+`"`) {
+		t.Errorf("logs %q, want the trace ID", logs.String())
+	}
+}
+
+// fakeConverter returns a failed conversion response
+type fakeConverter struct{}
+
+func (o *fakeConverter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := apix.ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	review.Response = &apix.ConversionResponse{
+		UID: review.Request.UID,
+		Result: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: "conversion failed",
+		},
+	}
+	review.Request = nil
+	json.NewEncoder(w).Encode(review)
+}
+
+func TestConversionHandler(t *testing.T) {
+	tracer, recorder := newTracer()
+	handler := ConversionHandler(&fakeConverter{}, tracer)
+
+	body, _ := json.Marshal(apix.ConversionReview{Request: &apix.ConversionRequest{
+		UID:               "123",
+		DesiredAPIVersion: "mygroup.myid.dev/v1beta1",
+		Objects:           []runtime.RawExtension{{Raw: []byte(`{}`)}, {Raw: []byte(`{}`)}},
+	}})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "conversion failed") {
+		t.Errorf("response = %d %s, want the response of the converter", w.Code, w.Body.String())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Name() != "Webhook.Convert" {
+		t.Fatalf("%d spans, want a Webhook.Convert span", len(spans))
+	}
+	got := attributes(spans[0])
+	if got["uid"] != "123" || got["desiredAPIVersion"] != "mygroup.myid.dev/v1beta1" || got["objects"] != "2" {
+		t.Errorf("attributes = %v", got)
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "conversion failed" {
+		t.Errorf("status = %+v, want the failure of the conversion", spans[0].Status())
+	}
+}
+
+// closingBuffer records the closing of the buffer
+type closingBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (o *closingBuffer) Close() error {
+	o.closed = true
+	return nil
+}
+
+func TestFileExporter(t *testing.T) {
+	buf := &closingBuffer{}
+	exporter := NewFileExporter(buf)
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := provider.Tracer(TracerName)
+
+	ctx, parent := tracer.Start(context.Background(), "Reconcile")
+	_, child := tracer.Start(ctx, "Client.Get", trace.WithAttributes(NameKey.String("dep")))
+	child.End()
+	parent.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if !buf.closed {
+		t.Errorf("the writer has not been closed")
+	}
+
+	spans := 0
+	decoder := json.NewDecoder(&buf.Buffer)
+	for decoder.More() {
+		var stub struct {
+			Name        string
+			SpanContext struct{ TraceID, SpanID string }
+			Parent      struct{ SpanID string }
+			Attributes  []struct{ Key string }
+		}
+		if err := decoder.Decode(&stub); err != nil {
+			t.Fatalf("decoding span %d: %v", spans, err)
+		}
+		if spans == 0 && (stub.Name != "Client.Get" || stub.Parent.SpanID != parent.SpanContext().SpanID().String() ||
+			len(stub.Attributes) != 1 || stub.Attributes[0].Key != string(NameKey)) {
+			t.Errorf("span 0 = %+v, want the Client.Get span", stub)
+		}
+		if spans == 1 && (stub.Name != "Reconcile" || stub.SpanContext.SpanID != parent.SpanContext().SpanID().String()) {
+			t.Errorf("span 1 = %+v, want the Reconcile span", stub)
+		}
+		spans++
+	}
+	if spans != 2 {
+		t.Errorf("%d spans exported, want 2", spans)
+	}
+}
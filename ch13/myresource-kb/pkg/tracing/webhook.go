@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	apix "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type conversionHandler struct {
+	handler http.Handler
+	tracer  trace.Tracer
+}
+
+// ConversionHandler returns a handler starting a Webhook.Convert
+// span for each conversion review served by handler, e.g.
+// a conversion.Webhook, with the desired API version
+// and the number of objects to convert. Nothing is injected
+// into handler, which must be ready to serve.
+func ConversionHandler(handler http.Handler, tracer trace.Tracer) http.Handler {
+	return &conversionHandler{handler: handler, tracer: tracer}
+}
+
+func (o *conversionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := o.tracer.Start(r.Context(), "Webhook.Convert", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	// the body is read to get the attributes of the span,
+	// and restored for the handler
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		endWithError(span, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	review := apix.ConversionReview{}
+	if err = json.Unmarshal(body, &review); err == nil && review.Request != nil {
+		span.SetAttributes(
+			attribute.String("uid", string(review.Request.UID)),
+			attribute.String("desiredAPIVersion", review.Request.DesiredAPIVersion),
+			attribute.Int("objects", len(review.Request.Objects)),
+		)
+	}
+
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	o.handler.ServeHTTP(recorder, r.WithContext(ctx))
+
+	span.SetAttributes(attribute.Int("http.status_code", recorder.status))
+	if recorder.status >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		return
+	}
+	// a failed conversion is reported in the response of the review
+	review = apix.ConversionReview{}
+	if err = json.Unmarshal(recorder.body.Bytes(), &review); err == nil &&
+		review.Response != nil && review.Response.Result.Status == metav1.StatusFailure {
+		span.SetStatus(codes.Error, review.Response.Result.Message)
+	}
+}
+
+// responseRecorder records the status and the body
+// of the response, written to the ResponseWriter
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (o *responseRecorder) WriteHeader(status int) {
+	o.status = status
+	o.ResponseWriter.WriteHeader(status)
+}
+
+func (o *responseRecorder) Write(data []byte) (int, error) {
+	o.body.Write(data)
+	return o.ResponseWriter.Write(data)
+}